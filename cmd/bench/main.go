@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+)
+
+const usage = `usage: bench [bench flags] -- [config flags]
+
+Seeds an empty DB with synthetic data and times every report before and
+after the report indexes migration. Use a DB only for the benchmark.`
+
+// indexesVersion is the migration adding the report indexes
+const indexesVersion = 2
+
+var ErrorBenchRegression = errors.New("reports slower than the baseline")
+
+// Results are the latencies of a run, saved with -out and compared with
+// -baseline.
+type Results struct {
+	Driver  string   `json:"driver"`
+	Scale   int      `json:"scale"`
+	Reports []Result `json:"reports"`
+}
+
+// Result is the median latency of a report, in milliseconds, without and
+// with the indexes.
+type Result struct {
+	Report   string  `json:"report"`
+	BeforeMs float64 `json:"before_ms"`
+	AfterMs  float64 `json:"after_ms"`
+}
+
+func main() {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	scale := flags.Int("scale", 10, "thousands of sales to seed, with invoices, customers and products in proportion")
+	runs := flags.Int("runs", 5, "runs of every report, the median is kept")
+	out := flags.String("out", "", "JSON file to save the results to")
+	baseline := flags.String("baseline", "", "JSON file of previous results, a report slower than it by more than the tolerance fails the run")
+	tolerance := flags.Float64("tolerance", 0.5, "slowdown allowed over the baseline, 0.5 is 50%")
+	flags.Parse(os.Args[1:])
+
+	cfg, err := config.Load(flags.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := storage.OpenWithOptions(context.Background(), cfg.DB.Driver, cfg.DB.ConnectionString(), cfg.DB.Options())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// The reports read what the seed just wrote
+	results, err := run(storage.WithPrimary(context.Background()), db, *scale, *runs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printResults(results)
+
+	if *out != "" {
+		if err = saveResults(*out, results); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *baseline != "" {
+		if err = compare(*baseline, results, *tolerance); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// run seeds db without the indexes, times the reports, adds the indexes and
+// times them again.
+func run(ctx context.Context, db *storage.DB, scale int, runs int) (Results, error) {
+	migrator, err := storage.NewMigrator(db)
+	if err != nil {
+		return Results{}, err
+	}
+
+	if _, err = migrator.UpTo(ctx, indexesVersion-1); err != nil {
+		return Results{}, err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return Results{}, err
+	}
+
+	if len(pending) == 0 || pending[0].Version != indexesVersion {
+		return Results{}, fmt.Errorf("the report indexes are already applied, bench needs an empty DB")
+	}
+
+	log.Printf("Seeding %d sales...", scale*1000)
+	start := time.Now()
+	if err = seed(ctx, db, scale); err != nil {
+		return Results{}, err
+	}
+	log.Printf("Seeded in %s", time.Since(start).Round(time.Millisecond))
+
+	reports := newReports(db, scale)
+	before, err := measure(ctx, reports, runs)
+	if err != nil {
+		return Results{}, err
+	}
+
+	if _, err = migrator.UpTo(ctx, indexesVersion); err != nil {
+		return Results{}, err
+	}
+
+	after, err := measure(ctx, reports, runs)
+	if err != nil {
+		return Results{}, err
+	}
+
+	results := Results{Driver: db.Dialect.Name(), Scale: scale}
+	for _, report := range reports {
+		results.Reports = append(results.Reports, Result{
+			Report:   report.name,
+			BeforeMs: before[report.name],
+			AfterMs:  after[report.name],
+		})
+	}
+
+	return results, nil
+}
+
+// measure returns the median latency of every report, in milliseconds.
+func measure(ctx context.Context, reports []report, runs int) (map[string]float64, error) {
+	latencies := map[string]float64{}
+	for _, report := range reports {
+		durations := make([]time.Duration, 0, runs)
+		for i := 0; i < runs; i++ {
+			start := time.Now()
+			if err := report.run(ctx); err != nil {
+				return nil, fmt.Errorf("%s: %w", report.name, err)
+			}
+			durations = append(durations, time.Since(start))
+		}
+
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		latencies[report.name] = float64(durations[len(durations)/2].Microseconds()) / 1000
+	}
+
+	return latencies, nil
+}
+
+func printResults(results Results) {
+	fmt.Printf("%-28s %12s %12s %8s\n", "report", "before ms", "after ms", "speedup")
+	for _, result := range results.Reports {
+		speedup := 0.0
+		if result.AfterMs > 0 {
+			speedup = result.BeforeMs / result.AfterMs
+		}
+
+		fmt.Printf("%-28s %12.2f %12.2f %7.1fx\n", result.Report, result.BeforeMs, result.AfterMs, speedup)
+	}
+}
+
+func saveResults(path string, results Results) error {
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// compare fails with ErrorBenchRegression when a report with the indexes is
+// slower than in the baseline by more than tolerance. Runs of other drivers
+// or scales are not comparable.
+func compare(path string, results Results, tolerance float64) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var baseline Results
+	if err = json.Unmarshal(content, &baseline); err != nil {
+		return err
+	}
+
+	if baseline.Driver != results.Driver || baseline.Scale != results.Scale {
+		return fmt.Errorf("baseline is of %s at scale %d, not %s at scale %d", baseline.Driver, baseline.Scale, results.Driver, results.Scale)
+	}
+
+	baselineMs := map[string]float64{}
+	for _, result := range baseline.Reports {
+		baselineMs[result.Report] = result.AfterMs
+	}
+
+	var slower []string
+	for _, result := range results.Reports {
+		previous, ok := baselineMs[result.Report]
+		if ok && result.AfterMs > previous*(1+tolerance) {
+			slower = append(slower, fmt.Sprintf("%s %.2fms over %.2fms", result.Report, result.AfterMs, previous))
+		}
+	}
+
+	if len(slower) > 0 {
+		return fmt.Errorf("%w: %v", ErrorBenchRegression, slower)
+	}
+
+	log.Println("No report slower than the baseline!")
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+)
+
+const (
+	// batchSize is the rows of every bulk insert of the seed
+	batchSize = 1000
+	// seedDays are the days the invoices are spread over
+	seedDays = 365
+)
+
+var (
+	situations = []string{"Activo", "Inactivo", "Bloqueado"}
+	// seedStart is the day of the first invoice
+	seedStart = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// report is a report query of the repositories to time.
+type report struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// seed stores scale thousand sales, with one invoice every 4 sales, one
+// customer every 10 invoices and one product every 100 sales. The data is
+// the same on every run.
+func seed(ctx context.Context, db *storage.DB, scale int) error {
+	random := rand.New(rand.NewSource(1))
+	sales := scale * 1000
+	invoices := sales / 4
+	customers := invoices / 10
+	products := sales / 100
+
+	productRepository := product.NewProductRepositoryWithDialect(db.DB, db.Dialect)
+	customerRepository := customer.NewCustomerRepositoryWithDialect(db.DB, db.Dialect)
+	invoiceRepository := invoice.NewInvoiceRepositoryWithDialect(db.DB, db.Dialect)
+	saleRepository := sale.NewSaleRepositoryWithDialect(db.DB, db.Dialect)
+
+	err := inBatches(products, func(from int, to int) error {
+		batch := make([]domain.Product, 0, to-from)
+		for id := from + 1; id <= to; id++ {
+			batch = append(batch, domain.Product{Id: id, Description: fmt.Sprintf("Product %d", id), Price: float64(random.Intn(10000)) / 100})
+		}
+
+		_, err := productRepository.StoreBulk(ctx, batch)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = inBatches(customers, func(from int, to int) error {
+		batch := make([]domain.Customer, 0, to-from)
+		for id := from + 1; id <= to; id++ {
+			batch = append(batch, domain.Customer{Id: id, FirstName: fmt.Sprintf("Name %d", id), LastName: fmt.Sprintf("Last %d", random.Intn(customers)), Situation: situations[random.Intn(len(situations))]})
+		}
+
+		_, err := customerRepository.StoreBulk(ctx, batch)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = inBatches(invoices, func(from int, to int) error {
+		batch := make([]domain.Invoice, 0, to-from)
+		for id := from + 1; id <= to; id++ {
+			datetime := seedStart.Add(time.Duration(random.Int63n(int64(seedDays * 24 * time.Hour))))
+			batch = append(batch, domain.Invoice{Id: id, Customer_id: random.Intn(customers) + 1, Datetime: datetime.Format(domain.InvoiceDatetimeLayout), Total: float64(random.Intn(100000)) / 100})
+		}
+
+		_, err := invoiceRepository.StoreBulk(ctx, batch)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return inBatches(sales, func(from int, to int) error {
+		batch := make([]domain.Sale, 0, to-from)
+		for id := from + 1; id <= to; id++ {
+			batch = append(batch, domain.Sale{Id: id, Invoice_id: random.Intn(invoices) + 1, Product_id: random.Intn(products) + 1, Quantity: float64(random.Intn(10) + 1)})
+		}
+
+		_, err := saleRepository.StoreBulk(ctx, batch)
+		return err
+	})
+}
+
+// inBatches calls store with the ranges of ids, from exclusive to inclusive,
+// of every batch of total rows.
+func inBatches(total int, store func(from int, to int) error) error {
+	for from := 0; from < total; from += batchSize {
+		to := from + batchSize
+		if to > total {
+			to = total
+		}
+
+		if err := store(from, to); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newReports returns every report query of the repositories on db.
+func newReports(db *storage.DB, scale int) []report {
+	productRepository := product.NewProductRepositoryWithDialect(db.DB, db.Dialect)
+	customerRepository := customer.NewCustomerRepositoryWithDialect(db.DB, db.Dialect)
+	invoiceRepository := invoice.NewInvoiceRepositoryWithDialect(db.DB, db.Dialect)
+	saleRepository := sale.NewSaleRepositoryWithDialect(db.DB, db.Dialect)
+
+	// The invoices of a hundredth of the seed, the ones the loads recalculate
+	invoicesIds := make([]int, 0, scale*10)
+	for id := 1; id <= scale*10; id++ {
+		invoicesIds = append(invoicesIds, id)
+	}
+
+	day := seedStart.AddDate(0, 6, 0)
+	from := day.Format(domain.InvoiceDatetimeLayout)
+	to := day.Add(24*time.Hour - time.Second).Format(domain.InvoiceDatetimeLayout)
+
+	return []report{
+		{"products_most_selled", func(ctx context.Context) error {
+			_, err := productRepository.ProductsMostSelled(ctx)
+			return err
+		}},
+		{"products_revenue", func(ctx context.Context) error {
+			_, err := productRepository.ProductsRevenue(ctx)
+			return err
+		}},
+		{"customers_total_by_condition", func(ctx context.Context) error {
+			_, err := customerRepository.GetTotalByCondition(ctx)
+			return err
+		}},
+		{"customers_cheaper_products", func(ctx context.Context) error {
+			_, err := customerRepository.GetCustomerCheaperProducts(ctx)
+			return err
+		}},
+		{"customers_rfm_values", func(ctx context.Context) error {
+			_, err := customerRepository.GetRFMValues(ctx)
+			return err
+		}},
+		{"invoices_calculate_total", func(ctx context.Context) error {
+			_, err := invoiceRepository.CalculateTotal(ctx, invoicesIds)
+			return err
+		}},
+		{"invoices_purchases", func(ctx context.Context) error {
+			_, err := invoiceRepository.GetPurchases(ctx, situations[0])
+			return err
+		}},
+		{"invoices_daily_revenue", func(ctx context.Context) error {
+			_, err := invoiceRepository.GetDailyRevenue(ctx)
+			return err
+		}},
+		{"sales_basket_items", func(ctx context.Context) error {
+			_, err := saleRepository.GetBasketItems(ctx, from, to)
+			return err
+		}},
+	}
+}
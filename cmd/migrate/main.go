@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+)
+
+const usage = `usage: migrate <command> [config flags]
+
+commands:
+  up         apply the migrations pending
+  down [n]   undo the last n migrations applied, 1 by default, every one with all
+  status     list the migrations and when they were applied
+  redo       undo the last migration applied and apply it again`
+
+// Migrates the DB of the config, in the dialect of its driver. The server
+// applies the migrations pending on start when DB_AUTO_MIGRATE is on.
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	steps := 1
+	if command == "down" && len(args) > 0 {
+		if args[0] == "all" {
+			steps, args = 0, args[1:]
+		} else if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := storage.OpenWithOptions(context.Background(), cfg.DB.Driver, cfg.DB.ConnectionString(), cfg.DB.Options())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := storage.NewMigrator(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var migrations []storage.Migration
+	switch command {
+	case "up":
+		migrations, err = migrator.Up(ctx)
+	case "down":
+		migrations, err = migrator.Down(ctx, steps)
+	case "redo":
+		migrations, err = migrator.Redo(ctx)
+	case "status":
+		err = printStatus(ctx, migrator)
+	default:
+		log.Fatal(usage)
+	}
+
+	for _, migration := range migrations {
+		log.Printf("%s %04d_%s", command, migration.Version, migration.Name)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if command != "status" {
+		log.Printf("Migrated %s, %d migrations!", db.Dialect.Name(), len(migrations))
+	}
+}
+
+func printStatus(ctx context.Context, migrator *storage.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != "" {
+			appliedAt = "applied " + status.AppliedAt
+		}
+
+		fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return nil
+}
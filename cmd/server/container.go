@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/anomaly"
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/cache"
+	"github.com/matias-ziliotto/HackthonGo/pkg/health"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// Container holds the services the handlers need, built once at startup.
+// Tests can fill it with fake services instead.
+type Container struct {
+	ProductService  product.ProductService
+	CustomerService customer.CustomerService
+	InvoiceService  invoice.InvoiceService
+	SaleService     sale.SaleService
+	SummaryService  summary.SummaryService
+	AnomalyService  anomaly.AnomalyService
+
+	// Report responses are cached until a write endpoint changes the data
+	ReportCache *web.ResponseCache
+	// ReportsFromPrimary is how long after a purge the reports filling the
+	// cache read from the primary, the replicas may not have the writes yet.
+	// Always when negative.
+	ReportsFromPrimary time.Duration
+	// Keyset listings cursors are signed with it
+	CursorCodec *web.CursorCodec
+	// PoolStats are the stats of the DB connections
+	PoolStats func() storage.PoolStats
+	// Deadlines of the requests of every route
+	Deadlines web.Deadlines
+	// Readiness has the checks of the subsystems the service needs to be
+	// ready, the server registers its own
+	Readiness *health.Readiness
+}
+
+// NewContainer builds the repositories on db, in its dialect, and the
+// services on them, the services loading the data files of cfg. A random
+// secret signs the cursors when cfg has none.
+func NewContainer(db *storage.DB, cfg config.Config) *Container {
+	// Summaries
+	summaryRepository := summary.NewSummaryRepositoryWithDialect(db.DB, db.Dialect)
+	summaryService := summary.NewSummaryService(summaryRepository)
+
+	// Products
+	productRepository := product.NewProductRepositoryWithDialect(db.DB, db.Dialect)
+	productService := product.NewProductServiceWithDataFile(productRepository, summaryRepository, cfg.Data.Path("products.txt"))
+
+	// Customers
+	customerRepository := customer.NewCustomerRepositoryWithDialect(db.DB, db.Dialect)
+	customerService := customer.NewCustomerServiceWithDataFile(customerRepository, summaryRepository, cfg.Data.Path("customers.txt"))
+
+	// Invoices
+	invoiceRepository := invoice.NewInvoiceRepositoryWithDialect(db.DB, db.Dialect)
+	invoiceService := invoice.NewInvoiceServiceWithDataFile(invoiceRepository, summaryRepository, cfg.Data.Path("invoices.txt"))
+
+	// Sales
+	saleRepository := sale.NewSaleRepositoryWithDialect(db.DB, db.Dialect)
+	saleService := sale.NewSaleServiceWithDataFile(saleRepository, cfg.Data.Path("sales.txt"))
+
+	// Anomalies
+	anomalyService := anomaly.NewAnomalyService(saleRepository, invoiceRepository)
+
+	// Readiness
+	readiness := health.NewReadiness(health.DefaultCheckTimeout)
+	readiness.Register("db", db.PingContext)
+	readiness.Register("migrations", db.CheckMigrated)
+
+	return &Container{
+		ProductService:     productService,
+		CustomerService:    customerService,
+		InvoiceService:     invoiceService,
+		SaleService:        saleService,
+		SummaryService:     summaryService,
+		AnomalyService:     anomalyService,
+		ReportCache:        web.NewResponseCache(cache.NewLRU(cache.DefaultLRUCapacity)),
+		ReportsFromPrimary: replicasMaxBehind(cfg.DB),
+		CursorCodec:        web.NewCursorCodec([]byte(cfg.Server.CursorSecret)),
+		PoolStats:          db.PoolStats,
+		Deadlines:          web.Deadlines{Default: cfg.Server.RequestTimeout, Routes: cfg.Server.RouteTimeouts},
+		Readiness:          readiness,
+	}
+}
+
+// replicasMaxBehind is the longest the replicas of cfg read from can be
+// behind the primary: the max lag plus the time until the next check finds
+// a replica is further behind. Negative when there is no limit.
+func replicasMaxBehind(cfg config.DB) time.Duration {
+	if len(cfg.Replicas) == 0 {
+		return 0
+	}
+
+	if cfg.ReplicaMaxLag == 0 || cfg.ReplicaCheckInterval == 0 {
+		return -1
+	}
+
+	return cfg.ReplicaMaxLag + cfg.ReplicaCheckInterval
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHandlerMapsKinds(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	requests := []struct {
+		method string
+		url    string
+		status int
+		code   string
+	}{
+		{http.MethodGet, "/reports/products/abc?cut_off_a=x", http.StatusBadRequest, "invalid_param"},
+		{http.MethodGet, "/reports/customers/rfm?buckets=1", http.StatusBadRequest, "rfm_invalid_buckets"},
+		{http.MethodGet, "/reports/anomalies?method=mean", http.StatusBadRequest, "invalid_param"},
+		{http.MethodGet, "/products?page=0", http.StatusBadRequest, "invalid_page"},
+		{http.MethodPost, "/sales/held/abc/release", http.StatusBadRequest, "invalid_param"},
+	}
+
+	for _, request := range requests {
+		// Act
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(request.method, request.url, nil))
+
+		// Assert
+		var errorResponse web.ErrorResponse
+		assert.Equal(t, request.status, response.Code, request.url)
+		assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &errorResponse), request.url)
+		assert.Equal(t, request.code, errorResponse.Code, request.url)
+	}
+}
+
+func TestErrorHandlerSkipsReportCache(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+
+	for i := 0; i < 2; i++ {
+		// Act
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/reports/products/abc?cut_off_a=x", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, response.Code, "failed reports should not be cached")
+		assert.Empty(t, response.Header().Get("X-Cache"), "failed reports should not be cached")
+	}
+}
+
+func TestErrorHandlerProblemParams(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	request := httptest.NewRequest(http.MethodGet, "/reports/products/basket?from=yesterday", nil)
+	request.Header.Set("Accept", web.MIMEProblemJSON)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, request)
+
+	// Assert
+	var problem web.Problem
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, web.MIMEProblemJSON, response.Header().Get("Content-Type"))
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	assert.Equal(t, web.Problem{
+		Type:     web.ProblemTypeBase + "invalid_param",
+		Title:    "invalid param",
+		Status:   http.StatusBadRequest,
+		Detail:   "invalid param: from must be a date, YYYY-MM-DD",
+		Instance: "/reports/products/basket?from=yesterday",
+		Code:     "invalid_param",
+		Errors:   []web.FieldError{{Field: "from", Rule: "date", Message: "must be a date, YYYY-MM-DD"}},
+	}, problem)
+}
+
+func TestErrorHandlerProblemBoundParams(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	request := httptest.NewRequest(http.MethodGet, "/reports/sales/forecast?horizon=soon&alpha=high&backtest=yes", nil)
+	request.Header.Set("Accept", web.MIMEProblemJSON)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, request)
+
+	// Assert
+	var problem web.Problem
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	assert.Equal(t, "invalid_param", problem.Code)
+	assert.Equal(t, "invalid param: horizon must be an integer, alpha must be a number, backtest must be a boolean", problem.Detail)
+	assert.Equal(t, []web.FieldError{
+		{Field: "horizon", Rule: "integer", Message: "must be an integer"},
+		{Field: "alpha", Rule: "number", Message: "must be a number"},
+		{Field: "backtest", Rule: "boolean", Message: "must be a boolean"},
+	}, problem.Errors)
+}
+
+func TestErrorHandlerProblemValidator(t *testing.T) {
+	// Arrange
+	type saleRequest struct {
+		InvoiceId int     `json:"invoice_id" binding:"required"`
+		Quantity  float64 `json:"quantity" binding:"gt=0"`
+	}
+
+	router, _ := NewRouter(newTestContainer())
+	router.POST("/test/sales", func(c *gin.Context) {
+		var body saleRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusCreated, body)
+	})
+	request := httptest.NewRequest(http.MethodPost, "/test/sales", strings.NewReader(`{"quantity": -1}`))
+	request.Header.Set("Accept", web.MIMEProblemJSON)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, request)
+
+	// Assert
+	var problem web.Problem
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &problem))
+	assert.Equal(t, "invalid_body", problem.Code)
+	assert.Equal(t, []web.FieldError{
+		{Field: "invoice_id", Rule: "required", Message: "is required"},
+		{Field: "quantity", Rule: "gt", Message: "must be greater than 0"},
+	}, problem.Errors)
+}
+
+func TestErrorHandlerHidesCauses(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	router.GET("/test/conflict", func(c *gin.Context) {
+		conflict := web.NewError(web.KindConflict, "sale_conflict", "sale already stored")
+		c.Error(fmt.Errorf("releasing sale 2: %w", conflict.Wrap(errors.New("Error 1062: Duplicate entry '2' for key 'sales.PRIMARY'"))))
+	})
+
+	for _, accept := range []string{gin.MIMEJSON, web.MIMEProblemJSON} {
+		request := httptest.NewRequest(http.MethodGet, "/test/conflict", nil)
+		request.Header.Set("Accept", accept)
+		response := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(response, request)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, response.Code, accept)
+		assert.Contains(t, response.Body.String(), `"sale already stored"`, accept)
+		assert.NotContains(t, response.Body.String(), "Duplicate entry", "the cause should not be answered")
+		assert.NotContains(t, response.Body.String(), "releasing sale", "the errors wrapping it should not be answered")
+	}
+}
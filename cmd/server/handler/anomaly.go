@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/anomaly"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+type AnomalyHandler struct {
+	anomalyService anomaly.AnomalyService
+}
+
+func NewAnomaly(anomalyService anomaly.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{
+		anomalyService: anomalyService,
+	}
+}
+
+func (h *AnomalyHandler) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		outlierConfig, err := outlierConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		anomalies, err := h.anomalyService.GetAnomalies(ctx, c.Query("source"), outlierConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, anomalies)
+	}
+}
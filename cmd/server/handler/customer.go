@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+type CustomerHandler struct {
+	customerService customer.CustomerService
+}
+
+func NewCustomer(customerService customer.CustomerService) *CustomerHandler {
+	return &CustomerHandler{
+		customerService: customerService,
+	}
+}
+
+// GetAll lists the customers, only the ones of an RFM segment with segment.
+func (h *CustomerHandler) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		rfmConfig, err := rfmConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		querySpec, err := web.ParseQuerySpec(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		customers, total, err := h.customerService.List(ctx, querySpec, rfmConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Paginated(c, http.StatusOK, customers, querySpec, total)
+	}
+}
+
+func (h *CustomerHandler) GetTotalByCondition() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		customerTotalByConditionDTO, err := h.customerService.GetTotalByCondition(ctx)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, customerTotalByConditionDTO)
+	}
+}
+
+func (h *CustomerHandler) GetCheaperProducts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		customerCheaperProducts, err := h.customerService.GetCustomerCheaperProducts(ctx)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, customerCheaperProducts)
+	}
+}
+
+func (h *CustomerHandler) GetRFM() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		rfmConfig, err := rfmConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		customersRFM, err := h.customerService.GetRFM(ctx, rfmConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, customersRFM)
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/pkg/health"
+	"github.com/matias-ziliotto/HackthonGo/pkg/version"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+var (
+	// Errors
+	ErrorNotReady = web.NewError(web.KindUnavailable, "not_ready", "service not ready")
+)
+
+// Status is the answer of the liveness probe.
+type Status struct {
+	Status string `json:"status"`
+}
+
+type HealthHandler struct {
+	readiness *health.Readiness
+}
+
+func NewHealth(readiness *health.Readiness) *HealthHandler {
+	return &HealthHandler{
+		readiness: readiness,
+	}
+}
+
+// Live answers while the process serves requests, it checks nothing else.
+func (h *HealthHandler) Live() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		web.Success(c, http.StatusOK, Status{Status: "ok"})
+	}
+}
+
+// Ready answers the readiness checks, failing with ErrorNotReady, naming
+// the ones failed, unless every one passes. Their errors are only logged.
+func (h *HealthHandler) Ready() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.readiness.Check(c.Request.Context())
+
+		if !report.Ready {
+			var failed, failedNames []string
+			for _, check := range report.Checks {
+				if !check.Ready {
+					failed = append(failed, check.Name+": "+check.Error)
+					failedNames = append(failedNames, check.Name)
+				}
+			}
+
+			notReady := ErrorNotReady.Wrap(errors.New(strings.Join(failed, "; ")))
+			notReady.Detail = "checks failed " + strings.Join(failedNames, ", ")
+			c.Error(notReady)
+			return
+		}
+
+		web.Success(c, http.StatusOK, report)
+	}
+}
+
+// Version answers the build of the running binary.
+func (h *HealthHandler) Version() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		web.Success(c, http.StatusOK, version.Get())
+	}
+}
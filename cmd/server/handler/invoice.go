@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+type InvoiceHandler struct {
+	invoiceService invoice.InvoiceService
+	cursorCodec    *web.CursorCodec
+}
+
+func NewInvoice(invoiceService invoice.InvoiceService, cursorCodec *web.CursorCodec) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+		cursorCodec:    cursorCodec,
+	}
+}
+
+// GetAll lists the invoices with cursor pagination.
+func (h *InvoiceHandler) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		querySpec, err := web.ParseQuerySpec(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		invoices, cursors, err := h.invoiceService.List(ctx, querySpec, h.cursorCodec)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.CursorPaginated(c, http.StatusOK, invoices, querySpec, cursors)
+	}
+}
+
+// GetCohorts answers the retention matrix as JSON, or as CSV when
+// format=csv or the request accepts text/csv.
+func (h *InvoiceHandler) GetCohorts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		cohorts, err := h.invoiceService.GetCohorts(ctx, c.Query("situation"))
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if c.Query("format") == "csv" || c.NegotiateFormat(gin.MIMEJSON, "text/csv") == "text/csv" {
+			web.CSV(c, http.StatusOK, "cohorts.csv", invoice.CohortsToCSV(cohorts))
+			return
+		}
+
+		web.Success(c, http.StatusOK, cohorts)
+	}
+}
+
+func (h *InvoiceHandler) GetForecast() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		forecastConfig, err := forecastConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		forecast, err := h.invoiceService.GetForecast(ctx, forecastConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, forecast)
+	}
+}
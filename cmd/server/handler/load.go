@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/anomaly"
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// LoadHandler loads the data files, it touches every domain.
+type LoadHandler struct {
+	productService  product.ProductService
+	customerService customer.CustomerService
+	invoiceService  invoice.InvoiceService
+	saleService     sale.SaleService
+	summaryService  summary.SummaryService
+	anomalyService  anomaly.AnomalyService
+}
+
+func NewLoad(productService product.ProductService, customerService customer.CustomerService, invoiceService invoice.InvoiceService,
+	saleService sale.SaleService, summaryService summary.SummaryService, anomalyService anomaly.AnomalyService) *LoadHandler {
+	return &LoadHandler{
+		productService:  productService,
+		customerService: customerService,
+		invoiceService:  invoiceService,
+		saleService:     saleService,
+		summaryService:  summaryService,
+		anomalyService:  anomalyService,
+	}
+}
+
+// loadQuery has the query params of the load, besides the ones of the
+// outliers it looks for.
+type loadQuery struct {
+	// flag reports outlier sales and invoices, hold also keeps outlier sales for review
+	Anomalies string `form:"anomalies" binding:"omitempty,oneof=flag hold"`
+}
+
+func (h *LoadHandler) Load() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// The params are checked before loading anything, an invalid one must
+		// not leave the data partly loaded.
+		var query loadQuery
+		if err := web.BindQuery(c, &query); err != nil {
+			c.Error(err)
+			return
+		}
+		anomaliesMode := query.Anomalies
+
+		outlierConfig, err := outlierConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		_, err = h.productService.StoreBulk(ctx)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		_, err = h.customerService.StoreBulk(ctx)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		invoicesStored, err := h.invoiceService.StoreBulk(ctx)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		var salesStored []domain.Sale
+		var saleAnomalies []domain.AnomalyDTO
+		if anomaliesMode == "" {
+			salesStored, err = h.saleService.StoreBulk(ctx)
+		} else {
+			salesStored, saleAnomalies, err = h.saleService.StoreBulkWithAnomalies(ctx, outlierConfig, anomaliesMode == "hold")
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		invoicesTotals, err := h.invoiceService.UpdateTotal(ctx)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		// Only the days of the new invoices and sales need their summaries refreshed
+		invoicesIds := make([]int, 0, len(invoicesStored)+len(salesStored)+len(invoicesTotals))
+		for _, invoiceStored := range invoicesStored {
+			invoicesIds = append(invoicesIds, invoiceStored.Id)
+		}
+		for _, saleStored := range salesStored {
+			invoicesIds = append(invoicesIds, saleStored.Invoice_id)
+		}
+		for _, invoiceTotal := range invoicesTotals {
+			invoicesIds = append(invoicesIds, invoiceTotal.Id)
+		}
+
+		_, err = h.summaryService.RefreshInvoices(ctx, invoicesIds)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		_, err = h.productService.UpdateABCClassification(ctx, product.ABCConfig{})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if anomaliesMode == "" {
+			web.Success(c, http.StatusOK, "Data loaded!")
+			return
+		}
+
+		// Only the invoices of this load are flagged, the others were before
+		invoicesStoredIds := make([]int, 0, len(invoicesStored))
+		for _, invoiceStored := range invoicesStored {
+			invoicesStoredIds = append(invoicesStoredIds, invoiceStored.Id)
+		}
+
+		invoiceAnomalies, err := h.anomalyService.GetInvoiceAnomalies(ctx, outlierConfig, invoicesStoredIds)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		heldSales, err := h.saleService.GetAllHeld(ctx)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, domain.LoadAnomaliesDTO{
+			Message:          "Data loaded!",
+			HeldSales:        heldSales,
+			SaleAnomalies:    saleAnomalies,
+			InvoiceAnomalies: invoiceAnomalies,
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"context"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -24,18 +24,107 @@ func (h *ProductHandler) Get() gin.HandlerFunc {
 		productId, err := strconv.Atoi(c.Param("id"))
 
 		if err != nil {
-			web.Error(c, 400, "invalid ID")
+			c.Error(web.InvalidParam("id", "integer", "must be an integer"))
 			return
 		}
 
-		ctx := context.Background()
+		ctx := c.Request.Context()
 		product, err := h.productService.Get(ctx, productId)
 
 		if err != nil {
-			web.Error(c, 404, err.Error())
+			c.Error(err)
 			return
 		}
 
 		web.Success(c, 200, product)
 	}
 }
+
+// GetAll lists the products, only the ones of an ABC class with class.
+func (h *ProductHandler) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		class := c.Query("class")
+		if class != "" && !product.ValidClass(class) {
+			c.Error(product.ErrorABCInvalidClass)
+			return
+		}
+
+		querySpec, err := web.ParseQuerySpec(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if class != "" {
+			querySpec = querySpec.WithFilter("class", web.OperatorEq, class)
+		}
+
+		products, total, err := h.productService.List(ctx, querySpec)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Paginated(c, http.StatusOK, products, querySpec, total)
+	}
+}
+
+func (h *ProductHandler) GetMostSelled() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		productsMostSelled, err := h.productService.GetProductsMostSelled(ctx)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, productsMostSelled)
+	}
+}
+
+func (h *ProductHandler) GetABC() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		abcConfig, err := abcConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		productsABC, err := h.productService.GetABCClassification(ctx, abcConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, productsABC)
+	}
+}
+
+func (h *ProductHandler) UpdateABC() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		abcConfig, err := abcConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		productsABC, err := h.productService.UpdateABCClassification(ctx, abcConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, productsABC)
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// The report params are bound as strings with the rule of their type, gin
+// fails on the numbers it can not parse without naming their param, and are
+// parsed once their rules pass.
+
+// rfmQuery has the query params of the RFM reports.
+type rfmQuery struct {
+	Buckets       string `form:"buckets" binding:"omitempty,integer"`
+	ReferenceDate string `form:"reference_date" binding:"omitempty,date"`
+	Situation     string `form:"situation"`
+	Segment       string `form:"segment"`
+}
+
+// rfmConfigFromQuery reads the optional buckets, reference_date (YYYY-MM-DD),
+// situation and segment query params.
+func rfmConfigFromQuery(c *gin.Context) (customer.RFMConfig, error) {
+	var query rfmQuery
+	if err := web.BindQuery(c, &query); err != nil {
+		return customer.RFMConfig{}, err
+	}
+
+	rfmConfig := customer.RFMConfig{
+		Situation: query.Situation,
+		Segment:   query.Segment,
+	}
+
+	if query.Buckets != "" {
+		rfmConfig.Buckets, _ = strconv.Atoi(query.Buckets)
+		if rfmConfig.Buckets < 2 || rfmConfig.Buckets > 10 {
+			return customer.RFMConfig{}, customer.ErrorRFMInvalidBuckets
+		}
+	}
+
+	if query.ReferenceDate != "" {
+		rfmConfig.ReferenceDate, _ = time.Parse(web.DateLayout, query.ReferenceDate)
+	}
+
+	return rfmConfig, nil
+}
+
+// basketQuery has the query params of the basket report.
+type basketQuery struct {
+	MinSupport    string `form:"min_support" binding:"omitempty,number"`
+	MinConfidence string `form:"min_confidence" binding:"omitempty,number"`
+	MinLift       string `form:"min_lift" binding:"omitempty,number"`
+	MaxItems      string `form:"max_items" binding:"omitempty,integer"`
+	From          string `form:"from" binding:"omitempty,date"`
+	To            string `form:"to" binding:"omitempty,date"`
+	Limit         string `form:"limit" binding:"omitempty,integer"`
+}
+
+// basketConfigFromQuery reads the optional min_support, min_confidence,
+// min_lift, max_items, from, to (YYYY-MM-DD) and limit query params.
+func basketConfigFromQuery(c *gin.Context) (sale.BasketConfig, error) {
+	var query basketQuery
+	if err := web.BindQuery(c, &query); err != nil {
+		return sale.BasketConfig{}, err
+	}
+
+	var basketConfig sale.BasketConfig
+	basketConfig.MinSupport = parseFloat(query.MinSupport)
+	basketConfig.MinConfidence = parseFloat(query.MinConfidence)
+	basketConfig.MinLift = parseFloat(query.MinLift)
+	basketConfig.MaxItems = parseInt(query.MaxItems)
+	basketConfig.Limit = parseInt(query.Limit)
+
+	if query.From != "" {
+		basketConfig.From = query.From + " 00:00:00"
+	}
+
+	if query.To != "" {
+		basketConfig.To = query.To + " 23:59:59"
+	}
+
+	return basketConfig, nil
+}
+
+// abcQuery has the query params of the ABC classification.
+type abcQuery struct {
+	CutOffA string `form:"cut_off_a" binding:"omitempty,number"`
+	CutOffB string `form:"cut_off_b" binding:"omitempty,number"`
+}
+
+// abcConfigFromQuery reads the optional cut_off_a and cut_off_b query params.
+func abcConfigFromQuery(c *gin.Context) (product.ABCConfig, error) {
+	var query abcQuery
+	if err := web.BindQuery(c, &query); err != nil {
+		return product.ABCConfig{}, err
+	}
+
+	return product.ABCConfig{
+		CutOffA: parseFloat(query.CutOffA),
+		CutOffB: parseFloat(query.CutOffB),
+	}, nil
+}
+
+// forecastQuery has the query params of the sales forecast.
+type forecastQuery struct {
+	Model        string `form:"model"`
+	Granularity  string `form:"granularity"`
+	Horizon      string `form:"horizon" binding:"omitempty,integer"`
+	Window       string `form:"window" binding:"omitempty,integer"`
+	SeasonLength string `form:"season_length" binding:"omitempty,integer"`
+	Alpha        string `form:"alpha" binding:"omitempty,number"`
+	Beta         string `form:"beta" binding:"omitempty,number"`
+	Gamma        string `form:"gamma" binding:"omitempty,number"`
+	Confidence   string `form:"confidence" binding:"omitempty,number"`
+	Backtest     string `form:"backtest" binding:"omitempty,boolean"`
+}
+
+// forecastConfigFromQuery reads the optional model, granularity, horizon,
+// window, season_length, alpha, beta, gamma, confidence and backtest query
+// params.
+func forecastConfigFromQuery(c *gin.Context) (invoice.ForecastConfig, error) {
+	var query forecastQuery
+	if err := web.BindQuery(c, &query); err != nil {
+		return invoice.ForecastConfig{}, err
+	}
+
+	return invoice.ForecastConfig{
+		Model:        query.Model,
+		Granularity:  query.Granularity,
+		Horizon:      parseInt(query.Horizon),
+		Window:       parseInt(query.Window),
+		SeasonLength: parseInt(query.SeasonLength),
+		Alpha:        parseFloat(query.Alpha),
+		Beta:         parseFloat(query.Beta),
+		Gamma:        parseFloat(query.Gamma),
+		Confidence:   parseFloat(query.Confidence),
+		Backtest:     parseBool(query.Backtest),
+	}, nil
+}
+
+// outlierQuery has the query params of the anomalies reports.
+type outlierQuery struct {
+	Method       string `form:"method" binding:"omitempty,oneof=zscore iqr"`
+	Threshold    string `form:"threshold" binding:"omitempty,number"`
+	PerProduct   string `form:"per_product" binding:"omitempty,boolean"`
+	MinGroupSize string `form:"min_group_size" binding:"omitempty,integer"`
+}
+
+// outlierConfigFromQuery reads the optional method, threshold, per_product
+// and min_group_size query params.
+func outlierConfigFromQuery(c *gin.Context) (outlier.Config, error) {
+	var query outlierQuery
+	if err := web.BindQuery(c, &query); err != nil {
+		return outlier.Config{}, err
+	}
+
+	outlierConfig, err := outlier.Config{
+		Method:       query.Method,
+		Threshold:    parseFloat(query.Threshold),
+		PerGroup:     parseBool(query.PerProduct),
+		MinGroupSize: parseInt(query.MinGroupSize),
+	}.WithDefaults()
+	if err != nil {
+		// The errors of the outlier package are only their messages
+		invalidParam := web.ErrorInvalidParam.Wrap(err)
+		invalidParam.Detail = err.Error()
+
+		return outlier.Config{}, invalidParam
+	}
+
+	return outlierConfig, nil
+}
+
+// parseInt, parseFloat and parseBool parse the params their binding rules
+// checked, an empty one is zero.
+func parseInt(param string) int {
+	value, _ := strconv.Atoi(param)
+	return value
+}
+
+func parseFloat(param string) float64 {
+	value, _ := strconv.ParseFloat(param, 64)
+	return value
+}
+
+func parseBool(param string) bool {
+	value, _ := strconv.ParseBool(param)
+	return value
+}
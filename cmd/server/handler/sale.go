@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+type SaleHandler struct {
+	saleService    sale.SaleService
+	invoiceService invoice.InvoiceService
+	summaryService summary.SummaryService
+	productService product.ProductService
+	cursorCodec    *web.CursorCodec
+}
+
+// NewSale needs the invoices, summaries and products services too, releasing
+// a held sale changes the total and the summaries of its invoice and the ABC
+// classes of the products.
+func NewSale(saleService sale.SaleService, invoiceService invoice.InvoiceService, summaryService summary.SummaryService, productService product.ProductService, cursorCodec *web.CursorCodec) *SaleHandler {
+	return &SaleHandler{
+		saleService:    saleService,
+		invoiceService: invoiceService,
+		summaryService: summaryService,
+		productService: productService,
+		cursorCodec:    cursorCodec,
+	}
+}
+
+// GetAll lists the sales with cursor pagination.
+func (h *SaleHandler) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		querySpec, err := web.ParseQuerySpec(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		sales, cursors, err := h.saleService.List(ctx, querySpec, h.cursorCodec)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.CursorPaginated(c, http.StatusOK, sales, querySpec, cursors)
+	}
+}
+
+func (h *SaleHandler) GetBasket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		basketConfig, err := basketConfigFromQuery(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		productAssociations, err := h.saleService.GetProductAssociations(ctx, basketConfig)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, productAssociations)
+	}
+}
+
+func (h *SaleHandler) GetHeld() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		heldSales, err := h.saleService.GetAllHeld(ctx)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, heldSales)
+	}
+}
+
+// ReleaseHeld stores a held sale and recalculates the total and the
+// summaries of its invoice and the ABC classes, as the load does.
+func (h *SaleHandler) ReleaseHeld() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		saleId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.Error(web.InvalidParam("id", "integer", "must be an integer"))
+			return
+		}
+
+		saleReleased, err := h.saleService.ReleaseHeld(ctx, saleId)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		_, err = h.invoiceService.RecalculateTotal(ctx, saleReleased.Invoice_id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		_, err = h.summaryService.RefreshInvoices(ctx, []int{saleReleased.Invoice_id})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		_, err = h.productService.UpdateABCClassification(ctx, product.ABCConfig{})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, saleReleased)
+	}
+}
+
+func (h *SaleHandler) RejectHeld() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		saleId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.Error(web.InvalidParam("id", "integer", "must be an integer"))
+			return
+		}
+
+		err = h.saleService.RejectHeld(ctx, saleId)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		web.Success(c, http.StatusOK, "Held sale rejected!")
+	}
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+type StatsHandler struct {
+	poolStats func() storage.PoolStats
+}
+
+func NewStats(poolStats func() storage.PoolStats) *StatsHandler {
+	return &StatsHandler{
+		poolStats: poolStats,
+	}
+}
+
+// GetDB returns the stats of the DB connections pool, for monitoring.
+func (h *StatsHandler) GetDB() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		web.Success(c, http.StatusOK, h.poolStats())
+	}
+}
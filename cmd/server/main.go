@@ -3,144 +3,56 @@ package main
 import (
 	"context"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/gin-gonic/gin"
-	"github.com/matias-ziliotto/HackthonGo/internal/customer"
-	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
-	"github.com/matias-ziliotto/HackthonGo/internal/product"
-	"github.com/matias-ziliotto/HackthonGo/internal/sale"
-	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
-	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
 )
 
 func main() {
-	router := gin.Default()
-
-	router.GET("/load-files", LoadData())
-	router.GET("/customers/total-by-condition", GetCustomersTotalByCondition())
-	router.GET("/products/top/most-selled", GetProductsMostSelled())
-	router.GET("/customers/top/cheaper-products", GetCustomersCheaperProducts())
-
-	if err := router.Run(); err != nil {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
 		log.Fatal(err)
 	}
-}
-
-func LoadData() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := context.Background()
-		dbProduct := sql.MySqlDB
-		dbCustomer := sql.MySqlDB
-		dbInvoice := sql.MySqlDB
-		dbSale := sql.MySqlDB
-
-		// Products
-		productRepository := product.NewProductRepository(dbProduct)
-		productService := product.NewProductService(productRepository)
 
-		// Customers
-		customerRepository := customer.NewCustomerRepository(dbCustomer)
-		customerService := customer.NewCustomerService(customerRepository)
-
-		// Invoices
-		invoiceRepository := invoice.NewInvoiceRepository(dbInvoice)
-		invoiceService := invoice.NewInvoiceService(invoiceRepository)
-
-		// Sales
-		saleRepository := sale.NewSaleRepository(dbSale)
-		saleService := sale.NewSaleService(saleRepository)
-
-		_, err := productService.StoreBulk(ctx)
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		_, err = customerService.StoreBulk(ctx)
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		_, err = invoiceService.StoreBulk(ctx)
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		_, err = saleService.StoreBulk(ctx)
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		_, err = invoiceService.UpdateTotal(context.Background())
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
+	// Done on SIGTERM or SIGINT, which stop the server and the DB setup
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-		web.Success(c, http.StatusOK, "Data loaded!")
+	db, err := storage.OpenWithOptions(ctx, cfg.DB.Driver, cfg.DB.ConnectionString(), cfg.DB.Options())
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-
-func GetCustomersTotalByCondition() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := context.Background()
-		dbCustomer := sql.MySqlDB
-
-		// Customers
-		customerRepository := customer.NewCustomerRepository(dbCustomer)
-		customerService := customer.NewCustomerService(customerRepository)
-
-		customerTotalByConditionDTO, err := customerService.GetTotalByCondition(ctx)
 
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
+	if cfg.DB.Migrate() {
+		if err = db.Migrate(ctx); err != nil {
+			db.Close()
+			log.Fatal(err)
 		}
-
-		web.Success(c, http.StatusOK, customerTotalByConditionDTO)
 	}
-}
-
-func GetProductsMostSelled() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := context.Background()
-		dbProducts := sql.MySqlDB
-
-		// Products
-		productRepository := product.NewProductRepository(dbProducts)
-		productService := product.NewProductService(productRepository)
+	log.Println("DB ready")
 
-		productsMostSelled, err := productService.GetProductsMostSelled(ctx)
-
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		web.Success(c, http.StatusOK, productsMostSelled)
+	deps := NewContainer(db, cfg)
+	router, apiDocs := NewRouter(deps)
+	if undocumented := apiDocs.Build(router.Routes()); len(undocumented) > 0 {
+		log.Printf("routes missing from the API docs: %v", undocumented)
 	}
-}
 
-func GetCustomersCheaperProducts() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := context.Background()
-		dbCustomer := sql.MySqlDB
+	server := NewServer(cfg.Server, router)
+	deps.Readiness.Register("server", server.Ready)
 
-		// Customers
-		customerRepository := customer.NewCustomerRepository(dbCustomer)
-		customerService := customer.NewCustomerService(customerRepository)
+	log.Printf("Listening on %s", cfg.Server.Addr())
+	err = server.Run(ctx)
 
-		customerCheaperProducts, err := customerService.GetCustomerCheaperProducts(ctx)
-
-		if err != nil {
-			web.Error(c, http.StatusInternalServerError, err.Error())
-			return
-		}
+	// The requests are over, none is using the DB anymore
+	if closeErr := db.Close(); closeErr != nil {
+		log.Printf("closing the DB: %v", closeErr)
+	}
 
-		web.Success(c, http.StatusOK, customerCheaperProducts)
+	if err != nil {
+		log.Fatal(err)
 	}
+	log.Println("Server stopped")
 }
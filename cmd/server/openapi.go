@@ -0,0 +1,281 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/matias-ziliotto/HackthonGo/cmd/server/handler"
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/pkg/health"
+	"github.com/matias-ziliotto/HackthonGo/pkg/openapi"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/version"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// describeRoutes documents every route registered by NewRouter. A route
+// without description makes TestOpenAPIDocumentsEveryRoute fail.
+func describeRoutes(apiDocs *openapi.Registry) {
+	// Data
+	apiDocs.Describe(http.MethodGet, "/load-files", openapi.Route{
+		Summary: "Load the data files, data is the load message unless anomalies is set",
+		Tags:    []string{"data"},
+		Query: append([]openapi.Param{
+			{Name: "anomalies", Description: "flag reports outlier sales and invoices, hold also keeps outlier sales for review"},
+		}, outlierParams...),
+		Data:   domain.LoadAnomaliesDTO{},
+		Errors: []int{http.StatusBadRequest},
+	})
+
+	// Customers
+	apiDocs.Describe(http.MethodGet, "/customers", openapi.Route{
+		Summary:   "List customers",
+		Tags:      []string{"customers"},
+		Query:     append(listParams(customer.CustomerQueryColumns, customer.CustomerQueryColumns, false), rfmParams...),
+		Data:      []domain.Customer{},
+		Paginated: true,
+		Errors:    []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodGet, "/customers/total-by-condition", openapi.Route{
+		Summary: "Invoices total by customer situation",
+		Tags:    []string{"customers", "reports"},
+		Data:    []domain.CustomerTotalByConditionDTO{},
+	})
+	apiDocs.Describe(http.MethodGet, "/customers/top/cheaper-products", openapi.Route{
+		Summary: "Customers who bought the cheapest products",
+		Tags:    []string{"customers", "reports"},
+		Data:    []domain.CustomerCheaperProductDTO{},
+	})
+	apiDocs.Describe(http.MethodGet, "/reports/customers/rfm", openapi.Route{
+		Summary: "Recency, frequency and monetary scores and segments of the customers",
+		Tags:    []string{"customers", "reports"},
+		Query:   rfmParams,
+		Data:    []domain.CustomerRFMDTO{},
+		Errors:  []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodGet, "/reports/customers/cohorts", openapi.Route{
+		Summary: "Retention of the customers by month of first purchase",
+		Tags:    []string{"customers", "reports"},
+		Query: []openapi.Param{
+			{Name: "situation", Description: "only customers in this situation"},
+			{Name: "format", Description: "csv answers text/csv, as does accepting it"},
+		},
+		Data: []domain.CohortDTO{},
+		CSV:  true,
+	})
+
+	// Products
+	apiDocs.Describe(http.MethodGet, "/products", openapi.Route{
+		Summary: "List products",
+		Tags:    []string{"products"},
+		Query: append(listParams(product.ProductQueryColumns, product.ProductQueryColumns, false), openapi.Param{
+			Name: "class", Description: "ABC class, A, B or C",
+		}),
+		Data:      []domain.Product{},
+		Paginated: true,
+		Errors:    []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodGet, "/products/:id", openapi.Route{
+		Summary: "Get a product",
+		Tags:    []string{"products"},
+		Data:    domain.Product{},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	apiDocs.Describe(http.MethodGet, "/products/top/most-selled", openapi.Route{
+		Summary: "Products sold the most times",
+		Tags:    []string{"products", "reports"},
+		Data:    []domain.ProductMostSelledDTO{},
+	})
+	apiDocs.Describe(http.MethodGet, "/reports/products/basket", openapi.Route{
+		Summary: "Products bought together",
+		Tags:    []string{"products", "reports"},
+		Query: []openapi.Param{
+			{Name: "min_support", Type: openapi.TypeNumber, Description: "share of invoices with the itemset, 0.01 when not set"},
+			{Name: "min_confidence", Type: openapi.TypeNumber},
+			{Name: "min_lift", Type: openapi.TypeNumber},
+			{Name: "max_items", Type: openapi.TypeInteger, Description: "items of an itemset, 2 to 4"},
+			{Name: "from", Description: "first day of the invoices, YYYY-MM-DD"},
+			{Name: "to", Description: "last day of the invoices, YYYY-MM-DD"},
+			{Name: "limit", Type: openapi.TypeInteger},
+		},
+		Data:   []domain.ProductAssociationDTO{},
+		Errors: []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodGet, "/reports/products/abc", openapi.Route{
+		Summary: "ABC classification of the products by revenue",
+		Tags:    []string{"products", "reports"},
+		Query:   abcParams,
+		Data:    []domain.ProductABCDTO{},
+		Errors:  []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodPost, "/products/abc-classification", openapi.Route{
+		Summary: "Calculate and store the ABC class of every product",
+		Tags:    []string{"products"},
+		Query:   abcParams,
+		Data:    []domain.ProductABCDTO{},
+		Errors:  []int{http.StatusBadRequest},
+	})
+
+	// Sales
+	apiDocs.Describe(http.MethodGet, "/sales", openapi.Route{
+		Summary:   "List sales, with cursor pagination",
+		Tags:      []string{"sales"},
+		Query:     listParams(sale.SaleQueryColumns, sale.SaleSortColumns, true),
+		Data:      []domain.Sale{},
+		Paginated: true,
+		Errors:    []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodGet, "/reports/sales/forecast", openapi.Route{
+		Summary: "Revenue forecast",
+		Tags:    []string{"sales", "reports"},
+		Query: []openapi.Param{
+			{Name: "model", Description: "moving_average, holt_winters or seasonal_naive"},
+			{Name: "granularity", Description: "day or week"},
+			{Name: "horizon", Type: openapi.TypeInteger},
+			{Name: "window", Type: openapi.TypeInteger},
+			{Name: "season_length", Type: openapi.TypeInteger},
+			{Name: "alpha", Type: openapi.TypeNumber},
+			{Name: "beta", Type: openapi.TypeNumber},
+			{Name: "gamma", Type: openapi.TypeNumber},
+			{Name: "confidence", Type: openapi.TypeNumber, Description: "0.8, 0.9, 0.95 or 0.99"},
+			{Name: "backtest", Type: openapi.TypeBoolean},
+		},
+		Data:   domain.ForecastDTO{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	})
+	apiDocs.Describe(http.MethodGet, "/reports/anomalies", openapi.Route{
+		Summary: "Outlier sale quantities or invoice totals",
+		Tags:    []string{"sales", "reports"},
+		Query: append([]openapi.Param{
+			{Name: "source", Description: "sale_quantity or invoice_total"},
+		}, outlierParams...),
+		Data:   []domain.AnomalyDTO{},
+		Errors: []int{http.StatusBadRequest},
+	})
+	apiDocs.Describe(http.MethodGet, "/sales/held", openapi.Route{
+		Summary: "Sales held for review while loading",
+		Tags:    []string{"sales"},
+		Data:    []domain.HeldSaleDTO{},
+	})
+	apiDocs.Describe(http.MethodPost, "/sales/held/:id/release", openapi.Route{
+		Summary: "Store a held sale",
+		Tags:    []string{"sales"},
+		Data:    domain.Sale{},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	})
+	apiDocs.Describe(http.MethodDelete, "/sales/held/:id", openapi.Route{
+		Summary: "Discard a held sale",
+		Tags:    []string{"sales"},
+		Data:    "",
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+
+	// Invoices
+	apiDocs.Describe(http.MethodGet, "/invoices", openapi.Route{
+		Summary:   "List invoices, with cursor pagination",
+		Tags:      []string{"invoices"},
+		Query:     listParams(invoice.InvoiceQueryColumns, invoice.InvoiceSortColumns, true),
+		Data:      []domain.Invoice{},
+		Paginated: true,
+		Errors:    []int{http.StatusBadRequest},
+	})
+
+	// Monitoring
+	apiDocs.Describe(http.MethodGet, "/stats/db", openapi.Route{
+		Summary: "Stats of the DB connections pool and of the statements retried",
+		Tags:    []string{"monitoring"},
+		Data:    storage.PoolStats{},
+	})
+	apiDocs.Describe(http.MethodGet, "/healthz", openapi.Route{
+		Summary: "Liveness probe, answers while the process serves requests",
+		Tags:    []string{"monitoring"},
+		Data:    handler.Status{},
+	})
+	apiDocs.Describe(http.MethodGet, "/readyz", openapi.Route{
+		Summary: "Readiness probe, ready when the DB is reachable, every migration is applied and the server is not shutting down",
+		Tags:    []string{"monitoring"},
+		Data:    health.Report{},
+		Errors:  []int{http.StatusServiceUnavailable},
+	})
+	apiDocs.Describe(http.MethodGet, "/version", openapi.Route{
+		Summary: "Build of the running binary",
+		Tags:    []string{"monitoring"},
+		Data:    version.Info{},
+	})
+
+	// Docs
+	apiDocs.Describe(http.MethodGet, "/openapi.json", openapi.Route{
+		Summary: "This document",
+		Tags:    []string{"docs"},
+		Data:    openapi.Document{},
+		Raw:     true,
+	})
+	apiDocs.Describe(http.MethodGet, "/docs", openapi.Route{
+		Summary: "Browsable API docs",
+		Tags:    []string{"docs"},
+		HTML:    true,
+	})
+}
+
+var (
+	rfmParams = []openapi.Param{
+		{Name: "buckets", Type: openapi.TypeInteger, Description: "scores of every dimension, 2 to 10"},
+		{Name: "reference_date", Description: "day recency is measured from, YYYY-MM-DD"},
+		{Name: "situation", Description: "only customers in this situation"},
+		{Name: "segment", Description: "only customers in this RFM segment"},
+	}
+
+	abcParams = []openapi.Param{
+		{Name: "cut_off_a", Type: openapi.TypeNumber, Description: "cumulative revenue share of class A"},
+		{Name: "cut_off_b", Type: openapi.TypeNumber, Description: "cumulative revenue share of classes A and B"},
+	}
+
+	outlierParams = []openapi.Param{
+		{Name: "method", Description: "zscore or iqr"},
+		{Name: "threshold", Type: openapi.TypeNumber},
+		{Name: "per_product", Type: openapi.TypeBoolean},
+		{Name: "min_group_size", Type: openapi.TypeInteger},
+	}
+)
+
+// listParams returns the pagination, sorting and filtering params of a
+// listing with the given allow-lists.
+func listParams(columns web.QueryColumns, sortColumns web.QueryColumns, cursor bool) []openapi.Param {
+	fields := columnFields(columns)
+
+	params := []openapi.Param{
+		{Name: "size", Type: openapi.TypeInteger, Description: "rows of a page, up to 500"},
+		{Name: "sort", Description: "comma separated fields, descending when prefixed with -, out of " + strings.Join(columnFields(sortColumns), ", ")},
+	}
+
+	if cursor {
+		params = append(params, openapi.Param{Name: "cursor", Description: "next_cursor or prev_cursor of a previous page"})
+	} else {
+		params = append(params, openapi.Param{Name: "page", Type: openapi.TypeInteger})
+	}
+
+	for _, field := range fields {
+		params = append(params, openapi.Param{
+			Name:        field + "[eq]",
+			Description: "filter by " + field + ", any of the eq, ne, gt, gte, lt, lte, like and in operators",
+		})
+	}
+
+	return params
+}
+
+// columnFields are the fields of an allow-list, sorted.
+func columnFields(columns web.QueryColumns) []string {
+	fields := make([]string, 0, len(columns))
+	for field := range columns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matias-ziliotto/HackthonGo/pkg/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	// Arrange
+	router, apiDocs := NewRouter(newTestContainer())
+
+	// Act
+	undocumented := apiDocs.Build(router.Routes())
+	document := apiDocs.Document()
+
+	// Assert
+	assert.Empty(t, undocumented)
+	for _, route := range router.Routes() {
+		path := route.Path
+		for _, part := range strings.Split(path, "/") {
+			if strings.HasPrefix(part, ":") {
+				path = strings.Replace(path, part, "{"+part[1:]+"}", 1)
+			}
+		}
+
+		operations, ok := document.Paths[path]
+		assert.True(t, ok, route.Path)
+		_, ok = operations[strings.ToLower(route.Method)]
+		assert.True(t, ok, route.Method+" "+route.Path)
+	}
+	assert.Contains(t, document.Components.Schemas, "ErrorResponse")
+}
+
+func TestOpenAPIServesDocument(t *testing.T) {
+	// Arrange
+	router, apiDocs := NewRouter(newTestContainer())
+	apiDocs.Build(router.Routes())
+	request := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, request)
+
+	// Assert
+	var document openapi.Document
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &document))
+	assert.Equal(t, openapi.Version, document.OpenAPI)
+	assert.Contains(t, document.Paths, "/sales/held/{id}/release")
+}
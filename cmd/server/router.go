@@ -0,0 +1,108 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/cmd/server/handler"
+	"github.com/matias-ziliotto/HackthonGo/pkg/openapi"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// NewRouter registers every route on the services of deps. The API docs
+// describe them, and have to be built once the routes are registered.
+func NewRouter(deps *Container) (*gin.Engine, *openapi.Registry) {
+	router := gin.Default()
+	router.Use(web.ErrorHandler(), deps.Deadlines.Handler())
+	web.RegisterJSONFieldNames()
+
+	apiDocs := openapi.NewRegistry(openapi.Info{Title: "HackthonGo API", Version: "1.0.0"})
+	describeRoutes(apiDocs)
+
+	reportCache := deps.ReportCache
+	cached := cachedReport(reportCache, deps.ReportsFromPrimary)
+	// The writes read what they wrote, which the replicas may not have yet
+	onPrimary := readFromPrimary()
+
+	// Handlers
+	loadHandler := handler.NewLoad(deps.ProductService, deps.CustomerService, deps.InvoiceService, deps.SaleService, deps.SummaryService, deps.AnomalyService)
+	productHandler := handler.NewProduct(deps.ProductService)
+	customerHandler := handler.NewCustomer(deps.CustomerService)
+	invoiceHandler := handler.NewInvoice(deps.InvoiceService, deps.CursorCodec)
+	saleHandler := handler.NewSale(deps.SaleService, deps.InvoiceService, deps.SummaryService, deps.ProductService, deps.CursorCodec)
+	anomalyHandler := handler.NewAnomaly(deps.AnomalyService)
+	statsHandler := handler.NewStats(deps.PoolStats)
+	healthHandler := handler.NewHealth(deps.Readiness)
+
+	// The load runs up to its deadline even on shutdown, its steps are not
+	// in one transaction and aborting it would leave the data half loaded
+	router.GET("/load-files", web.Uninterruptible(), onPrimary, reportCache.Invalidate(), loadHandler.Load())
+
+	// Customers
+	router.GET("/customers", customerHandler.GetAll())
+	router.GET("/customers/total-by-condition", cached("customers-total-by-condition"), customerHandler.GetTotalByCondition())
+	router.GET("/customers/top/cheaper-products", cached("customers-cheaper-products"), customerHandler.GetCheaperProducts())
+	router.GET("/reports/customers/rfm", cached("customers-rfm"), customerHandler.GetRFM())
+	router.GET("/reports/customers/cohorts", cached("customers-cohorts"), invoiceHandler.GetCohorts())
+
+	// Products
+	router.GET("/products", productHandler.GetAll())
+	router.GET("/products/:id", productHandler.Get())
+	router.GET("/products/top/most-selled", cached("products-most-selled"), productHandler.GetMostSelled())
+	router.GET("/reports/products/basket", cached("products-basket"), saleHandler.GetBasket())
+	router.GET("/reports/products/abc", cached("products-abc"), productHandler.GetABC())
+	router.POST("/products/abc-classification", onPrimary, reportCache.Invalidate(), productHandler.UpdateABC())
+
+	// Sales
+	router.GET("/sales", saleHandler.GetAll())
+	router.GET("/sales/held", saleHandler.GetHeld())
+	// The release runs up to its deadline too, its total and summaries are
+	// recalculated after the sale is stored and not in its transaction
+	router.POST("/sales/held/:id/release", web.Uninterruptible(), onPrimary, reportCache.Invalidate(), saleHandler.ReleaseHeld())
+	router.DELETE("/sales/held/:id", onPrimary, reportCache.Invalidate(), saleHandler.RejectHeld())
+	router.GET("/reports/sales/forecast", cached("sales-forecast"), invoiceHandler.GetForecast())
+	router.GET("/reports/anomalies", cached("anomalies"), anomalyHandler.GetAll())
+
+	// Invoices
+	router.GET("/invoices", invoiceHandler.GetAll())
+
+	// Monitoring
+	router.GET("/stats/db", statsHandler.GetDB())
+	router.GET("/healthz", healthHandler.Live())
+	router.GET("/readyz", healthHandler.Ready())
+	router.GET("/version", healthHandler.Version())
+
+	// Docs
+	router.GET("/openapi.json", apiDocs.JSONHandler())
+	router.GET("/docs", apiDocs.UIHandler("/openapi.json"))
+
+	return router, apiDocs
+}
+
+// readFromPrimary sends the reads of the request to the primary DB instead
+// of its replicas.
+func readFromPrimary() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(storage.WithPrimary(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// cachedReport caches the report like ResponseCache.Cached, its reads going
+// to the primary for fromPrimary after a purge, always when negative. A
+// replica may not have the writes purging the cache yet, and its stale
+// result would be cached until the next purge.
+func cachedReport(reportCache *web.ResponseCache, fromPrimary time.Duration) func(report string) gin.HandlerFunc {
+	return func(report string) gin.HandlerFunc {
+		cached := reportCache.Cached(report)
+
+		return func(c *gin.Context) {
+			if fromPrimary < 0 || reportCache.PurgedWithin(fromPrimary) {
+				c.Request = c.Request.WithContext(storage.WithPrimary(c.Request.Context()))
+			}
+
+			cached(c)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/cache"
+	"github.com/matias-ziliotto/HackthonGo/pkg/health"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/version"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+// The fake services embed their interface, calling a method they do not
+// implement panics.
+
+type fakeProductService struct {
+	product.ProductService
+	products   []domain.Product
+	storedBulk bool
+	abcUpdated bool
+	// abcFromPrimary has whether every ABC report read from the primary
+	abcFromPrimary []bool
+	// started is closed once StoreBulk starts, which then takes storeDelay
+	started    chan struct{}
+	storeDelay time.Duration
+}
+
+func (s *fakeProductService) Get(ctx context.Context, id int) (domain.Product, error) {
+	for _, product := range s.products {
+		if product.Id == id {
+			return product, nil
+		}
+	}
+
+	return domain.Product{}, product.ErrorProductNotFound
+}
+
+func (s *fakeProductService) StoreBulk(ctx context.Context) ([]domain.Product, error) {
+	s.storedBulk = true
+	if s.started != nil {
+		close(s.started)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.storeDelay):
+	}
+
+	return nil, nil
+}
+
+func (s *fakeProductService) GetABCClassification(ctx context.Context, config product.ABCConfig) ([]domain.ProductABCDTO, error) {
+	s.abcFromPrimary = append(s.abcFromPrimary, storage.ReadsFromPrimary(ctx))
+
+	return nil, nil
+}
+
+func (s *fakeProductService) UpdateABCClassification(ctx context.Context, config product.ABCConfig) ([]domain.ProductABCDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.abcUpdated = true
+
+	return nil, nil
+}
+
+func (s *fakeProductService) List(ctx context.Context, spec web.QuerySpec) ([]domain.Product, int, error) {
+	return s.products, len(s.products), nil
+}
+
+type fakeCustomerService struct {
+	customer.CustomerService
+}
+
+func (s *fakeCustomerService) StoreBulk(ctx context.Context) ([]domain.Customer, error) {
+	return nil, ctx.Err()
+}
+
+type fakeSaleService struct {
+	sale.SaleService
+	heldSales map[int]domain.Sale
+	stored    map[int]bool
+}
+
+func (s *fakeSaleService) StoreBulk(ctx context.Context) ([]domain.Sale, error) {
+	return nil, ctx.Err()
+}
+
+func (s *fakeSaleService) ReleaseHeld(ctx context.Context, id int) (domain.Sale, error) {
+	heldSale, ok := s.heldSales[id]
+	if !ok {
+		return domain.Sale{}, sale.ErrorSaleNotFound
+	}
+
+	if s.stored[id] {
+		return domain.Sale{}, sale.ErrorSaleAlreadyStored
+	}
+
+	return heldSale, nil
+}
+
+type fakeInvoiceService struct {
+	invoice.InvoiceService
+	recalculated []int
+}
+
+func (s *fakeInvoiceService) StoreBulk(ctx context.Context) ([]domain.Invoice, error) {
+	return nil, ctx.Err()
+}
+
+func (s *fakeInvoiceService) UpdateTotal(ctx context.Context) ([]domain.InvoiceTotalDTO, error) {
+	return nil, ctx.Err()
+}
+
+func (s *fakeInvoiceService) RecalculateTotal(ctx context.Context, id int) (domain.InvoiceTotalDTO, error) {
+	s.recalculated = append(s.recalculated, id)
+
+	return domain.InvoiceTotalDTO{Id: id}, nil
+}
+
+type fakeSummaryService struct {
+	summary.SummaryService
+	refreshed []int
+}
+
+func (s *fakeSummaryService) RefreshInvoices(ctx context.Context, invoicesIds []int) ([]string, error) {
+	s.refreshed = append(s.refreshed, invoicesIds...)
+
+	return nil, nil
+}
+
+func newTestContainer() *Container {
+	gin.SetMode(gin.TestMode)
+
+	return &Container{
+		ProductService:  &fakeProductService{products: []domain.Product{{Id: 1, Description: "Lemon", Price: 10.5, Class: "A"}}},
+		CustomerService: &fakeCustomerService{},
+		SaleService: &fakeSaleService{
+			heldSales: map[int]domain.Sale{2: {Id: 2, Invoice_id: 72, Product_id: 53, Quantity: 44618}, 3: {Id: 3, Invoice_id: 72, Product_id: 1, Quantity: 9000}},
+			stored:    map[int]bool{3: true},
+		},
+		InvoiceService: &fakeInvoiceService{},
+		SummaryService: &fakeSummaryService{},
+		ReportCache:    web.NewResponseCache(cache.NewLRU(cache.DefaultLRUCapacity)),
+		CursorCodec:    web.NewCursorCodec([]byte("secret")),
+		PoolStats: func() storage.PoolStats {
+			return storage.PoolStats{MaxOpenConnections: 25, OpenConnections: 2, Retries: 1}
+		},
+		Readiness: health.NewReadiness(time.Second),
+	}
+}
+
+func TestRouterGetProducts(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/products", nil))
+
+	// Assert
+	var body struct {
+		Data []domain.Product `json:"data"`
+		Meta web.PageMeta     `json:"meta"`
+	}
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, []domain.Product{{Id: 1, Description: "Lemon", Price: 10.5, Class: "A"}}, body.Data)
+	assert.Equal(t, 1, *body.Meta.Total)
+}
+
+func TestRouterGetProductNotFound(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/products/7", nil))
+
+	// Assert
+	var errorResponse web.ErrorResponse
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "product_not_found", errorResponse.Code)
+}
+
+func TestRouterGetDBStats(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/stats/db", nil))
+
+	// Assert
+	var body struct {
+		Data storage.PoolStats `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, storage.PoolStats{MaxOpenConnections: 25, OpenConnections: 2, Retries: 1}, body.Data)
+}
+
+func TestRouterHealthz(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	deps.Readiness.Register("db", func(ctx context.Context) error { return errors.New("db not reachable") })
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, response.Code, "the process should be alive even when not ready")
+	assert.JSONEq(t, `{"data":{"status":"ok"}}`, response.Body.String())
+}
+
+func TestRouterReadyz(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	deps.Readiness.Register("db", func(ctx context.Context) error { return nil })
+	deps.Readiness.Register("migrations", func(ctx context.Context) error { return nil })
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert
+	var body struct {
+		Data health.Report `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.True(t, body.Data.Ready, "service should be ready")
+	assert.Equal(t, 2, len(body.Data.Checks), "every check should be reported")
+}
+
+func TestRouterReadyzNotReady(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	deps.Readiness.Register("db", func(ctx context.Context) error { return nil })
+	deps.Readiness.Register("migrations", func(ctx context.Context) error { return storage.ErrorStoragePendingMigration })
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert
+	var errorResponse web.ErrorResponse
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "not_ready", errorResponse.Code)
+	assert.Equal(t, "service not ready: checks failed migrations", errorResponse.Message, "message should name the checks failed only")
+	assert.NotContains(t, errorResponse.Message, storage.ErrorStoragePendingMigration.Error(), "message should not have the errors of the checks")
+}
+
+func TestRouterVersion(t *testing.T) {
+	// Arrange
+	router, _ := NewRouter(newTestContainer())
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	// Assert
+	var body struct {
+		Data version.Info `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.NotEmpty(t, body.Data.Version, "version should be dev at least")
+	assert.Equal(t, runtime.Version(), body.Data.GoVersion)
+}
+
+// newSlowProductsContainer is a test container whose products most selled
+// query takes a minute, unless its context is done first.
+func newSlowProductsContainer(t *testing.T) (*Container, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	t.Cleanup(func() { db.Close() })
+
+	rows := mock.NewRows([]string{"id", "description", "total"}).AddRow(1, "Lemon", 10)
+	mock.ExpectQuery("SELECT COUNT").WillDelayFor(time.Minute).WillReturnRows(rows)
+
+	deps := newTestContainer()
+	deps.ProductService = product.NewProductService(product.NewProductRepository(db))
+
+	return deps, mock
+}
+
+func TestRouterRequestTimeout(t *testing.T) {
+	// Arrange
+	deps, mock := newSlowProductsContainer(t)
+	deps.Deadlines = web.Deadlines{Default: time.Minute, Routes: map[string]time.Duration{"GET /products/top/most-selled": 20 * time.Millisecond}}
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	start := time.Now()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/products/top/most-selled", nil))
+
+	// Assert
+	var errorResponse web.ErrorResponse
+	assert.Equal(t, http.StatusGatewayTimeout, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "request_timeout", errorResponse.Code)
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second), "the query should be aborted at the deadline")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the query should have run")
+}
+
+func TestRouterRequestCanceled(t *testing.T) {
+	// Arrange
+	deps, mock := newSlowProductsContainer(t)
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/products/top/most-selled", nil).WithContext(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	// Act
+	start := time.Now()
+	router.ServeHTTP(response, request)
+
+	// Assert
+	var errorResponse web.ErrorResponse
+	assert.Equal(t, web.StatusClientClosedRequest, response.Code)
+	assert.Nil(t, json.Unmarshal(response.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "request_canceled", errorResponse.Code)
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second), "the query should be aborted when the client goes away")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the query should have run")
+}
+
+func TestRouterReleaseHeldSale(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/sales/held/2/release", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, []int{72}, deps.InvoiceService.(*fakeInvoiceService).recalculated, "invoice total should be recalculated")
+	assert.Equal(t, []int{72}, deps.SummaryService.(*fakeSummaryService).refreshed, "invoice summaries should be refreshed")
+	assert.True(t, deps.ProductService.(*fakeProductService).abcUpdated, "ABC classes should be recalculated")
+}
+
+func TestRouterReleaseHeldSaleAfterClientGone(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := httptest.NewRequest(http.MethodPost, "/sales/held/2/release", nil).WithContext(ctx)
+
+	// Act
+	router.ServeHTTP(response, request)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, deps.ProductService.(*fakeProductService).abcUpdated, "the release should run to its last step")
+}
+
+func TestRouterReleaseHeldSaleConflict(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/sales/held/3/release", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, response.Code)
+	assert.Empty(t, deps.InvoiceService.(*fakeInvoiceService).recalculated, "invoice total should not change")
+	assert.False(t, deps.ProductService.(*fakeProductService).abcUpdated, "ABC classes should not change")
+}
+
+func TestRouterLoadFilesInvalidParam(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	router, _ := NewRouter(deps)
+	response := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/load-files?anomalies=bogus", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.False(t, deps.ProductService.(*fakeProductService).storedBulk, "nothing should be loaded")
+}
+
+func TestRouterReportsReadFromPrimaryAfterPurge(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	deps.ReportsFromPrimary = 100 * time.Millisecond
+	products := deps.ProductService.(*fakeProductService)
+	router, _ := NewRouter(deps)
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(method, path, nil))
+		return response
+	}
+
+	// Act
+	time.Sleep(150 * time.Millisecond)
+	request(http.MethodGet, "/reports/products/abc")
+	request(http.MethodPost, "/products/abc-classification")
+	afterPurge := request(http.MethodGet, "/reports/products/abc")
+	cached := request(http.MethodGet, "/reports/products/abc")
+
+	// Assert
+	assert.Equal(t, []bool{false, true}, products.abcFromPrimary, "the report right after the purge should read from the primary")
+	assert.Equal(t, "MISS", afterPurge.Header().Get("X-Cache"))
+	assert.Equal(t, "HIT", cached.Header().Get("X-Cache"), "the report read from the primary should be cached")
+}
+
+func TestRouterReportsAlwaysReadFromPrimaryWithReplicasUnbounded(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	deps.ReportsFromPrimary = -1
+	products := deps.ProductService.(*fakeProductService)
+	router, _ := NewRouter(deps)
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reports/products/abc", nil))
+
+	// Assert
+	assert.Equal(t, []bool{true}, products.abcFromPrimary)
+}
+
+func TestReplicasMaxBehind(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.DB
+		expected time.Duration
+	}{
+		{"no replicas", config.DB{ReplicaMaxLag: 10 * time.Second, ReplicaCheckInterval: 5 * time.Second}, 0},
+		{"max lag", config.DB{Replicas: []string{"replica"}, ReplicaMaxLag: 10 * time.Second, ReplicaCheckInterval: 5 * time.Second}, 15 * time.Second},
+		{"no max lag", config.DB{Replicas: []string{"replica"}, ReplicaCheckInterval: 5 * time.Second}, -1},
+		{"never checked", config.DB{Replicas: []string{"replica"}, ReplicaMaxLag: 10 * time.Second}, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act & Assert
+			assert.Equal(t, test.expected, replicasMaxBehind(test.cfg))
+		})
+	}
+}
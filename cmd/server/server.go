@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+)
+
+var (
+	// Errors
+	ErrorServerShuttingDown = errors.New("server shutting down")
+)
+
+// Server serves the router with the timeouts of the config, and drains the
+// requests in flight on shutdown.
+type Server struct {
+	httpServer      *http.Server
+	shutdownDelay   time.Duration
+	shutdownTimeout time.Duration
+	shuttingDown    int32
+	// cancel cancels the context of every request
+	cancel   context.CancelFunc
+	inFlight sync.WaitGroup
+}
+
+func NewServer(cfg config.Server, handler http.Handler) *Server {
+	requestsCtx, cancel := context.WithCancel(context.Background())
+	server := &Server{
+		shutdownDelay:   cfg.ShutdownDelay,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		cancel:          cancel,
+	}
+
+	server.httpServer = &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           server.track(handler),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return requestsCtx },
+	}
+
+	return server
+}
+
+// Run serves on the address of the config until ctx is done, then shuts
+// down.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on listener until ctx is done, then shuts down. It is not
+// ready but still serves for the shutdown delay, for the probes to see it
+// first, then stops accepting requests and waits for the ones in flight up
+// to the shutdown timeout. The ones still running then are canceled, their
+// queries aborted, and waited for too; the uninterruptible ones, like the
+// load, are waited for up to their own deadline.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		s.cancel()
+		return err
+	case <-ctx.Done():
+	}
+
+	atomic.StoreInt32(&s.shuttingDown, 1)
+	if s.shutdownDelay > 0 {
+		log.Printf("Not ready, shutting down in %s...", s.shutdownDelay)
+		time.Sleep(s.shutdownDelay)
+	}
+
+	log.Printf("Shutting down, waiting up to %s for the requests in flight...", s.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	err := s.httpServer.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Println("Canceling the requests still in flight...")
+		err = nil
+	}
+
+	s.cancel()
+	s.inFlight.Wait()
+
+	if closeErr := s.httpServer.Close(); err == nil {
+		err = closeErr
+	}
+
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+
+	return err
+}
+
+// Ready is the readiness check of the server, failing with
+// ErrorServerShuttingDown once it is shutting down.
+func (s *Server) Ready(ctx context.Context) error {
+	if atomic.LoadInt32(&s.shuttingDown) == 1 {
+		return ErrorServerShuttingDown
+	}
+
+	return nil
+}
+
+// track counts the requests in flight until their handler returns.
+func (s *Server) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Done()
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveTest serves handler with shutdownTimeout until the returned cancel is
+// called, and returns its URL and the error of the server.
+func serveTest(t *testing.T, handler http.Handler, shutdownTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	_, url, cancel, serveErr := serveTestWithConfig(t, handler, config.Server{WriteTimeout: time.Minute, ShutdownTimeout: shutdownTimeout})
+
+	return url, cancel, serveErr
+}
+
+func serveTestWithConfig(t *testing.T, handler http.Handler, cfg config.Server) (*Server, string, context.CancelFunc, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "error should be nil")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := NewServer(cfg, handler)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx, listener)
+	}()
+
+	return server, "http://" + listener.Addr().String(), cancel, serveErr
+}
+
+func TestServerDrainsRequestsInFlight(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	url, shutdown, serveErr := serveTest(t, handler, time.Minute)
+
+	responseStatus := make(chan int, 1)
+	go func() {
+		response, err := http.Get(url)
+		if err != nil {
+			responseStatus <- 0
+			return
+		}
+		response.Body.Close()
+		responseStatus <- response.StatusCode
+	}()
+
+	// Act
+	<-started
+	shutdown()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, <-responseStatus, "the request in flight should be completed")
+	assert.Nil(t, <-serveErr, "error should be nil")
+
+	_, err := http.Get(url)
+	assert.Error(t, err, "new requests should be refused")
+}
+
+func TestServerCancelsRequestsAfterShutdownTimeout(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	canceled := make(chan bool, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			canceled <- true
+		case <-time.After(time.Minute):
+			canceled <- false
+		}
+	})
+	url, shutdown, serveErr := serveTest(t, handler, 50*time.Millisecond)
+
+	go func() {
+		if response, err := http.Get(url); err == nil {
+			response.Body.Close()
+		}
+	}()
+
+	// Act
+	<-started
+	start := time.Now()
+	shutdown()
+	err := <-serveErr
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, <-canceled, "the request still running should be canceled")
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second), "shutdown should not wait for the request")
+}
+
+func TestServerLetsTheLoadFinishAfterShutdownTimeout(t *testing.T) {
+	// Arrange
+	deps := newTestContainer()
+	products := deps.ProductService.(*fakeProductService)
+	products.started = make(chan struct{})
+	products.storeDelay = 300 * time.Millisecond
+	router, _ := NewRouter(deps)
+	url, shutdown, serveErr := serveTest(t, router, 50*time.Millisecond)
+
+	responseStatus := make(chan int, 1)
+	go func() {
+		response, err := http.Get(url + "/load-files")
+		if err != nil {
+			responseStatus <- 0
+			return
+		}
+		response.Body.Close()
+		responseStatus <- response.StatusCode
+	}()
+
+	// Act
+	<-products.started
+	shutdown()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, <-responseStatus, "the load should not be canceled")
+	assert.Nil(t, <-serveErr, "error should be nil")
+	assert.True(t, products.abcUpdated, "the load should run to its last step")
+}
+
+func TestServerNotReadyWhileShuttingDown(t *testing.T) {
+	// Arrange
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server, url, shutdown, serveErr := serveTestWithConfig(t, handler, config.Server{ShutdownDelay: 200 * time.Millisecond, ShutdownTimeout: time.Second})
+	readyBefore := server.Ready(context.Background())
+
+	// Act
+	shutdown()
+	time.Sleep(50 * time.Millisecond)
+	readyDuring := server.Ready(context.Background())
+	response, err := http.Get(url)
+
+	// Assert
+	assert.Nil(t, readyBefore, "server should be ready before shutting down")
+	assert.ErrorIs(t, readyDuring, ErrorServerShuttingDown, "server should not be ready while shutting down")
+	assert.Nil(t, err, "server should still serve during the shutdown delay")
+	if err == nil {
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+	assert.Nil(t, <-serveErr, "error should be nil")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+)
+
+// Rebuilds the daily summaries from every sale and invoice. The server keeps
+// them up to date on every load, and the migration creating them fills them
+// with the data loaded before, so this is only needed for data changed
+// outside the server.
+func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// A SIGTERM or SIGINT cancels the rebuild, its transaction rolled back
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	db, err := storage.OpenWithOptions(ctx, cfg.DB.Driver, cfg.DB.ConnectionString(), cfg.DB.Options())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.DB.Migrate() {
+		if err = db.Migrate(ctx); err != nil {
+			db.Close()
+			log.Fatal(err)
+		}
+	}
+
+	summaryRepository := summary.NewSummaryRepositoryWithDialect(db.DB, db.Dialect)
+	summaryService := summary.NewSummaryService(summaryRepository)
+
+	err = summaryService.Rebuild(ctx)
+	db.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Summaries rebuilt!")
+}
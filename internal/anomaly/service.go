@@ -0,0 +1,101 @@
+package anomaly
+
+import (
+	"context"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+var (
+	// Errors
+	ErrorAnomalyInvalidSource = web.NewError(web.KindValidation, "anomaly_invalid_source", "source must be sale_quantity or invoice_total")
+)
+
+type AnomalyService interface {
+	GetAnomalies(ctx context.Context, source string, config outlier.Config) ([]domain.AnomalyDTO, error)
+	GetInvoiceAnomalies(ctx context.Context, config outlier.Config, invoicesIds []int) ([]domain.AnomalyDTO, error)
+}
+
+func NewAnomalyService(sr sale.SaleRepository, ir invoice.InvoiceRepository) AnomalyService {
+	return &anomalyService{
+		saleRepository:    sr,
+		invoiceRepository: ir,
+	}
+}
+
+type anomalyService struct {
+	saleRepository    sale.SaleRepository
+	invoiceRepository invoice.InvoiceRepository
+}
+
+// GetAnomalies flags the stored sale quantities and invoice totals, or only
+// the ones of source when it is not empty.
+func (s *anomalyService) GetAnomalies(ctx context.Context, source string, config outlier.Config) ([]domain.AnomalyDTO, error) {
+	if source != "" && source != sale.AnomalySourceSaleQuantity && source != invoice.AnomalySourceInvoiceTotal {
+		return nil, ErrorAnomalyInvalidSource
+	}
+
+	anomalies := []domain.AnomalyDTO{}
+
+	if source == "" || source == sale.AnomalySourceSaleQuantity {
+		sales, err := s.saleRepository.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		saleAnomalies, err := sale.SaleAnomalies(sales, config)
+		if err != nil {
+			return nil, err
+		}
+
+		anomalies = append(anomalies, saleAnomalies...)
+	}
+
+	if source == "" || source == invoice.AnomalySourceInvoiceTotal {
+		invoices, err := s.invoiceRepository.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		invoiceAnomalies, err := invoice.InvoiceAnomalies(invoices, config)
+		if err != nil {
+			return nil, err
+		}
+
+		anomalies = append(anomalies, invoiceAnomalies...)
+	}
+
+	return anomalies, nil
+}
+
+// GetInvoiceAnomalies flags the totals of the invoices of invoicesIds, using
+// every stored invoice as the baseline.
+func (s *anomalyService) GetInvoiceAnomalies(ctx context.Context, config outlier.Config, invoicesIds []int) ([]domain.AnomalyDTO, error) {
+	invoices, err := s.invoiceRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allAnomalies, err := invoice.InvoiceAnomalies(invoices, config)
+	if err != nil {
+		return nil, err
+	}
+
+	flagged := map[int]bool{}
+	for _, invoiceId := range invoicesIds {
+		flagged[invoiceId] = true
+	}
+
+	anomalies := []domain.AnomalyDTO{}
+	for _, anomaly := range allAnomalies {
+		if flagged[anomaly.Id] {
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	return anomalies, nil
+}
@@ -0,0 +1,122 @@
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAnomalyGetAnomalies(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	anomalyService := NewAnomalyService(sale.NewSaleRepository(db), invoice.NewInvoiceRepository(db))
+
+	salesRows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"})
+	for i := 1; i <= 10; i++ {
+		salesRows.AddRow(i, i, 1, float64(10+i))
+	}
+	salesRows.AddRow(11, 11, 1, 44618.0)
+	mock.ExpectQuery(sale.GetAllSalesQuery).WillReturnRows(salesRows)
+
+	invoicesRows := mock.NewRows([]string{"id", "customer_id", "datetime", "total"})
+	for i := 1; i <= 10; i++ {
+		invoicesRows.AddRow(i, 1, "2021-12-14 12:24:27", float64(1000+i))
+	}
+	invoicesRows.AddRow(11, 1, "2021-12-14 12:24:27", 29710000.0)
+	mock.ExpectQuery(invoice.GetAllInvoicesQuery).WillReturnRows(invoicesRows)
+
+	// Act
+	result, err := anomalyService.GetAnomalies(context.Background(), "", outlier.Config{PerGroup: true})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, len(result), "len of result should be equal to 2")
+	assert.Equal(t, sale.AnomalySourceSaleQuantity, result[0].Source, "first anomaly should be the sale quantity")
+	assert.Equal(t, 11, result[0].Id, "sale 11 should be an anomaly")
+	assert.Equal(t, 1, result[0].ProductId, "sale anomaly should have the product")
+	assert.Equal(t, invoice.AnomalySourceInvoiceTotal, result[1].Source, "second anomaly should be the invoice total")
+	assert.Equal(t, 11, result[1].Id, "invoice 11 should be an anomaly")
+}
+
+func TestServiceAnomalyGetAnomaliesZScore(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	anomalyService := NewAnomalyService(sale.NewSaleRepository(db), invoice.NewInvoiceRepository(db))
+
+	invoicesRows := mock.NewRows([]string{"id", "customer_id", "datetime", "total"})
+	for i := 1; i <= 20; i++ {
+		invoicesRows.AddRow(i, 1, "2021-12-14 12:24:27", float64(1000+i%2))
+	}
+	invoicesRows.AddRow(21, 1, "2021-12-14 12:24:27", 5000.0)
+	mock.ExpectQuery(invoice.GetAllInvoicesQuery).WillReturnRows(invoicesRows)
+
+	// Act
+	result, err := anomalyService.GetAnomalies(context.Background(), invoice.AnomalySourceInvoiceTotal, outlier.Config{Method: outlier.MethodZScore})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1, len(result), "len of result should be equal to 1")
+	assert.Equal(t, 21, result[0].Id, "invoice 21 should be an anomaly")
+	assert.True(t, result[0].Score > outlier.DefaultZScoreThreshold, "score should be over the threshold")
+}
+
+func TestServiceAnomalyGetAnomaliesInvalidSource(t *testing.T) {
+	// Arrange
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	anomalyService := NewAnomalyService(sale.NewSaleRepository(db), invoice.NewInvoiceRepository(db))
+
+	// Act
+	result, err := anomalyService.GetAnomalies(context.Background(), "customers", outlier.Config{})
+
+	// Assert
+	assert.Equal(t, ErrorAnomalyInvalidSource, err, "error should be invalid source")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceAnomalyGetAnomaliesError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	anomalyService := NewAnomalyService(sale.NewSaleRepository(db), invoice.NewInvoiceRepository(db))
+
+	mock.ExpectQuery(sale.GetAllSalesQuery).WillReturnError(errors.New("error"))
+
+	// Act
+	result, err := anomalyService.GetAnomalies(context.Background(), sale.AnomalySourceSaleQuantity, outlier.Config{})
+
+	// Assert
+	assert.Error(t, err, "should exists an error")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceAnomalyGetInvoiceAnomalies(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	anomalyService := NewAnomalyService(sale.NewSaleRepository(db), invoice.NewInvoiceRepository(db))
+
+	invoicesRows := mock.NewRows([]string{"id", "customer_id", "datetime", "total"})
+	for i := 1; i <= 10; i++ {
+		invoicesRows.AddRow(i, 1, "2021-12-14 12:24:27", float64(1000+i))
+	}
+	invoicesRows.AddRow(11, 1, "2021-12-14 12:24:27", 29710000.0)
+	invoicesRows.AddRow(12, 1, "2021-12-14 12:24:27", 31520000.0)
+	mock.ExpectQuery(invoice.GetAllInvoicesQuery).WillReturnRows(invoicesRows)
+
+	// Act
+	result, err := anomalyService.GetInvoiceAnomalies(context.Background(), outlier.Config{}, []int{10, 12})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1, len(result), "only the invoices given should be flagged")
+	assert.Equal(t, 12, result[0].Id, "invoice 12 should be an anomaly")
+}
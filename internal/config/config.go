@@ -0,0 +1,512 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Drivers
+	DriverMySQL    = storage.DriverMySQL
+	DriverSQLite   = storage.DriverSQLite
+	DriverPostgres = storage.DriverPostgres
+
+	DefaultEnvPath = ".env"
+)
+
+var (
+	// Errors
+	ErrorConfigReadFile       = errors.New("can not read config file")
+	ErrorConfigReadEnvFile    = errors.New("can not read .env file")
+	ErrorConfigInvalidValue   = errors.New("invalid config value")
+	ErrorConfigInvalidDriver  = errors.New("db driver must be mysql, sqlite or postgres")
+	ErrorConfigMissingDB      = errors.New("db name or dsn is required")
+	ErrorConfigInvalidPort    = errors.New("port must be between 1 and 65535")
+	ErrorConfigInvalidTimeout = errors.New("timeouts can not be negative")
+	ErrorConfigLongDeadline   = errors.New("request deadlines must be shorter than the server write timeout")
+	ErrorConfigInvalidPool    = errors.New("db pool, retry and replica settings can not be negative")
+	ErrorConfigMissingDataDir = errors.New("data dir is required")
+)
+
+// Config is the configuration of the app. Every source overrides the ones
+// before it: defaults, the YAML file, the .env file, the environment and
+// the flags.
+type Config struct {
+	DB     DB     `yaml:"db"`
+	Server Server `yaml:"server"`
+	Data   Data   `yaml:"data"`
+}
+
+type DB struct {
+	Driver       string        `yaml:"driver"`
+	DSN          string        `yaml:"dsn"`  // used as is instead of the fields below when not empty
+	Name         string        `yaml:"name"` // the DB file for sqlite
+	User         string        `yaml:"user"`
+	Password     string        `yaml:"password"`
+	Host         string        `yaml:"host"`
+	Port         int           `yaml:"port"`     // 0 is the default port of the driver
+	SSLMode      string        `yaml:"ssl_mode"` // postgres only, require when empty
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	AutoMigrate  bool          `yaml:"auto_migrate"` // apply the migrations pending on start, always on for sqlite
+
+	// Pool
+	MaxOpenConns    int           `yaml:"max_open_conns"`     // no limit when 0
+	MaxIdleConns    int           `yaml:"max_idle_conns"`     // 2 when 0
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`  // forever when 0
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // forever when 0
+
+	// Retries, the wait doubles on every one
+	ConnectRetries int           `yaml:"connect_retries"` // pings while the DB is not reachable on start
+	ConnectBackoff time.Duration `yaml:"connect_backoff"`
+	Retries        int           `yaml:"retries"` // statements failing with a deadlock or a lock wait timeout
+	RetryBackoff   time.Duration `yaml:"retry_backoff"`
+
+	// Read replicas, by DSN, the reads go to the ones reachable and not
+	// further behind than the max lag, and to the primary when none is
+	Replicas             []string      `yaml:"replicas"`
+	ReplicaMaxLag        time.Duration `yaml:"replica_max_lag"`        // no limit when 0
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"` // only checked on start when 0
+}
+
+type Server struct {
+	Port              int           `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"` // longer than every request deadline
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownDelay     time.Duration `yaml:"shutdown_delay"`   // not ready but serving on shutdown, for the probes to see it
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"` // the requests in flight still running then are canceled, but the load
+	CursorSecret      string        `yaml:"cursor_secret"`    // random when empty, cursors stop being valid on restart
+
+	// Deadlines of the requests, their queries are aborted once over. No
+	// deadline when 0, only without a write timeout
+	RequestTimeout time.Duration            `yaml:"request_timeout"`
+	RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts"` // by "<METHOD> <path>", like "GET /load-files"
+}
+
+type Data struct {
+	Dir string `yaml:"dir"` // where the products, customers, invoices and sales files are
+}
+
+// setting is a value that can come from the environment and the flags.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(value string) error
+}
+
+// Default is the configuration before reading any source.
+func Default() Config {
+	return Config{
+		DB: DB{
+			Driver:       DriverMySQL,
+			Host:         "localhost",
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: time.Minute,
+
+			ConnectRetries: 5,
+			ConnectBackoff: time.Second,
+			Retries:        3,
+			RetryBackoff:   50 * time.Millisecond,
+
+			ReplicaMaxLag:        10 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+		},
+		Server: Server{
+			Port:              8080,
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      6 * time.Minute,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+
+			RequestTimeout: 30 * time.Second,
+			RouteTimeouts: map[string]time.Duration{
+				"GET /load-files": 5 * time.Minute,
+			},
+		},
+		Data: Data{
+			Dir: "../../datos",
+		},
+	}
+}
+
+// Load reads the configuration from every source, args being the command
+// line flags without the program name. The YAML file is given with -config
+// or CONFIG_FILE, and the .env file with -env, .env by default and only
+// read when it exists.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML config file")
+	envPath := flags.String("env", DefaultEnvPath, ".env file, ignored when it does not exist")
+	for _, s := range settings {
+		flags.String(s.flag, "", s.usage+", or "+s.env)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configPath != "" {
+		content, err := os.ReadFile(*configPath)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %v", ErrorConfigReadFile, err)
+		}
+
+		if err = yaml.Unmarshal(content, &cfg); err != nil {
+			return Config{}, fmt.Errorf("%w: %v", ErrorConfigReadFile, err)
+		}
+	}
+
+	envFile := map[string]string{}
+	if _, err := os.Stat(*envPath); err == nil {
+		if envFile, err = godotenv.Read(*envPath); err != nil {
+			return Config{}, fmt.Errorf("%w: %v", ErrorConfigReadEnvFile, err)
+		}
+	}
+
+	// The environment wins over the .env file, which never changes it. Empty
+	// values are the same as missing ones
+	for _, s := range settings {
+		value := os.Getenv(s.env)
+		if value == "" {
+			value = envFile[s.env]
+		}
+
+		if value == "" {
+			continue
+		}
+
+		if err := s.set(value); err != nil {
+			return Config{}, fmt.Errorf("%w: %s: %v", ErrorConfigInvalidValue, s.env, err)
+		}
+	}
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && err == nil {
+				if setErr := s.set(f.Value.String()); setErr != nil {
+					err = fmt.Errorf("%w: -%s: %v", ErrorConfigInvalidValue, s.flag, setErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration is usable, not that the DB is reachable.
+func (c Config) Validate() error {
+	if _, err := storage.DialectFor(c.DB.Driver); err != nil {
+		return ErrorConfigInvalidDriver
+	}
+
+	if c.DB.DSN == "" && c.DB.Name == "" {
+		return ErrorConfigMissingDB
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 || c.DB.Port < 0 || c.DB.Port > 65535 {
+		return ErrorConfigInvalidPort
+	}
+
+	for _, timeout := range []time.Duration{c.DB.DialTimeout, c.DB.ReadTimeout, c.DB.WriteTimeout,
+		c.Server.ReadTimeout, c.Server.ReadHeaderTimeout, c.Server.WriteTimeout, c.Server.IdleTimeout,
+		c.Server.ShutdownDelay, c.Server.ShutdownTimeout, c.Server.RequestTimeout} {
+		if timeout < 0 {
+			return ErrorConfigInvalidTimeout
+		}
+	}
+
+	// A response written after the write timeout never gets to the client
+	deadlines := []time.Duration{c.Server.RequestTimeout}
+	for _, timeout := range c.Server.RouteTimeouts {
+		deadlines = append(deadlines, timeout)
+	}
+	for _, deadline := range deadlines {
+		if deadline < 0 {
+			return ErrorConfigInvalidTimeout
+		}
+
+		if c.Server.WriteTimeout > 0 && (deadline == 0 || deadline >= c.Server.WriteTimeout) {
+			return ErrorConfigLongDeadline
+		}
+	}
+
+	for _, setting := range []int{c.DB.MaxOpenConns, c.DB.MaxIdleConns, c.DB.ConnectRetries, c.DB.Retries,
+		int(c.DB.ConnMaxLifetime), int(c.DB.ConnMaxIdleTime), int(c.DB.ConnectBackoff), int(c.DB.RetryBackoff),
+		int(c.DB.ReplicaMaxLag), int(c.DB.ReplicaCheckInterval)} {
+		if setting < 0 {
+			return ErrorConfigInvalidPool
+		}
+	}
+
+	if c.Data.Dir == "" {
+		return ErrorConfigMissingDataDir
+	}
+
+	return nil
+}
+
+// ConnectionString is the DSN of the DB, built from its fields unless DSN is
+// set. The sqlite DB is the Name file, with the foreign keys on and waiting
+// for the locks up to the dial timeout.
+func (db DB) ConnectionString() string {
+	if db.DSN != "" {
+		return db.DSN
+	}
+
+	if db.Driver == DriverSQLite {
+		return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)", db.Name, db.DialTimeout.Milliseconds())
+	}
+
+	if db.Driver == DriverPostgres {
+		return db.postgresConnectionString()
+	}
+
+	port := db.Port
+	if port == 0 {
+		port = 3306
+	}
+
+	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", db.User, db.Password, db.Host, port, db.Name)
+
+	var params []string
+	timeouts := []struct {
+		param   string
+		timeout time.Duration
+	}{
+		{"timeout", db.DialTimeout},
+		{"readTimeout", db.ReadTimeout},
+		{"writeTimeout", db.WriteTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.timeout > 0 {
+			params = append(params, timeout.param+"="+timeout.timeout.String())
+		}
+	}
+
+	if len(params) > 0 {
+		connectionString += "?" + strings.Join(params, "&")
+	}
+
+	return connectionString
+}
+
+// postgresConnectionString is the URL of the DB, lib/pq takes the connect
+// timeout in seconds and has no read nor write timeouts.
+func (db DB) postgresConnectionString() string {
+	port := db.Port
+	if port == 0 {
+		port = 5432
+	}
+
+	connectionURL := url.URL{
+		Scheme: DriverPostgres,
+		User:   url.UserPassword(db.User, db.Password),
+		Host:   net.JoinHostPort(db.Host, strconv.Itoa(port)),
+		Path:   "/" + db.Name,
+	}
+
+	params := url.Values{}
+	if db.SSLMode != "" {
+		params.Set("sslmode", db.SSLMode)
+	}
+	if db.DialTimeout > 0 {
+		params.Set("connect_timeout", strconv.Itoa(int(db.DialTimeout.Seconds())))
+	}
+	connectionURL.RawQuery = params.Encode()
+
+	return connectionURL.String()
+}
+
+// Path is the path of the data file name.
+func (d Data) Path(name string) string {
+	return filepath.Join(d.Dir, name)
+}
+
+// Options are the pool, retry and replica settings of the DB.
+func (db DB) Options() storage.Options {
+	return storage.Options{
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: db.ConnMaxLifetime,
+		ConnMaxIdleTime: db.ConnMaxIdleTime,
+		ConnectRetries:  db.ConnectRetries,
+		ConnectBackoff:  db.ConnectBackoff,
+		Retries:         db.Retries,
+		RetryBackoff:    db.RetryBackoff,
+
+		Replicas:             db.Replicas,
+		ReplicaMaxLag:        db.ReplicaMaxLag,
+		ReplicaCheckInterval: db.ReplicaCheckInterval,
+	}
+}
+
+// Migrate reports whether the migrations pending are applied on start. The
+// embedded DB has no other setup, so it always is.
+func (db DB) Migrate() bool {
+	return db.AutoMigrate || db.Driver == DriverSQLite
+}
+
+// Addr is the address the server listens on.
+func (s Server) Addr() string {
+	return ":" + strconv.Itoa(s.Port)
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"DB_DRIVER", "db-driver", "db driver, mysql, sqlite or postgres", stringSetter(&c.DB.Driver)},
+		{"DB_DSN", "db-dsn", "db DSN, instead of the other db settings", stringSetter(&c.DB.DSN)},
+		{"DB_USER", "db-user", "db user", stringSetter(&c.DB.User)},
+		{"DB_PASSWORD", "db-password", "db password", stringSetter(&c.DB.Password)},
+		{"DB_HOST", "db-host", "db host", stringSetter(&c.DB.Host)},
+		{"DB_PORT", "db-port", "db port", intSetter(&c.DB.Port)},
+		{"DB_NAME", "db-name", "db name, the DB file for sqlite", stringSetter(&c.DB.Name)},
+		{"DB_SSL_MODE", "db-ssl-mode", "postgres ssl mode", stringSetter(&c.DB.SSLMode)},
+		{"DB_DIAL_TIMEOUT", "db-dial-timeout", "db connection timeout", durationSetter(&c.DB.DialTimeout)},
+		{"DB_READ_TIMEOUT", "db-read-timeout", "db read timeout", durationSetter(&c.DB.ReadTimeout)},
+		{"DB_WRITE_TIMEOUT", "db-write-timeout", "db write timeout", durationSetter(&c.DB.WriteTimeout)},
+		{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "db pool max open connections, no limit when 0", intSetter(&c.DB.MaxOpenConns)},
+		{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "db pool max idle connections", intSetter(&c.DB.MaxIdleConns)},
+		{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "db connections max lifetime, forever when 0", durationSetter(&c.DB.ConnMaxLifetime)},
+		{"DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "db connections max idle time, forever when 0", durationSetter(&c.DB.ConnMaxIdleTime)},
+		{"DB_CONNECT_RETRIES", "db-connect-retries", "db pings retried while it is not reachable on start", intSetter(&c.DB.ConnectRetries)},
+		{"DB_CONNECT_BACKOFF", "db-connect-backoff", "wait before the first db ping retried, doubled on every one", durationSetter(&c.DB.ConnectBackoff)},
+		{"DB_RETRIES", "db-retries", "db statements retried after a deadlock or a lock wait timeout", intSetter(&c.DB.Retries)},
+		{"DB_RETRY_BACKOFF", "db-retry-backoff", "wait before the first db statement retried, doubled on every one", durationSetter(&c.DB.RetryBackoff)},
+		{"DB_REPLICAS", "db-replicas", "DSNs of the db read replicas, comma separated", stringsSetter(&c.DB.Replicas)},
+		{"DB_REPLICA_MAX_LAG", "db-replica-max-lag", "db replicas further behind are not read, no limit when 0", durationSetter(&c.DB.ReplicaMaxLag)},
+		{"DB_REPLICA_CHECK_INTERVAL", "db-replica-check-interval", "how often the db replicas lag is checked, only on start when 0", durationSetter(&c.DB.ReplicaCheckInterval)},
+		{"DB_AUTO_MIGRATE", "db-auto-migrate", "apply the db migrations pending on start", boolSetter(&c.DB.AutoMigrate)},
+		{"PORT", "port", "server port", intSetter(&c.Server.Port)},
+		{"SERVER_READ_TIMEOUT", "read-timeout", "server read timeout", durationSetter(&c.Server.ReadTimeout)},
+		{"SERVER_READ_HEADER_TIMEOUT", "read-header-timeout", "server read header timeout", durationSetter(&c.Server.ReadHeaderTimeout)},
+		{"SERVER_WRITE_TIMEOUT", "write-timeout", "server write timeout, longer than every request deadline", durationSetter(&c.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", "idle-timeout", "server idle timeout", durationSetter(&c.Server.IdleTimeout)},
+		{"SERVER_SHUTDOWN_DELAY", "shutdown-delay", "time not ready but serving on shutdown, for the probes to see it", durationSetter(&c.Server.ShutdownDelay)},
+		{"SERVER_SHUTDOWN_TIMEOUT", "shutdown-timeout", "wait for the requests in flight on shutdown, canceled after it", durationSetter(&c.Server.ShutdownTimeout)},
+		{"SERVER_REQUEST_TIMEOUT", "request-timeout", "deadline of the requests", durationSetter(&c.Server.RequestTimeout)},
+		{"SERVER_ROUTE_TIMEOUTS", "route-timeouts", "deadlines of routes, like GET /load-files=5m,GET /products=5s", durationsSetter(&c.Server.RouteTimeouts)},
+		{"CURSOR_SECRET", "cursor-secret", "secret signing the listings cursors", stringSetter(&c.Server.CursorSecret)},
+		{"DATA_DIR", "data-dir", "dir of the data files", stringSetter(&c.Data.Dir)},
+	}
+}
+
+func stringSetter(field *string) func(string) error {
+	return func(value string) error {
+		*field = value
+		return nil
+	}
+}
+
+func stringsSetter(field *[]string) func(string) error {
+	return func(value string) error {
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+
+		*field = values
+		return nil
+	}
+}
+
+func intSetter(field *int) func(string) error {
+	return func(value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		*field = number
+		return nil
+	}
+}
+
+func boolSetter(field *bool) func(string) error {
+	return func(value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		*field = enabled
+		return nil
+	}
+}
+
+func durationSetter(field *time.Duration) func(string) error {
+	return func(value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		*field = duration
+		return nil
+	}
+}
+
+// durationsSetter sets the durations of a list of key=duration, the keys
+// not in the list keep theirs.
+func durationsSetter(field *map[string]time.Duration) func(string) error {
+	return func(value string) error {
+		durations := map[string]time.Duration{}
+		for _, pair := range strings.Split(value, ",") {
+			keyDuration := strings.SplitN(pair, "=", 2)
+			if len(keyDuration) != 2 {
+				return fmt.Errorf("%q is not key=duration", pair)
+			}
+
+			duration, err := time.ParseDuration(strings.TrimSpace(keyDuration[1]))
+			if err != nil {
+				return err
+			}
+
+			durations[strings.TrimSpace(keyDuration[0])] = duration
+		}
+
+		if *field == nil {
+			*field = map[string]time.Duration{}
+		}
+		for key, duration := range durations {
+			(*field)[key] = duration
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,238 @@
+package customer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/memory"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// NewCustomerMemoryRepository returns a CustomerRepository keeping the
+// customers in store, answering like the SQL one.
+func NewCustomerMemoryRepository(store *memory.Store) CustomerRepository {
+	return &customerMemoryRepository{
+		store: store,
+	}
+}
+
+type customerMemoryRepository struct {
+	store *memory.Store
+}
+
+func (r *customerMemoryRepository) Get(ctx context.Context, id int) (domain.Customer, error) {
+	var customer domain.Customer
+	var ok bool
+	r.store.Read(func(tables *memory.Tables) {
+		customer, ok = tables.Customers[id]
+	})
+
+	if !ok {
+		return domain.Customer{}, ErrorCustomerNotFound
+	}
+
+	return customer, nil
+}
+
+func (r *customerMemoryRepository) GetAll(ctx context.Context) ([]domain.Customer, error) {
+	var customers []domain.Customer
+	r.store.Read(func(tables *memory.Tables) {
+		customers = append(customers, tables.SortedCustomers()...)
+	})
+
+	return customers, nil
+}
+
+func (r *customerMemoryRepository) List(ctx context.Context, spec web.QuerySpec) ([]domain.Customer, int, error) {
+	var all []domain.Customer
+	r.store.Read(func(tables *memory.Tables) {
+		all = tables.SortedCustomers()
+	})
+
+	rows := make([]web.Row, 0, len(all))
+	for _, customer := range all {
+		rows = append(rows, customerRow(customer))
+	}
+
+	indexes, total, err := spec.Select(CustomerQueryColumns, CustomerDefaultSort, rows)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	customers := make([]domain.Customer, 0, len(indexes))
+	for _, index := range indexes {
+		customers = append(customers, all[index])
+	}
+
+	return customers, total, nil
+}
+
+// GetTotalByCondition returns the totals ordered by situation.
+func (r *customerMemoryRepository) GetTotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error) {
+	totals := map[string]float64{}
+	r.store.Read(func(tables *memory.Tables) {
+		for _, invoice := range tables.Invoices {
+			if customer, ok := tables.Customers[invoice.Customer_id]; ok {
+				totals[customer.Situation] += invoice.Total
+			}
+		}
+	})
+
+	var customerTotalByConditions []domain.CustomerTotalByConditionDTO
+	for situation, total := range totals {
+		customerTotalByConditions = append(customerTotalByConditions, domain.CustomerTotalByConditionDTO{
+			Situation: situation,
+			Total:     memory.Round(total, 2),
+		})
+	}
+
+	sort.Slice(customerTotalByConditions, func(i, j int) bool {
+		return customerTotalByConditions[i].Situation < customerTotalByConditions[j].Situation
+	})
+
+	return customerTotalByConditions, nil
+}
+
+// GetCustomerCheaperProducts returns the 5 customers buying the cheapest
+// products, by price, last name and first name.
+func (r *customerMemoryRepository) GetCustomerCheaperProducts(ctx context.Context) ([]domain.CustomerCheaperProductDTO, error) {
+	type cheaperProduct struct {
+		lastName  string
+		firstName string
+		price     float64
+	}
+
+	seen := map[cheaperProduct]bool{}
+	var cheaperProducts []cheaperProduct
+	r.store.Read(func(tables *memory.Tables) {
+		for _, sale := range tables.Sales {
+			invoice, ok := tables.Invoices[sale.Invoice_id]
+			if !ok {
+				continue
+			}
+
+			customer, ok := tables.Customers[invoice.Customer_id]
+			if !ok {
+				continue
+			}
+
+			product, ok := tables.Products[sale.Product_id]
+			if !ok {
+				continue
+			}
+
+			row := cheaperProduct{lastName: customer.LastName, firstName: customer.FirstName, price: product.Price}
+			if !seen[row] {
+				seen[row] = true
+				cheaperProducts = append(cheaperProducts, row)
+			}
+		}
+	})
+
+	sort.Slice(cheaperProducts, func(i, j int) bool {
+		if cheaperProducts[i].price != cheaperProducts[j].price {
+			return cheaperProducts[i].price < cheaperProducts[j].price
+		}
+
+		if cheaperProducts[i].lastName != cheaperProducts[j].lastName {
+			return cheaperProducts[i].lastName < cheaperProducts[j].lastName
+		}
+
+		return cheaperProducts[i].firstName < cheaperProducts[j].firstName
+	})
+	if len(cheaperProducts) > 5 {
+		cheaperProducts = cheaperProducts[:5]
+	}
+
+	var customerCheaperProducts []domain.CustomerCheaperProductDTO
+	for _, cheaperProduct := range cheaperProducts {
+		customerCheaperProducts = append(customerCheaperProducts, domain.CustomerCheaperProductDTO{
+			FirstName: cheaperProduct.firstName,
+			LastName:  cheaperProduct.lastName,
+		})
+	}
+
+	return customerCheaperProducts, nil
+}
+
+func (r *customerMemoryRepository) GetRFMValues(ctx context.Context) ([]domain.CustomerRFMValuesDTO, error) {
+	var customersRFMValues []domain.CustomerRFMValuesDTO
+	r.store.Read(func(tables *memory.Tables) {
+		values := map[int]*domain.CustomerRFMValuesDTO{}
+		for _, invoice := range tables.SortedInvoices() {
+			customer, ok := tables.Customers[invoice.Customer_id]
+			if !ok {
+				continue
+			}
+
+			customerRFMValues, ok := values[customer.Id]
+			if !ok {
+				customerRFMValues = &domain.CustomerRFMValuesDTO{Customer: customer}
+				values[customer.Id] = customerRFMValues
+			}
+
+			if invoice.Datetime > customerRFMValues.LastPurchase {
+				customerRFMValues.LastPurchase = invoice.Datetime
+			}
+			customerRFMValues.Frequency++
+			customerRFMValues.Monetary += invoice.Total
+		}
+
+		for _, customer := range tables.SortedCustomers() {
+			if customerRFMValues, ok := values[customer.Id]; ok {
+				customerRFMValues.Monetary = memory.Round(customerRFMValues.Monetary, 2)
+				customersRFMValues = append(customersRFMValues, *customerRFMValues)
+			}
+		}
+	})
+
+	return customersRFMValues, nil
+}
+
+func (r *customerMemoryRepository) StoreBulk(ctx context.Context, customers []domain.Customer) ([]domain.Customer, error) {
+	if len(customers) == 0 {
+		return nil, ErrorCustomerPrepareStoreStatement.Wrap(memory.ErrorMemoryNoRows)
+	}
+
+	err := r.store.Write(func(tables *memory.Tables) error {
+		ids := make([]int, 0, len(customers))
+		for _, customer := range customers {
+			ids = append(ids, customer.Id)
+		}
+
+		if err := memory.CheckIds(ids, func(id int) bool { _, ok := tables.Customers[id]; return ok }); err != nil {
+			return err
+		}
+
+		for _, customer := range customers {
+			tables.Customers[customer.Id] = customer
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, ErrorCustomerExecStoreStatement.Wrap(err)
+	}
+
+	return customers, nil
+}
+
+func customerRow(customer domain.Customer) web.Row {
+	return func(field string) interface{} {
+		switch field {
+		case "id":
+			return customer.Id
+		case "first_name":
+			return customer.FirstName
+		case "last_name":
+			return customer.LastName
+		case "situation":
+			return customer.Situation
+		}
+
+		return nil
+	}
+}
@@ -5,44 +5,70 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
 	// Db queries & statements
 	GetCustomerQuery                  = "SELECT id, first_name, last_name, situation FROM customers WHERE id = ?"
+	GetAllCustomersQuery              = "SELECT id, first_name, last_name, situation FROM customers ORDER BY id"
 	GetCustomersTotalByConditionQuery = "SELECT customers.situation, ROUND(SUM(invoices.total), 2) FROM customers INNER JOIN invoices ON invoices.customer_id = customers.id GROUP BY customers.situation;"
 	GetCustomersCheaperProductsQuery  = "SELECT DISTINCT(customers.last_name), customers.first_name, products.price FROM customers INNER JOIN invoices ON invoices.customer_id = customers.id INNER JOIN sales ON sales.invoice_id = invoices.id INNER JOIN products ON sales.product_id = products.id ORDER BY products.price ASC, customers.last_name ASC LIMIT 5;"
+	GetCustomersRFMValuesQuery        = "SELECT customers.id, customers.first_name, customers.last_name, customers.situation, MAX(invoices.datetime), COUNT(invoices.id), ROUND(SUM(invoices.total), 2) FROM customers INNER JOIN invoices ON invoices.customer_id = customers.id GROUP BY customers.id, customers.first_name, customers.last_name, customers.situation ORDER BY customers.id;"
 	StoreCustomerStatement            = "INSERT INTO customers(first_name, last_name, situation) VALUES(?, ?, ?)"
+	ListCustomersQuery                = "SELECT id, first_name, last_name, situation FROM customers %s %s %s"
+	CountCustomersQuery               = "SELECT COUNT(*) FROM customers %s"
+
+	// Bulk insert columns
+	StoreCustomersColumns = []string{"id", "first_name", "last_name", "situation"}
+
+	// Listing allow-list
+	CustomerQueryColumns = web.QueryColumns{
+		"id":         "id",
+		"first_name": "first_name",
+		"last_name":  "last_name",
+		"situation":  "situation",
+	}
+	CustomerDefaultSort = []web.Sort{{Field: "id"}}
 
 	// Errors
-	ErrorCustomerNotFound              = errors.New("customer not found")
-	ErrorCustomerPrepareStoreStatement = errors.New("can not prepare store statement")
-	ErrorCustomerExecStoreStatement    = errors.New("error executing store statement")
+	ErrorCustomerNotFound              = web.NewError(web.KindNotFound, "customer_not_found", "customer not found")
+	ErrorCustomerPrepareStoreStatement = web.NewError(web.KindInternal, "customer_store_prepare_failed", "can not prepare store statement")
+	ErrorCustomerExecStoreStatement    = web.NewError(web.KindInternal, "customer_store_failed", "error executing store statement")
 )
 
 type CustomerRepository interface {
 	Get(ctx context.Context, id int) (domain.Customer, error)
+	GetAll(ctx context.Context) ([]domain.Customer, error)
+	List(ctx context.Context, spec web.QuerySpec) ([]domain.Customer, int, error)
 	GetTotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error)
 	GetCustomerCheaperProducts(ctx context.Context) ([]domain.CustomerCheaperProductDTO, error)
+	GetRFMValues(ctx context.Context) ([]domain.CustomerRFMValuesDTO, error)
 	StoreBulk(ctx context.Context, customers []domain.Customer) ([]domain.Customer, error)
 }
 
 func NewCustomerRepository(db *sql.DB) CustomerRepository {
+	return NewCustomerRepositoryWithDialect(db, storage.MySQL)
+}
+
+// NewCustomerRepositoryWithDialect returns a CustomerRepository running its queries
+// in dialect.
+func NewCustomerRepositoryWithDialect(db *sql.DB, dialect storage.Dialect) CustomerRepository {
 	return &customerRepository{
-		db: db,
+		db: storage.New(db, dialect),
 	}
 }
 
 type customerRepository struct {
-	db *sql.DB
+	db *storage.DB
 }
 
 func (r *customerRepository) Get(ctx context.Context, id int) (domain.Customer, error) {
 	var customer domain.Customer
-	err := r.db.QueryRowContext(ctx, GetCustomerQuery, id).Scan(&customer.Id, &customer.FirstName, &customer.LastName, &customer.Situation)
+	err := r.db.Reader(ctx).QueryRowContext(ctx, GetCustomerQuery, id).Scan(&customer.Id, &customer.FirstName, &customer.LastName, &customer.Situation)
 
 	if err != nil {
 		return domain.Customer{}, ErrorCustomerNotFound
@@ -51,47 +77,105 @@ func (r *customerRepository) Get(ctx context.Context, id int) (domain.Customer,
 	return customer, nil
 }
 
-func (r *customerRepository) StoreBulk(ctx context.Context, customers []domain.Customer) ([]domain.Customer, error) {
-	valueStrings := make([]string, 0, len(customers))
-	valueArgs := make([]interface{}, 0, len(customers)*4)
+func (r *customerRepository) GetAll(ctx context.Context) ([]domain.Customer, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetAllCustomersQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var customers []domain.Customer
+
+	for rows.Next() {
+		var customer domain.Customer
+		err = rows.Scan(&customer.Id, &customer.FirstName, &customer.LastName, &customer.Situation)
+		if err != nil {
+			return nil, err
+		}
+
+		customers = append(customers, customer)
+	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return customers, nil
+}
+
+func (r *customerRepository) StoreBulk(ctx context.Context, customers []domain.Customer) ([]domain.Customer, error) {
+	rows := make([][]interface{}, 0, len(customers))
 	for _, customer := range customers {
-		valueStrings = append(valueStrings, "(?, ?, ?, ?)")
-		valueArgs = append(valueArgs, customer.Id)
-		valueArgs = append(valueArgs, customer.FirstName)
-		valueArgs = append(valueArgs, customer.LastName)
-		valueArgs = append(valueArgs, customer.Situation)
+		rows = append(rows, []interface{}{customer.Id, customer.FirstName, customer.LastName, customer.Situation})
+	}
+
+	err := r.db.InsertBulk(ctx, "customers", StoreCustomersColumns, rows)
+
+	if errors.Is(err, storage.ErrorStoragePrepareBulk) {
+		return nil, ErrorCustomerPrepareStoreStatement.Wrap(err)
 	}
 
-	stmtString := fmt.Sprintf("INSERT INTO customers (id, first_name, last_name, situation) VALUES %s", strings.Join(valueStrings, ","))
-	stmt, err := r.db.PrepareContext(ctx, stmtString)
 	if err != nil {
-		return nil, ErrorCustomerPrepareStoreStatement
+		return nil, ErrorCustomerExecStoreStatement.Wrap(err)
 	}
 
-	defer stmt.Close()
+	return customers, nil
+}
 
-	result, err := r.db.Exec(stmtString, valueArgs...)
+// List returns the page of customers of spec and how many customers match
+// its filters.
+func (r *customerRepository) List(ctx context.Context, spec web.QuerySpec) ([]domain.Customer, int, error) {
+	clauses, err := spec.SQL(CustomerQueryColumns, CustomerDefaultSort)
 
 	if err != nil {
-		return nil, ErrorCustomerExecStoreStatement
+		return nil, 0, err
 	}
 
-	_, err = result.RowsAffected()
+	var total int
+	err = r.db.Reader(ctx).QueryRowContext(ctx, fmt.Sprintf(CountCustomersQuery, clauses.Where), clauses.Args...).Scan(&total)
+
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return customers, nil
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, fmt.Sprintf(ListCustomersQuery, clauses.Where, clauses.OrderBy, clauses.Limit), clauses.Args...)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	customers := []domain.Customer{}
+
+	for rows.Next() {
+		var customer domain.Customer
+		err = rows.Scan(&customer.Id, &customer.FirstName, &customer.LastName, &customer.Situation)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		customers = append(customers, customer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return customers, total, nil
 }
 
 func (r *customerRepository) GetTotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error) {
-	rows, err := r.db.QueryContext(ctx, GetCustomersTotalByConditionQuery)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetCustomersTotalByConditionQuery)
 
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var customerTotalByConditions []domain.CustomerTotalByConditionDTO
 
 	for rows.Next() {
@@ -104,16 +188,22 @@ func (r *customerRepository) GetTotalByCondition(ctx context.Context) ([]domain.
 		customerTotalByConditions = append(customerTotalByConditions, customerTotalByCondition)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return customerTotalByConditions, nil
 }
 
 func (r *customerRepository) GetCustomerCheaperProducts(ctx context.Context) ([]domain.CustomerCheaperProductDTO, error) {
-	rows, err := r.db.QueryContext(ctx, GetCustomersCheaperProductsQuery)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetCustomersCheaperProductsQuery)
 
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var customerCheaperProducts []domain.CustomerCheaperProductDTO
 
 	for rows.Next() {
@@ -127,5 +217,45 @@ func (r *customerRepository) GetCustomerCheaperProducts(ctx context.Context) ([]
 		customerCheaperProducts = append(customerCheaperProducts, customerCheaperProduct)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return customerCheaperProducts, nil
 }
+
+func (r *customerRepository) GetRFMValues(ctx context.Context) ([]domain.CustomerRFMValuesDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetCustomersRFMValuesQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var customersRFMValues []domain.CustomerRFMValuesDTO
+
+	for rows.Next() {
+		var customerRFMValues domain.CustomerRFMValuesDTO
+		err = rows.Scan(
+			&customerRFMValues.Customer.Id,
+			&customerRFMValues.Customer.FirstName,
+			&customerRFMValues.Customer.LastName,
+			&customerRFMValues.Customer.Situation,
+			&customerRFMValues.LastPurchase,
+			&customerRFMValues.Frequency,
+			&customerRFMValues.Monetary,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		customersRFMValues = append(customersRFMValues, customerRFMValues)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return customersRFMValues, nil
+}
@@ -2,13 +2,33 @@ package customer
 
 import (
 	"context"
+	"log"
+	"os"
 	"testing"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
 	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
+// dialect is the one of the DB of the config, the repositories run on
+var dialect = storage.MySQL
+
+func TestMain(m *testing.M) {
+	configDialect, remove, err := sql.RegisterConfigTxDb([]string{"-env", "../../cmd/server/.env"})
+	if err == nil {
+		dialect = configDialect
+	} else {
+		log.Printf("repository tests need a db: %v", err)
+	}
+
+	code := m.Run()
+	remove()
+	os.Exit(code)
+}
+
 var customersToStoreAndGet = []domain.Customer{
 	{
 		Id:        40000,
@@ -58,10 +78,8 @@ var customersToStoreErrorStore = []domain.Customer{
 
 func TestCustomerGet(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
 
 	// Act
 	customerStored, _ := repository.StoreBulk(context.Background(), customersToStoreAndGet)
@@ -74,10 +92,8 @@ func TestCustomerGet(t *testing.T) {
 
 func TestCustomerGetNotFound(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.Get(context.Background(), 99999)
@@ -89,10 +105,8 @@ func TestCustomerGetNotFound(t *testing.T) {
 
 func TestCustomerStoreBulk(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), customersToStore)
@@ -104,10 +118,8 @@ func TestCustomerStoreBulk(t *testing.T) {
 
 func TestCustomerStoreBulkErrorPrepare(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), customersToStoreErrorPrepare)
@@ -119,10 +131,8 @@ func TestCustomerStoreBulkErrorPrepare(t *testing.T) {
 
 func TestCustomerStoreBulkError(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), customersToStoreErrorStore)
@@ -134,13 +144,11 @@ func TestCustomerStoreBulkError(t *testing.T) {
 
 func TestCustomerGetTotalByCondition(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
 
 	// Act
-	_, err = repository.GetTotalByCondition(context.Background())
+	_, err := repository.GetTotalByCondition(context.Background())
 
 	// Assert
 	assert.Nil(t, err, "error should be nil")
@@ -148,14 +156,57 @@ func TestCustomerGetTotalByCondition(t *testing.T) {
 
 func TestGetCustomerCheaperProducts(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
+
+	// Act
+	_, err := repository.GetCustomerCheaperProducts(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+}
+
+func TestCustomerGetAll(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
+
+	// Act
+	_, err := repository.StoreBulk(context.Background(), customersToStore)
+	assert.Nil(t, err, "error should be nil")
+	result, err := repository.GetAll(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, len(result) >= 3, "result should has at least the stored customers")
+}
+
+func TestCustomerGetRFMValues(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
+
+	// Act
+	_, err := repository.GetRFMValues(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+}
+
+func TestCustomerList(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewCustomerRepositoryWithDialect(db, dialect)
+
+	_, err := repository.StoreBulk(context.Background(), customersToStore)
 	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewCustomerRepository(db)
+	spec := web.QuerySpec{Page: 1, Size: 10}.WithFilter("id", web.OperatorIn, "1000", "1002")
 
 	// Act
-	_, err = repository.GetCustomerCheaperProducts(context.Background())
+	result, total, err := repository.List(context.Background(), spec)
 
 	// Assert
 	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, total, "total should count the filtered customers")
+	assert.Equal(t, []domain.Customer{customersToStore[0], customersToStore[2]}, result, "result should be sorted by id")
 }
@@ -0,0 +1,208 @@
+package customer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+const (
+	// Situation of the customers that are left out of the scoring
+	SituationBlocked = "Bloqueado"
+
+	// Segments
+	SegmentChampions   = "champions"
+	SegmentLoyal       = "loyal"
+	SegmentAtRisk      = "at_risk"
+	SegmentPotential   = "potential"
+	SegmentLost        = "lost"
+	SegmentHibernating = "hibernating"
+	SegmentBlocked     = "blocked"
+
+	DefaultRFMBuckets = 5
+)
+
+var (
+	// Errors
+	ErrorRFMInvalidBuckets      = web.NewError(web.KindValidation, "rfm_invalid_buckets", "buckets must be between 2 and 10")
+	ErrorRFMInvalidLastPurchase = web.NewError(web.KindInternal, "rfm_invalid_last_purchase", "invalid last purchase datetime")
+
+	// DefaultRFMSegments are evaluated in order, the first one matching a customer wins
+	DefaultRFMSegments = []RFMSegment{
+		{Name: SegmentChampions, MinRecency: 0.75, MaxRecency: 1, MinFrequencyMonetary: 0.75, MaxFrequencyMonetary: 1},
+		{Name: SegmentLoyal, MinRecency: 0.5, MaxRecency: 1, MinFrequencyMonetary: 0.5, MaxFrequencyMonetary: 1},
+		{Name: SegmentAtRisk, MinRecency: 0, MaxRecency: 0.5, MinFrequencyMonetary: 0.5, MaxFrequencyMonetary: 1},
+		{Name: SegmentPotential, MinRecency: 0.5, MaxRecency: 1, MinFrequencyMonetary: 0, MaxFrequencyMonetary: 1},
+		{Name: SegmentLost, MinRecency: 0, MaxRecency: 0.25, MinFrequencyMonetary: 0, MaxFrequencyMonetary: 1},
+		{Name: SegmentHibernating, MinRecency: 0, MaxRecency: 1, MinFrequencyMonetary: 0, MaxFrequencyMonetary: 1},
+	}
+)
+
+// RFMSegment names a region of the recency / frequency-monetary plane. Bounds
+// are inclusive and expressed as normalized scores between 0 (worst bucket)
+// and 1 (best bucket), so the same rules work for any number of buckets.
+type RFMSegment struct {
+	Name                 string
+	MinRecency           float64
+	MaxRecency           float64
+	MinFrequencyMonetary float64
+	MaxFrequencyMonetary float64
+}
+
+type RFMConfig struct {
+	Buckets       int          // quantile buckets for each score, DefaultRFMBuckets when 0
+	ReferenceDate time.Time    // recency is measured from here, last purchase in data when zero
+	Situation     string       // only return customers in this situation
+	Segment       string       // only return customers in this segment
+	Segments      []RFMSegment // DefaultRFMSegments when empty
+}
+
+func (c RFMConfig) withDefaults() (RFMConfig, error) {
+	if c.Buckets == 0 {
+		c.Buckets = DefaultRFMBuckets
+	}
+
+	if c.Buckets < 2 || c.Buckets > 10 {
+		return RFMConfig{}, ErrorRFMInvalidBuckets
+	}
+
+	if len(c.Segments) == 0 {
+		c.Segments = DefaultRFMSegments
+	}
+
+	return c, nil
+}
+
+// CalculateRFM scores every customer from 1 to config.Buckets on recency,
+// frequency and monetary value and assigns a segment. Blocked customers are
+// not part of the scored population and always land in SegmentBlocked.
+func CalculateRFM(values []domain.CustomerRFMValuesDTO, config RFMConfig) ([]domain.CustomerRFMDTO, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	referenceDate := config.ReferenceDate
+	lastPurchases := make([]time.Time, len(values))
+	for i, value := range values {
+		lastPurchase, err := time.Parse(domain.InvoiceDatetimeLayout, value.LastPurchase)
+		if err != nil {
+			return nil, ErrorRFMInvalidLastPurchase
+		}
+
+		lastPurchases[i] = lastPurchase
+		if config.ReferenceDate.IsZero() && lastPurchase.After(referenceDate) {
+			referenceDate = lastPurchase
+		}
+	}
+
+	var scored []int
+	var recencies, frequencies, monetaries []float64
+	customersRFM := make([]domain.CustomerRFMDTO, len(values))
+
+	for i, value := range values {
+		recency := int(referenceDate.Sub(lastPurchases[i]).Hours() / 24)
+		customersRFM[i] = domain.CustomerRFMDTO{
+			Id:           value.Customer.Id,
+			FirstName:    value.Customer.FirstName,
+			LastName:     value.Customer.LastName,
+			Situation:    value.Customer.Situation,
+			LastPurchase: value.LastPurchase,
+			Recency:      recency,
+			Frequency:    value.Frequency,
+			Monetary:     value.Monetary,
+			Segment:      SegmentBlocked,
+		}
+
+		if value.Customer.Situation == SituationBlocked {
+			continue
+		}
+
+		scored = append(scored, i)
+		// Less days since the last purchase is better, so it is negated to rank it like the others
+		recencies = append(recencies, -float64(recency))
+		frequencies = append(frequencies, float64(value.Frequency))
+		monetaries = append(monetaries, value.Monetary)
+	}
+
+	recencyScores := quantileScores(recencies, config.Buckets)
+	frequencyScores := quantileScores(frequencies, config.Buckets)
+	monetaryScores := quantileScores(monetaries, config.Buckets)
+
+	for i, index := range scored {
+		customerRFM := &customersRFM[index]
+		customerRFM.RecencyScore = recencyScores[i]
+		customerRFM.FrequencyScore = frequencyScores[i]
+		customerRFM.MonetaryScore = monetaryScores[i]
+		customerRFM.Segment = segmentFor(customerRFM, config)
+	}
+
+	var result []domain.CustomerRFMDTO
+	for _, customerRFM := range customersRFM {
+		if config.Situation != "" && customerRFM.Situation != config.Situation {
+			continue
+		}
+
+		if config.Segment != "" && customerRFM.Segment != config.Segment {
+			continue
+		}
+
+		result = append(result, customerRFM)
+	}
+
+	return result, nil
+}
+
+// quantileScores splits the percent rank of each value into equally sized
+// buckets, the highest values get the highest score. Equal values always get
+// the same score.
+func quantileScores(values []float64, buckets int) []int {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return values[indexes[i]] < values[indexes[j]]
+	})
+
+	scores := make([]int, len(values))
+	rank := 0
+	for position, index := range indexes {
+		if position == 0 || values[index] != values[indexes[position-1]] {
+			rank = position
+		}
+
+		percentRank := 1.0
+		if len(values) > 1 {
+			percentRank = float64(rank) / float64(len(values)-1)
+		}
+
+		scores[index] = int(percentRank*float64(buckets)) + 1
+		if scores[index] > buckets {
+			scores[index] = buckets
+		}
+	}
+
+	return scores
+}
+
+func segmentFor(customerRFM *domain.CustomerRFMDTO, config RFMConfig) string {
+	recency := normalizeScore(customerRFM.RecencyScore, config.Buckets)
+	frequencyMonetary := (normalizeScore(customerRFM.FrequencyScore, config.Buckets) + normalizeScore(customerRFM.MonetaryScore, config.Buckets)) / 2
+
+	for _, segment := range config.Segments {
+		if recency >= segment.MinRecency && recency <= segment.MaxRecency &&
+			frequencyMonetary >= segment.MinFrequencyMonetary && frequencyMonetary <= segment.MaxFrequencyMonetary {
+			return segment.Name
+		}
+	}
+
+	return SegmentHibernating
+}
+
+func normalizeScore(score int, buckets int) float64 {
+	return float64(score-1) / float64(buckets-1)
+}
@@ -5,7 +5,9 @@ import (
 	"strconv"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
 	"github.com/matias-ziliotto/HackthonGo/pkg/file"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
@@ -14,19 +16,45 @@ var (
 
 type CustomerService interface {
 	Get(ctx context.Context, id int) (domain.Customer, error)
+	GetAll(ctx context.Context, config RFMConfig) ([]domain.Customer, error)
+	List(ctx context.Context, spec web.QuerySpec, config RFMConfig) ([]domain.Customer, int, error)
 	StoreBulk(ctx context.Context) ([]domain.Customer, error)
 	GetTotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error)
 	GetCustomerCheaperProducts(ctx context.Context) ([]domain.CustomerCheaperProductDTO, error)
+	GetRFM(ctx context.Context, config RFMConfig) ([]domain.CustomerRFMDTO, error)
 }
 
 func NewCustomerService(pr CustomerRepository) CustomerService {
 	return &customerService{
 		repository: pr,
+		txtPath:    CustomerTxtPath,
+	}
+}
+
+// NewCustomerServiceWithSummaries returns a CustomerService whose totals by
+// condition read the daily summaries instead of every invoice.
+func NewCustomerServiceWithSummaries(pr CustomerRepository, sr summary.SummaryRepository) CustomerService {
+	return &customerService{
+		repository: pr,
+		summaries:  sr,
+		txtPath:    CustomerTxtPath,
+	}
+}
+
+// NewCustomerServiceWithDataFile is NewCustomerServiceWithSummaries loading the
+// customers from txtPath instead of CustomerTxtPath.
+func NewCustomerServiceWithDataFile(pr CustomerRepository, sr summary.SummaryRepository, txtPath string) CustomerService {
+	return &customerService{
+		repository: pr,
+		summaries:  sr,
+		txtPath:    txtPath,
 	}
 }
 
 type customerService struct {
 	repository CustomerRepository
+	summaries  summary.SummaryRepository
+	txtPath    string
 }
 
 func (s *customerService) Get(ctx context.Context, id int) (domain.Customer, error) {
@@ -39,8 +67,104 @@ func (s *customerService) Get(ctx context.Context, id int) (domain.Customer, err
 	return customer, nil
 }
 
+// GetAll returns every customer, filtered by config.Situation and, when
+// config.Segment is set, by the RFM segment calculated with config.
+func (s *customerService) GetAll(ctx context.Context, config RFMConfig) ([]domain.Customer, error) {
+	if config.Segment != "" {
+		customersRFM, err := s.GetRFM(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+
+		customers := make([]domain.Customer, 0, len(customersRFM))
+		for _, customerRFM := range customersRFM {
+			customers = append(customers, domain.Customer{
+				Id:        customerRFM.Id,
+				FirstName: customerRFM.FirstName,
+				LastName:  customerRFM.LastName,
+				Situation: customerRFM.Situation,
+			})
+		}
+
+		return customers, nil
+	}
+
+	customers, err := s.repository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Situation == "" {
+		return customers, nil
+	}
+
+	customersBySituation := make([]domain.Customer, 0, len(customers))
+	for _, customer := range customers {
+		if customer.Situation == config.Situation {
+			customersBySituation = append(customersBySituation, customer)
+		}
+	}
+
+	return customersBySituation, nil
+}
+
+// List returns a page of the customers of GetAll, paginated, sorted and
+// filtered by spec. The segment is only known once the RFM is calculated,
+// so the customers of a segment are paged from it instead of the table.
+func (s *customerService) List(ctx context.Context, spec web.QuerySpec, config RFMConfig) ([]domain.Customer, int, error) {
+	if config.Segment != "" {
+		return s.listSegment(ctx, spec, config)
+	}
+
+	if config.Situation != "" {
+		spec = spec.WithFilter("situation", web.OperatorEq, config.Situation)
+	}
+
+	customers, total, err := s.repository.List(ctx, spec)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return customers, total, nil
+}
+
+// listSegment returns the page of spec of the customers in config.Segment.
+func (s *customerService) listSegment(ctx context.Context, spec web.QuerySpec, config RFMConfig) ([]domain.Customer, int, error) {
+	customersRFM, err := s.GetRFM(ctx, config)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	all := make([]domain.Customer, 0, len(customersRFM))
+	rows := make([]web.Row, 0, len(customersRFM))
+	for _, customerRFM := range customersRFM {
+		customer := domain.Customer{
+			Id:        customerRFM.Id,
+			FirstName: customerRFM.FirstName,
+			LastName:  customerRFM.LastName,
+			Situation: customerRFM.Situation,
+		}
+		all = append(all, customer)
+		rows = append(rows, customerRow(customer))
+	}
+
+	indexes, total, err := spec.Select(CustomerQueryColumns, CustomerDefaultSort, rows)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	customers := make([]domain.Customer, 0, len(indexes))
+	for _, index := range indexes {
+		customers = append(customers, all[index])
+	}
+
+	return customers, total, nil
+}
+
 func (s *customerService) StoreBulk(ctx context.Context) ([]domain.Customer, error) {
-	data, err := file.ReadFile(CustomerTxtPath)
+	data, err := file.ReadFile(s.txtPath)
 
 	if err != nil {
 		return nil, err
@@ -85,7 +209,12 @@ func (s *customerService) StoreBulk(ctx context.Context) ([]domain.Customer, err
 }
 
 func (s *customerService) GetTotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error) {
-	customersTotalByContidion, err := s.repository.GetTotalByCondition(ctx)
+	getTotalByCondition := s.repository.GetTotalByCondition
+	if s.summaries != nil {
+		getTotalByCondition = s.summaries.TotalByCondition
+	}
+
+	customersTotalByContidion, err := getTotalByCondition(ctx)
 
 	if err != nil {
 		return nil, err
@@ -103,3 +232,13 @@ func (s *customerService) GetCustomerCheaperProducts(ctx context.Context) ([]dom
 
 	return customersCheaperProducts, nil
 }
+
+func (s *customerService) GetRFM(ctx context.Context, config RFMConfig) ([]domain.CustomerRFMDTO, error) {
+	customersRFMValues, err := s.repository.GetRFMValues(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return CalculateRFM(customersRFMValues, config)
+}
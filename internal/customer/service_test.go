@@ -7,6 +7,8 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -174,3 +176,197 @@ func TestServiceCustomerGetCheaperProductError(t *testing.T) {
 	assert.Error(t, err, "error should exists")
 	assert.Nil(t, result, "result should be nil")
 }
+
+func TestServiceCustomerGetRFM(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation", "last_purchase", "frequency", "monetary"})
+	rows.AddRow(1, "Pepe", "Argento", "Activo", "2021-12-31 10:00:00", 4, 4000.0)
+	rows.AddRow(2, "Moni", "Argento", "Activo", "2021-12-01 10:00:00", 3, 3000.0)
+	rows.AddRow(3, "Coki", "Argento", "Inactivo", "2021-11-01 10:00:00", 1, 100.0)
+	rows.AddRow(4, "Paola", "Argento", "Bloqueado", "2021-12-31 10:00:00", 9, 9000.0)
+	mock.ExpectQuery("SELECT customers.id").WillReturnRows(rows)
+
+	// Act
+	result, err := customerService.GetRFM(context.Background(), RFMConfig{Buckets: 3})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 4, len(result), "len of result should be equal to 4")
+	assert.Equal(t, SegmentChampions, result[0].Segment, "first customer should be a champion")
+	assert.Equal(t, 3, result[0].RecencyScore, "first customer should have the best recency score")
+	assert.Equal(t, 30, result[1].Recency, "second customer recency should be 30 days")
+	assert.Equal(t, SegmentLost, result[2].Segment, "third customer should be lost")
+	assert.Equal(t, SegmentBlocked, result[3].Segment, "blocked customer should not be scored")
+}
+
+func TestServiceCustomerGetRFMFilterSituation(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation", "last_purchase", "frequency", "monetary"})
+	rows.AddRow(1, "Pepe", "Argento", "Activo", "2021-12-31 10:00:00", 4, 4000.0)
+	rows.AddRow(3, "Coki", "Argento", "Inactivo", "2021-11-01 10:00:00", 1, 100.0)
+	mock.ExpectQuery("SELECT customers.id").WillReturnRows(rows)
+
+	// Act
+	result, err := customerService.GetRFM(context.Background(), RFMConfig{Situation: "Inactivo"})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1, len(result), "len of result should be equal to 1")
+	assert.Equal(t, 3, result[0].Id, "result should only have the inactive customer")
+}
+
+func TestServiceCustomerGetRFMInvalidBuckets(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation", "last_purchase", "frequency", "monetary"})
+	mock.ExpectQuery("SELECT customers.id").WillReturnRows(rows)
+
+	// Act
+	result, err := customerService.GetRFM(context.Background(), RFMConfig{Buckets: 1})
+
+	// Assert
+	assert.Equal(t, ErrorRFMInvalidBuckets, err, "error should be invalid buckets")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceCustomerGetRFMError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	mock.ExpectQuery("SELECT customers.id").WillReturnError(errors.New("error"))
+
+	// Act
+	result, err := customerService.GetRFM(context.Background(), RFMConfig{})
+
+	// Assert
+	assert.Error(t, err, "error should exists")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceCustomerGetAll(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation"})
+	rows.AddRow(1, "Pepe", "Argento", "Activo")
+	rows.AddRow(2, "Coki", "Argento", "Inactivo")
+	mock.ExpectQuery(GetAllCustomersQuery).WillReturnRows(rows)
+
+	// Act
+	result, err := customerService.GetAll(context.Background(), RFMConfig{Situation: "Activo"})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []domain.Customer{{Id: 1, FirstName: "Pepe", LastName: "Argento", Situation: "Activo"}}, result, "result should only have active customers")
+}
+
+func TestServiceCustomerGetAllBySegment(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation", "last_purchase", "frequency", "monetary"})
+	rows.AddRow(1, "Pepe", "Argento", "Activo", "2021-12-31 10:00:00", 4, 4000.0)
+	rows.AddRow(3, "Coki", "Argento", "Inactivo", "2021-11-01 10:00:00", 1, 100.0)
+	mock.ExpectQuery("SELECT customers.id").WillReturnRows(rows)
+
+	// Act
+	result, err := customerService.GetAll(context.Background(), RFMConfig{Segment: SegmentChampions})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []domain.Customer{{Id: 1, FirstName: "Pepe", LastName: "Argento", Situation: "Activo"}}, result, "result should only have champions")
+}
+
+func TestServiceCustomerGetTotalByConditionWithSummaries(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerServiceWithSummaries(customerRepository, summary.NewSummaryRepository(db))
+
+	rows := mock.NewRows([]string{"situation", "total"})
+	rows.AddRow("Activo", 300.0)
+	rows.AddRow("Inactivo", 100.0)
+	mock.ExpectQuery("FROM daily_situation_revenue").WillReturnRows(rows)
+
+	// Act
+	result, err := customerService.GetTotalByCondition(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []domain.CustomerTotalByConditionDTO{{Situation: "Activo", Total: 300.0}, {Situation: "Inactivo", Total: 100.0}}, result, "result should come from the summaries")
+	assert.Nil(t, mock.ExpectationsWereMet(), "invoices should not be read")
+}
+
+func TestServiceCustomerListBySituation(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM customers WHERE situation = \?`).WithArgs("Activo").WillReturnRows(mock.NewRows([]string{"count"}).AddRow(1))
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation"})
+	rows.AddRow(1001, "Coki", "Argento", "Activo")
+	mock.ExpectQuery(`SELECT id, first_name, last_name, situation FROM customers WHERE situation = \? ORDER BY id ASC LIMIT 50 OFFSET 0`).WithArgs("Activo").WillReturnRows(rows)
+
+	// Act
+	result, total, err := customerService.List(context.Background(), web.QuerySpec{Page: 1, Size: web.DefaultPageSize}, RFMConfig{Situation: "Activo"})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1, total, "total should be 1")
+	assert.Equal(t, []domain.Customer{{Id: 1001, FirstName: "Coki", LastName: "Argento", Situation: "Activo"}}, result, "result should have the active customer")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestServiceCustomerListBySegment(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	customerRepository := NewCustomerRepository(db)
+	customerService := NewCustomerService(customerRepository)
+
+	rows := mock.NewRows([]string{"id", "first_name", "last_name", "situation", "last_purchase", "frequency", "monetary"})
+	rows.AddRow(1, "Pepe", "Argento", "Activo", "2021-12-31 10:00:00", 4, 4000.0)
+	rows.AddRow(2, "Moni", "Argento", "Activo", "2021-12-31 10:00:00", 4, 4000.0)
+	rows.AddRow(3, "Coki", "Argento", "Inactivo", "2021-11-01 10:00:00", 1, 100.0)
+	rows.AddRow(4, "Paola", "Argento", "Activo", "2021-12-31 10:00:00", 4, 4000.0)
+	rows.AddRow(5, "Fatiga", "Argento", "Activo", "2021-10-01 10:00:00", 1, 100.0)
+	rows.AddRow(6, "Dardo", "Fuseneco", "Activo", "2021-09-01 10:00:00", 1, 100.0)
+	rows.AddRow(7, "Maria Elena", "Fuseneco", "Activo", "2021-08-01 10:00:00", 1, 100.0)
+	mock.ExpectQuery("SELECT customers.id").WillReturnRows(rows)
+	spec := web.QuerySpec{Page: 2, Size: 1, Sort: []web.Sort{{Field: "first_name"}}}
+
+	// Act
+	result, total, err := customerService.List(context.Background(), spec, RFMConfig{Segment: SegmentChampions})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 3, total, "total should count the champions")
+	assert.Equal(t, []domain.Customer{{Id: 4, FirstName: "Paola", LastName: "Argento", Situation: "Activo"}}, result, "result should be the second champion by first name")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the customers table should not be queried")
+}
@@ -0,0 +1,19 @@
+package domain
+
+type AnomalyDTO struct {
+	Source    string  `json:"source"`
+	Id        int     `json:"id"`
+	ProductId int     `json:"product_id,omitempty"`
+	Value     float64 `json:"value"`
+	Score     float64 `json:"score"`
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+	Method    string  `json:"method"`
+}
+
+type LoadAnomaliesDTO struct {
+	Message          string        `json:"message"`
+	HeldSales        []HeldSaleDTO `json:"held_sales"`
+	SaleAnomalies    []AnomalyDTO  `json:"sale_anomalies"`
+	InvoiceAnomalies []AnomalyDTO  `json:"invoice_anomalies"`
+}
@@ -16,3 +16,25 @@ type CustomerCheaperProductDTO struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 }
+
+type CustomerRFMValuesDTO struct {
+	Customer     Customer `json:"customer"`
+	LastPurchase string   `json:"last_purchase"`
+	Frequency    int      `json:"frequency"`
+	Monetary     float64  `json:"monetary"`
+}
+
+type CustomerRFMDTO struct {
+	Id             int     `json:"id"`
+	FirstName      string  `json:"first_name"`
+	LastName       string  `json:"last_name"`
+	Situation      string  `json:"situation"`
+	LastPurchase   string  `json:"last_purchase"`
+	Recency        int     `json:"recency"`
+	Frequency      int     `json:"frequency"`
+	Monetary       float64 `json:"monetary"`
+	RecencyScore   int     `json:"recency_score"`
+	FrequencyScore int     `json:"frequency_score"`
+	MonetaryScore  int     `json:"monetary_score"`
+	Segment        string  `json:"segment"`
+}
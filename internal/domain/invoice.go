@@ -1,5 +1,8 @@
 package domain
 
+// InvoiceDatetimeLayout is the layout of Invoice.Datetime
+const InvoiceDatetimeLayout = "2006-01-02 15:04:05"
+
 type Invoice struct {
 	Id          int     `json:"id"`
 	Customer_id int     `json:"customer_id"`
@@ -18,3 +21,42 @@ type InvoiceTotalDTO struct {
 	Id    int     `json:"id"`
 	Total float64 `json:"total"`
 }
+
+type InvoicePurchaseDTO struct {
+	CustomerId int    `json:"customer_id"`
+	Datetime   string `json:"datetime"`
+}
+
+type CohortDTO struct {
+	Cohort    string    `json:"cohort"`
+	Customers int       `json:"customers"`
+	Retained  []int     `json:"retained"`
+	Retention []float64 `json:"retention"`
+}
+
+type RevenuePointDTO struct {
+	Date    string  `json:"date"`
+	Revenue float64 `json:"revenue"`
+}
+
+type ForecastPointDTO struct {
+	Date   string   `json:"date"`
+	Value  float64  `json:"value"`
+	Lower  float64  `json:"lower"`
+	Upper  float64  `json:"upper"`
+	Actual *float64 `json:"actual,omitempty"`
+}
+
+type ForecastBacktestDTO struct {
+	MAPE   float64            `json:"mape"`
+	RMSE   float64            `json:"rmse"`
+	Points []ForecastPointDTO `json:"points"`
+}
+
+type ForecastDTO struct {
+	Model       string               `json:"model"`
+	Granularity string               `json:"granularity"`
+	Confidence  float64              `json:"confidence"`
+	Forecast    []ForecastPointDTO   `json:"forecast"`
+	Backtest    *ForecastBacktestDTO `json:"backtest,omitempty"`
+}
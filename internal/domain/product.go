@@ -4,9 +4,25 @@ type Product struct {
 	Id          int     `json:"id"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
+	Class       string  `json:"class"`
 }
 
 type ProductMostSelledDTO struct {
 	Description string  `json:"description"`
 	Total       float64 `json:"total"`
 }
+
+type ProductRevenueDTO struct {
+	Id          int     `json:"id"`
+	Description string  `json:"description"`
+	Revenue     float64 `json:"revenue"`
+}
+
+type ProductABCDTO struct {
+	Id              int     `json:"id"`
+	Description     string  `json:"description"`
+	Revenue         float64 `json:"revenue"`
+	Share           float64 `json:"share"`
+	CumulativeShare float64 `json:"cumulative_share"`
+	Class           string  `json:"class"`
+}
@@ -13,3 +13,28 @@ type SaleDTO struct {
 	Product  Product `json:"product"`
 	Quantity float64 `json:"quantity"`
 }
+
+type SaleBasketItemDTO struct {
+	InvoiceId   int    `json:"invoice_id"`
+	ProductId   int    `json:"product_id"`
+	Description string `json:"description"`
+}
+
+type BasketProductDTO struct {
+	Id          int    `json:"id"`
+	Description string `json:"description"`
+}
+
+type ProductAssociationDTO struct {
+	Antecedent []BasketProductDTO `json:"antecedent"`
+	Consequent BasketProductDTO   `json:"consequent"`
+	Invoices   int                `json:"invoices"`
+	Support    float64            `json:"support"`
+	Confidence float64            `json:"confidence"`
+	Lift       float64            `json:"lift"`
+}
+
+type HeldSaleDTO struct {
+	Sale   Sale   `json:"sale"`
+	Reason string `json:"reason"`
+}
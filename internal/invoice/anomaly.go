@@ -0,0 +1,47 @@
+package invoice
+
+import (
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+)
+
+const (
+	AnomalySourceInvoiceTotal = "invoice_total"
+)
+
+// InvoiceAnomalies flags the invoices with an outlier total. Invoices are
+// not grouped, so they always share a single baseline.
+func InvoiceAnomalies(invoices []domain.Invoice, config outlier.Config) ([]domain.AnomalyDTO, error) {
+	config, err := config.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	observations := make([]outlier.Observation, 0, len(invoices))
+	for _, invoice := range invoices {
+		observations = append(observations, outlier.Observation{
+			Id:    invoice.Id,
+			Value: invoice.Total,
+		})
+	}
+
+	outliers, err := outlier.Detect(observations, config)
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies := make([]domain.AnomalyDTO, 0, len(outliers))
+	for _, invoiceOutlier := range outliers {
+		anomalies = append(anomalies, domain.AnomalyDTO{
+			Source: AnomalySourceInvoiceTotal,
+			Id:     invoiceOutlier.Id,
+			Value:  invoiceOutlier.Value,
+			Score:  invoiceOutlier.Score,
+			Lower:  invoiceOutlier.Lower,
+			Upper:  invoiceOutlier.Upper,
+			Method: config.Method,
+		})
+	}
+
+	return anomalies, nil
+}
@@ -0,0 +1,114 @@
+package invoice
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+const (
+	CohortLayout = "2006-01"
+)
+
+var (
+	// Errors
+	ErrorCohortInvalidDatetime = web.NewError(web.KindInternal, "cohort_invalid_datetime", "invalid invoice datetime")
+)
+
+// CalculateCohorts groups customers by the month of their first purchase and
+// counts, for every month since then, how many of them bought again. Index 0
+// of Retained and Retention is the cohort month itself, so it is always every
+// customer of the cohort.
+func CalculateCohorts(purchases []domain.InvoicePurchaseDTO) ([]domain.CohortDTO, error) {
+	// months has the months with purchases of every customer, as months since year 0
+	months := map[int]map[int]bool{}
+	firstMonths := map[int]int{}
+	lastMonth := 0
+
+	for _, purchase := range purchases {
+		datetime, err := time.Parse(domain.InvoiceDatetimeLayout, purchase.Datetime)
+		if err != nil {
+			return nil, ErrorCohortInvalidDatetime
+		}
+
+		month := datetime.Year()*12 + int(datetime.Month()) - 1
+		if months[purchase.CustomerId] == nil {
+			months[purchase.CustomerId] = map[int]bool{}
+		}
+		months[purchase.CustomerId][month] = true
+
+		if firstMonth, ok := firstMonths[purchase.CustomerId]; !ok || month < firstMonth {
+			firstMonths[purchase.CustomerId] = month
+		}
+
+		if month > lastMonth {
+			lastMonth = month
+		}
+	}
+
+	cohortsByMonth := map[int]*domain.CohortDTO{}
+	for customerId, firstMonth := range firstMonths {
+		cohort, ok := cohortsByMonth[firstMonth]
+		if !ok {
+			cohort = &domain.CohortDTO{
+				Cohort:   time.Date(firstMonth/12, time.Month(firstMonth%12+1), 1, 0, 0, 0, 0, time.UTC).Format(CohortLayout),
+				Retained: make([]int, lastMonth-firstMonth+1),
+			}
+			cohortsByMonth[firstMonth] = cohort
+		}
+
+		cohort.Customers++
+		for month := range months[customerId] {
+			cohort.Retained[month-firstMonth]++
+		}
+	}
+
+	cohorts := make([]domain.CohortDTO, 0, len(cohortsByMonth))
+	for _, cohort := range cohortsByMonth {
+		cohort.Retention = make([]float64, len(cohort.Retained))
+		for i, retained := range cohort.Retained {
+			cohort.Retention[i] = float64(retained) / float64(cohort.Customers)
+		}
+
+		cohorts = append(cohorts, *cohort)
+	}
+
+	sort.Slice(cohorts, func(i, j int) bool {
+		return cohorts[i].Cohort < cohorts[j].Cohort
+	})
+
+	return cohorts, nil
+}
+
+// CohortsToCSV returns the retention matrix as CSV records, with a header
+// row and one row per cohort. Months a cohort did not reach yet are empty.
+func CohortsToCSV(cohorts []domain.CohortDTO) [][]string {
+	months := 0
+	for _, cohort := range cohorts {
+		if len(cohort.Retention) > months {
+			months = len(cohort.Retention)
+		}
+	}
+
+	header := []string{"cohort", "customers"}
+	for month := 0; month < months; month++ {
+		header = append(header, "month_"+strconv.Itoa(month))
+	}
+
+	records := [][]string{header}
+	for _, cohort := range cohorts {
+		record := make([]string, len(header))
+		record[0] = cohort.Cohort
+		record[1] = strconv.Itoa(cohort.Customers)
+		for month, retention := range cohort.Retention {
+			record[month+2] = strconv.FormatFloat(retention, 'f', 4, 64)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
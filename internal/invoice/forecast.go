@@ -0,0 +1,373 @@
+package invoice
+
+import (
+	"math"
+	"time"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+const (
+	// Models
+	ForecastModelMovingAverage = "moving_average"
+	ForecastModelHoltWinters   = "holt_winters"
+	ForecastModelSeasonalNaive = "seasonal_naive"
+
+	// Granularities
+	GranularityDay  = "day"
+	GranularityWeek = "week"
+
+	DateLayout = "2006-01-02"
+
+	DefaultForecastHorizon    = 7
+	MaxForecastHorizon        = 365
+	DefaultForecastConfidence = 0.95
+	DefaultHoltWintersAlpha   = 0.3
+	DefaultHoltWintersBeta    = 0.1
+	DefaultHoltWintersGamma   = 0.1
+)
+
+var (
+	// Errors
+	ErrorForecastInvalidModel       = web.NewError(web.KindValidation, "forecast_invalid_model", "model must be moving_average, holt_winters or seasonal_naive")
+	ErrorForecastInvalidGranularity = web.NewError(web.KindValidation, "forecast_invalid_granularity", "granularity must be day or week")
+	ErrorForecastInvalidHorizon     = web.NewError(web.KindValidation, "forecast_invalid_horizon", "horizon must be between 1 and 365")
+	ErrorForecastInvalidConfidence  = web.NewError(web.KindValidation, "forecast_invalid_confidence", "confidence must be 0.8, 0.9, 0.95 or 0.99")
+	ErrorForecastInvalidParameters  = web.NewError(web.KindValidation, "forecast_invalid_parameters", "window and season length must be positive and smoothing parameters between 0 and 1")
+	ErrorForecastNotEnoughData      = web.NewError(web.KindUnprocessable, "forecast_not_enough_data", "not enough data for the selected model")
+	ErrorForecastInvalidDate        = web.NewError(web.KindInternal, "forecast_invalid_date", "invalid revenue date")
+
+	// zScores are the two-sided normal quantiles of the supported confidences
+	zScores = map[float64]float64{
+		0.8:  1.2816,
+		0.9:  1.6449,
+		0.95: 1.96,
+		0.99: 2.5758,
+	}
+)
+
+type ForecastConfig struct {
+	Model        string  // ForecastModelMovingAverage when empty
+	Granularity  string  // GranularityDay when empty
+	Horizon      int     // periods to forecast, DefaultForecastHorizon when 0
+	Window       int     // moving average periods, 7 days or 4 weeks when 0
+	SeasonLength int     // periods of a season, 7 days or 4 weeks when 0
+	Alpha        float64 // Holt-Winters level smoothing
+	Beta         float64 // Holt-Winters trend smoothing
+	Gamma        float64 // Holt-Winters seasonal smoothing
+	Confidence   float64 // DefaultForecastConfidence when 0
+	Backtest     bool    // also forecast the last Horizon periods and compare them with the real ones
+}
+
+func (c ForecastConfig) withDefaults() (ForecastConfig, error) {
+	if c.Model == "" {
+		c.Model = ForecastModelMovingAverage
+	}
+
+	if c.Model != ForecastModelMovingAverage && c.Model != ForecastModelHoltWinters && c.Model != ForecastModelSeasonalNaive {
+		return ForecastConfig{}, ErrorForecastInvalidModel
+	}
+
+	if c.Granularity == "" {
+		c.Granularity = GranularityDay
+	}
+
+	if c.Granularity != GranularityDay && c.Granularity != GranularityWeek {
+		return ForecastConfig{}, ErrorForecastInvalidGranularity
+	}
+
+	if c.Horizon == 0 {
+		c.Horizon = DefaultForecastHorizon
+	}
+
+	if c.Horizon < 1 || c.Horizon > MaxForecastHorizon {
+		return ForecastConfig{}, ErrorForecastInvalidHorizon
+	}
+
+	defaultPeriods := 7
+	if c.Granularity == GranularityWeek {
+		defaultPeriods = 4
+	}
+
+	if c.Window == 0 {
+		c.Window = defaultPeriods
+	}
+
+	if c.SeasonLength == 0 {
+		c.SeasonLength = defaultPeriods
+	}
+
+	if c.Alpha == 0 {
+		c.Alpha = DefaultHoltWintersAlpha
+	}
+
+	if c.Beta == 0 {
+		c.Beta = DefaultHoltWintersBeta
+	}
+
+	if c.Gamma == 0 {
+		c.Gamma = DefaultHoltWintersGamma
+	}
+
+	if c.Window < 1 || c.SeasonLength < 1 || c.Alpha > 1 || c.Beta > 1 || c.Gamma > 1 || c.Alpha < 0 || c.Beta < 0 || c.Gamma < 0 {
+		return ForecastConfig{}, ErrorForecastInvalidParameters
+	}
+
+	if c.Confidence == 0 {
+		c.Confidence = DefaultForecastConfidence
+	}
+
+	if _, ok := zScores[c.Confidence]; !ok {
+		return ForecastConfig{}, ErrorForecastInvalidConfidence
+	}
+
+	return c, nil
+}
+
+// CalculateForecast builds a gap free series from dailyRevenue with the
+// configured granularity and forecasts the next config.Horizon periods.
+// Intervals come from the standard deviation of the one step ahead errors of
+// the model over the history, widened with the square root of the step.
+func CalculateForecast(dailyRevenue []domain.RevenuePointDTO, config ForecastConfig) (domain.ForecastDTO, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return domain.ForecastDTO{}, err
+	}
+
+	start, series, err := revenueSeries(dailyRevenue, config.Granularity)
+	if err != nil {
+		return domain.ForecastDTO{}, err
+	}
+
+	forecast := domain.ForecastDTO{
+		Model:       config.Model,
+		Granularity: config.Granularity,
+		Confidence:  config.Confidence,
+	}
+
+	forecast.Forecast, err = forecastPoints(series, start, len(series), config)
+	if err != nil {
+		return domain.ForecastDTO{}, err
+	}
+
+	if config.Backtest {
+		if len(series) <= config.Horizon {
+			return domain.ForecastDTO{}, ErrorForecastNotEnoughData
+		}
+
+		train := series[:len(series)-config.Horizon]
+		points, err := forecastPoints(train, start, len(train), config)
+		if err != nil {
+			return domain.ForecastDTO{}, err
+		}
+
+		backtest := domain.ForecastBacktestDTO{Points: points}
+		var squaredErrors, percentageErrors float64
+		var percentagePoints int
+
+		for i := range backtest.Points {
+			actual := series[len(train)+i]
+			backtest.Points[i].Actual = &actual
+
+			difference := actual - backtest.Points[i].Value
+			squaredErrors += difference * difference
+			if actual != 0 {
+				percentageErrors += math.Abs(difference / actual)
+				percentagePoints++
+			}
+		}
+
+		backtest.RMSE = math.Sqrt(squaredErrors / float64(len(backtest.Points)))
+		if percentagePoints > 0 {
+			backtest.MAPE = percentageErrors / float64(percentagePoints) * 100
+		}
+
+		forecast.Backtest = &backtest
+	}
+
+	return forecast, nil
+}
+
+// forecastPoints forecasts the periods after series, which starts at start.
+// offset is the index of the first forecasted period since start.
+func forecastPoints(series []float64, start time.Time, offset int, config ForecastConfig) ([]domain.ForecastPointDTO, error) {
+	var fitted, predictions []float64
+	var err error
+
+	switch config.Model {
+	case ForecastModelMovingAverage:
+		fitted, predictions, err = movingAverage(series, config.Window, config.Horizon)
+	case ForecastModelSeasonalNaive:
+		fitted, predictions, err = seasonalNaive(series, config.SeasonLength, config.Horizon)
+	case ForecastModelHoltWinters:
+		fitted, predictions, err = holtWinters(series, config.SeasonLength, config.Alpha, config.Beta, config.Gamma, config.Horizon)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var squaredErrors float64
+	var residuals int
+	for i, value := range fitted {
+		if !math.IsNaN(value) {
+			squaredErrors += (series[i] - value) * (series[i] - value)
+			residuals++
+		}
+	}
+
+	var deviation float64
+	if residuals > 0 {
+		deviation = math.Sqrt(squaredErrors / float64(residuals))
+	}
+
+	points := make([]domain.ForecastPointDTO, len(predictions))
+	for i, prediction := range predictions {
+		margin := zScores[config.Confidence] * deviation * math.Sqrt(float64(i+1))
+		points[i] = domain.ForecastPointDTO{
+			Date:  periodDate(start, offset+i, config.Granularity).Format(DateLayout),
+			Value: math.Max(prediction, 0),
+			Lower: math.Max(prediction-margin, 0),
+			Upper: math.Max(prediction+margin, 0),
+		}
+	}
+
+	return points, nil
+}
+
+// revenueSeries adds up dailyRevenue by period and fills the periods without
+// invoices with 0. Weeks start on monday.
+func revenueSeries(dailyRevenue []domain.RevenuePointDTO, granularity string) (time.Time, []float64, error) {
+	var start time.Time
+	var series []float64
+
+	for _, revenuePoint := range dailyRevenue {
+		date, err := time.Parse(DateLayout, revenuePoint.Date)
+		if err != nil {
+			return time.Time{}, nil, ErrorForecastInvalidDate
+		}
+
+		if granularity == GranularityWeek {
+			date = date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+		}
+
+		if series == nil {
+			start = date
+		}
+
+		period := int(date.Sub(start).Hours() / 24)
+		if granularity == GranularityWeek {
+			period /= 7
+		}
+
+		if period < 0 {
+			return time.Time{}, nil, ErrorForecastInvalidDate
+		}
+
+		for len(series) <= period {
+			series = append(series, 0)
+		}
+
+		series[period] += revenuePoint.Revenue
+	}
+
+	return start, series, nil
+}
+
+func periodDate(start time.Time, period int, granularity string) time.Time {
+	if granularity == GranularityWeek {
+		return start.AddDate(0, 0, period*7)
+	}
+
+	return start.AddDate(0, 0, period)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+
+	return sum / float64(len(values))
+}
+
+// nanSlice returns a slice of size NaNs, the fitted value of the periods a
+// model can not predict.
+func nanSlice(size int) []float64 {
+	values := make([]float64, size)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+
+	return values
+}
+
+func movingAverage(series []float64, window int, horizon int) ([]float64, []float64, error) {
+	if len(series) < window {
+		return nil, nil, ErrorForecastNotEnoughData
+	}
+
+	fitted := nanSlice(len(series))
+	for t := window; t < len(series); t++ {
+		fitted[t] = mean(series[t-window : t])
+	}
+
+	predictions := make([]float64, horizon)
+	last := mean(series[len(series)-window:])
+	for h := range predictions {
+		predictions[h] = last
+	}
+
+	return fitted, predictions, nil
+}
+
+func seasonalNaive(series []float64, seasonLength int, horizon int) ([]float64, []float64, error) {
+	if len(series) < seasonLength {
+		return nil, nil, ErrorForecastNotEnoughData
+	}
+
+	fitted := nanSlice(len(series))
+	for t := seasonLength; t < len(series); t++ {
+		fitted[t] = series[t-seasonLength]
+	}
+
+	predictions := make([]float64, horizon)
+	for h := range predictions {
+		predictions[h] = series[len(series)-seasonLength+h%seasonLength]
+	}
+
+	return fitted, predictions, nil
+}
+
+// holtWinters is additive triple exponential smoothing, initialized with the
+// first two seasons.
+func holtWinters(series []float64, seasonLength int, alpha float64, beta float64, gamma float64, horizon int) ([]float64, []float64, error) {
+	if len(series) < 2*seasonLength {
+		return nil, nil, ErrorForecastNotEnoughData
+	}
+
+	level := mean(series[:seasonLength])
+	trend := (mean(series[seasonLength:2*seasonLength]) - level) / float64(seasonLength)
+	seasonals := make([]float64, len(series))
+	for t := 0; t < seasonLength; t++ {
+		seasonals[t] = series[t] - level
+	}
+
+	fitted := nanSlice(len(series))
+	for t := seasonLength; t < len(series); t++ {
+		fitted[t] = level + trend + seasonals[t-seasonLength]
+
+		previousLevel := level
+		level = alpha*(series[t]-seasonals[t-seasonLength]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previousLevel) + (1-beta)*trend
+		seasonals[t] = gamma*(series[t]-level) + (1-gamma)*seasonals[t-seasonLength]
+	}
+
+	predictions := make([]float64, horizon)
+	for h := range predictions {
+		predictions[h] = level + float64(h+1)*trend + seasonals[len(series)-seasonLength+h%seasonLength]
+	}
+
+	return fitted, predictions, nil
+}
@@ -0,0 +1,232 @@
+package invoice
+
+import (
+	"context"
+	"sort"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/memory"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// NewInvoiceMemoryRepository returns an InvoiceRepository keeping the
+// invoices in store, answering like the SQL one.
+func NewInvoiceMemoryRepository(store *memory.Store) InvoiceRepository {
+	return &invoiceMemoryRepository{
+		store: store,
+	}
+}
+
+type invoiceMemoryRepository struct {
+	store *memory.Store
+}
+
+func (r *invoiceMemoryRepository) GetAllTotalEmpty(ctx context.Context) ([]int, error) {
+	var invoicesIds []int
+	r.store.Read(func(tables *memory.Tables) {
+		for _, invoice := range tables.SortedInvoices() {
+			if invoice.Total == 0 {
+				invoicesIds = append(invoicesIds, invoice.Id)
+			}
+		}
+	})
+
+	return invoicesIds, nil
+}
+
+func (r *invoiceMemoryRepository) Get(ctx context.Context, id int) (domain.Invoice, error) {
+	var invoice domain.Invoice
+	var ok bool
+	r.store.Read(func(tables *memory.Tables) {
+		invoice, ok = tables.Invoices[id]
+	})
+
+	if !ok {
+		return domain.Invoice{}, ErrorInvoiceNotFound
+	}
+
+	return invoice, nil
+}
+
+func (r *invoiceMemoryRepository) GetAll(ctx context.Context) ([]domain.Invoice, error) {
+	var invoices []domain.Invoice
+	r.store.Read(func(tables *memory.Tables) {
+		invoices = append(invoices, tables.SortedInvoices()...)
+	})
+
+	return invoices, nil
+}
+
+func (r *invoiceMemoryRepository) List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Invoice, web.Cursors, error) {
+	keyset, err := spec.Keyset(InvoiceQueryColumns, InvoiceSortColumns, InvoiceDefaultSort, codec)
+
+	if err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	var all []domain.Invoice
+	r.store.Read(func(tables *memory.Tables) {
+		all = tables.SortedInvoices()
+	})
+
+	rows := make([]web.Row, 0, len(all))
+	for _, invoice := range all {
+		rows = append(rows, invoiceRow(invoice))
+	}
+
+	var fetched []domain.Invoice
+	var keys [][]string
+	for _, index := range keyset.Select(rows) {
+		fetched = append(fetched, all[index])
+		keys = append(keys, invoiceKey(all[index], keyset.Fields))
+	}
+
+	indexes, cursors := keyset.Page(keys)
+	invoices := make([]domain.Invoice, 0, len(indexes))
+	for _, index := range indexes {
+		invoices = append(invoices, fetched[index])
+	}
+
+	return invoices, cursors, nil
+}
+
+func (r *invoiceMemoryRepository) StoreBulk(ctx context.Context, invoices []domain.Invoice) ([]domain.Invoice, error) {
+	if len(invoices) == 0 {
+		return nil, ErrorInvoicePrepareStoreStatement.Wrap(memory.ErrorMemoryNoRows)
+	}
+
+	err := r.store.Write(func(tables *memory.Tables) error {
+		ids := make([]int, 0, len(invoices))
+		for _, invoice := range invoices {
+			if _, ok := tables.Customers[invoice.Customer_id]; !ok {
+				return memory.ErrorMemoryForeignKey
+			}
+
+			ids = append(ids, invoice.Id)
+		}
+
+		if err := memory.CheckIds(ids, func(id int) bool { _, ok := tables.Invoices[id]; return ok }); err != nil {
+			return err
+		}
+
+		for _, invoice := range invoices {
+			tables.Invoices[invoice.Id] = invoice
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, ErrorInvoiceExecStoreStatement.Wrap(err)
+	}
+
+	return invoices, nil
+}
+
+func (r *invoiceMemoryRepository) UpdateTotal(ctx context.Context, invoice domain.Invoice) (domain.Invoice, error) {
+	r.store.Write(func(tables *memory.Tables) error {
+		if stored, ok := tables.Invoices[invoice.Id]; ok {
+			stored.Total = invoice.Total
+			tables.Invoices[invoice.Id] = stored
+		}
+
+		return nil
+	})
+
+	return invoice, nil
+}
+
+// CalculateTotal returns the totals of the invoices of ids with sales,
+// ordered by id.
+func (r *invoiceMemoryRepository) CalculateTotal(ctx context.Context, ids []int) ([]domain.InvoiceTotalDTO, error) {
+	wanted := map[int]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var invoiceTotals []domain.InvoiceTotalDTO
+	r.store.Read(func(tables *memory.Tables) {
+		totals := map[int]float64{}
+		for _, sale := range tables.SortedSales() {
+			product, ok := tables.Products[sale.Product_id]
+			if ok && wanted[sale.Invoice_id] {
+				totals[sale.Invoice_id] += product.Price * sale.Quantity
+			}
+		}
+
+		for _, invoice := range tables.SortedInvoices() {
+			if total, ok := totals[invoice.Id]; ok {
+				invoiceTotals = append(invoiceTotals, domain.InvoiceTotalDTO{Id: invoice.Id, Total: total})
+			}
+		}
+	})
+
+	return invoiceTotals, nil
+}
+
+func (r *invoiceMemoryRepository) GetPurchases(ctx context.Context, situation string) ([]domain.InvoicePurchaseDTO, error) {
+	var purchases []domain.InvoicePurchaseDTO
+	r.store.Read(func(tables *memory.Tables) {
+		for _, invoice := range tables.Invoices {
+			if situation != "" && tables.Customers[invoice.Customer_id].Situation != situation {
+				continue
+			}
+
+			purchases = append(purchases, domain.InvoicePurchaseDTO{CustomerId: invoice.Customer_id, Datetime: invoice.Datetime})
+		}
+	})
+
+	sort.Slice(purchases, func(i, j int) bool {
+		if purchases[i].CustomerId != purchases[j].CustomerId {
+			return purchases[i].CustomerId < purchases[j].CustomerId
+		}
+
+		return purchases[i].Datetime < purchases[j].Datetime
+	})
+
+	return purchases, nil
+}
+
+func (r *invoiceMemoryRepository) GetDailyRevenue(ctx context.Context) ([]domain.RevenuePointDTO, error) {
+	revenues := map[string]float64{}
+	r.store.Read(func(tables *memory.Tables) {
+		for _, invoice := range tables.Invoices {
+			revenues[invoiceDay(invoice)] += invoice.Total
+		}
+	})
+
+	var dailyRevenue []domain.RevenuePointDTO
+	for day, revenue := range revenues {
+		dailyRevenue = append(dailyRevenue, domain.RevenuePointDTO{Date: day, Revenue: memory.Round(revenue, 2)})
+	}
+
+	sort.Slice(dailyRevenue, func(i, j int) bool { return dailyRevenue[i].Date < dailyRevenue[j].Date })
+
+	return dailyRevenue, nil
+}
+
+// invoiceDay is the day of the datetime of invoice, its YYYY-MM-DD prefix.
+func invoiceDay(invoice domain.Invoice) string {
+	if len(invoice.Datetime) < 10 {
+		return invoice.Datetime
+	}
+
+	return invoice.Datetime[:10]
+}
+
+func invoiceRow(invoice domain.Invoice) web.Row {
+	return func(field string) interface{} {
+		switch field {
+		case "id":
+			return invoice.Id
+		case "customer_id":
+			return invoice.Customer_id
+		case "datetime":
+			return invoice.Datetime
+		case "total":
+			return invoice.Total
+		}
+
+		return nil
+	}
+}
@@ -5,52 +5,90 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
 	// Db queries & statements
 	GetAllTotalEmptyInvoiceQuery = "SELECT id FROM invoices WHERE total = 0"
 	GetInvoiceQuery              = "SELECT id, customer_id, datetime, total FROM invoices WHERE id = ?"
-	CalculateTotalInvoiceQuery   = "SELECT DISTINCT(invoices.id), SUM(calc.total) as total FROM invoices INNER JOIN ( SELECT sales.invoice_id,  SUM(products.price) * sales.quantity AS total FROM sales INNER JOIN products ON products.id = sales.product_id WHERE sales.invoice_id IN replace_with_invoices_ids GROUP BY sales.id ) calc ON calc.invoice_id = invoices.id GROUP BY calc.invoice_id;"
+	GetAllInvoicesQuery          = "SELECT id, customer_id, datetime, total FROM invoices ORDER BY id"
+	CalculateTotalInvoiceQuery   = "SELECT DISTINCT(invoices.id), SUM(calc.total) as total FROM invoices INNER JOIN ( SELECT sales.invoice_id,  SUM(products.price) * sales.quantity AS total FROM sales INNER JOIN products ON products.id = sales.product_id WHERE sales.invoice_id IN replace_with_invoices_ids GROUP BY sales.id ) calc ON calc.invoice_id = invoices.id GROUP BY invoices.id;"
+	GetPurchasesQuery            = "SELECT invoices.customer_id, invoices.datetime FROM invoices ORDER BY invoices.customer_id, invoices.datetime"
+	GetPurchasesBySituationQuery = "SELECT invoices.customer_id, invoices.datetime FROM invoices INNER JOIN customers ON customers.id = invoices.customer_id WHERE customers.situation = ? ORDER BY invoices.customer_id, invoices.datetime"
+	GetDailyRevenueQuery         = "SELECT DATE_FORMAT(invoices.datetime, '%Y-%m-%d') as day, ROUND(SUM(invoices.total), 2) FROM invoices GROUP BY day ORDER BY day"
 	StoreInvoiceStatement        = "INSERT INTO invoices(customer_id, datetime, total) VALUES(?, ?, ?)"
 	UpdateInvoiceStatement       = "UPDATE invoices SET total = ? WHERE id = ?"
+	ListInvoicesQuery            = "SELECT id, customer_id, datetime, total FROM invoices %s %s %s"
+
+	// Bulk insert columns
+	StoreInvoicesColumns = []string{"id", "customer_id", "datetime", "total"}
+
+	// Listing allow-list
+	InvoiceQueryColumns = web.QueryColumns{
+		"id":          "id",
+		"customer_id": "customer_id",
+		"datetime":    "datetime",
+		"total":       "total",
+	}
+	// The totals are floats, the cursors can not point at them
+	InvoiceSortColumns = web.QueryColumns{
+		"id":          "id",
+		"customer_id": "customer_id",
+		"datetime":    "datetime",
+	}
+	InvoiceDefaultSort = []web.Sort{{Field: "id"}}
 
 	// Errors
-	ErrorInvoiceNotFound               = errors.New("invoice not found")
-	ErrorInvoicePrepareStoreStatement  = errors.New("can not prepare store statement")
-	ErrorInvoiceExecStoreStatement     = errors.New("error executing store statement")
-	ErrorInvoicePrepareUpdateStatement = errors.New("can not prepare update statement")
-	ErrorInvoiceExecUpdateStatement    = errors.New("error executing update statement")
+	ErrorInvoiceNotFound               = web.NewError(web.KindNotFound, "invoice_not_found", "invoice not found")
+	ErrorInvoicePrepareStoreStatement  = web.NewError(web.KindInternal, "invoice_store_prepare_failed", "can not prepare store statement")
+	ErrorInvoiceExecStoreStatement     = web.NewError(web.KindInternal, "invoice_store_failed", "error executing store statement")
+	ErrorInvoicePrepareUpdateStatement = web.NewError(web.KindInternal, "invoice_update_prepare_failed", "can not prepare update statement")
+	ErrorInvoiceExecUpdateStatement    = web.NewError(web.KindInternal, "invoice_update_failed", "error executing update statement")
 )
 
 type InvoiceRepository interface {
 	GetAllTotalEmpty(ctx context.Context) ([]int, error)
 	Get(ctx context.Context, id int) (domain.Invoice, error)
+	GetAll(ctx context.Context) ([]domain.Invoice, error)
+	List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Invoice, web.Cursors, error)
 	StoreBulk(ctx context.Context, invoices []domain.Invoice) ([]domain.Invoice, error)
 	UpdateTotal(ctx context.Context, invoice domain.Invoice) (domain.Invoice, error)
 	CalculateTotal(ctx context.Context, ids []int) ([]domain.InvoiceTotalDTO, error)
+	GetPurchases(ctx context.Context, situation string) ([]domain.InvoicePurchaseDTO, error)
+	GetDailyRevenue(ctx context.Context) ([]domain.RevenuePointDTO, error)
 }
 
 func NewInvoiceRepository(db *sql.DB) InvoiceRepository {
+	return NewInvoiceRepositoryWithDialect(db, storage.MySQL)
+}
+
+// NewInvoiceRepositoryWithDialect returns a InvoiceRepository running its queries
+// in dialect.
+func NewInvoiceRepositoryWithDialect(db *sql.DB, dialect storage.Dialect) InvoiceRepository {
 	return &invoiceRepository{
-		db: db,
+		db: storage.New(db, dialect),
 	}
 }
 
 type invoiceRepository struct {
-	db *sql.DB
+	db *storage.DB
 }
 
 func (r *invoiceRepository) GetAllTotalEmpty(ctx context.Context) ([]int, error) {
-	rows, err := r.db.QueryContext(ctx, GetAllTotalEmptyInvoiceQuery)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetAllTotalEmptyInvoiceQuery)
 
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var invoicesIds []int
 
 	for rows.Next() {
@@ -63,12 +101,16 @@ func (r *invoiceRepository) GetAllTotalEmpty(ctx context.Context) ([]int, error)
 		invoicesIds = append(invoicesIds, invoiceId)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return invoicesIds, nil
 }
 
 func (r *invoiceRepository) Get(ctx context.Context, id int) (domain.Invoice, error) {
 	var invoice domain.Invoice
-	err := r.db.QueryRowContext(ctx, GetInvoiceQuery, id).Scan(&invoice.Id, &invoice.Customer_id, &invoice.Datetime, &invoice.Total)
+	err := r.db.Reader(ctx).QueryRowContext(ctx, GetInvoiceQuery, id).Scan(&invoice.Id, &invoice.Customer_id, &invoice.Datetime, &invoice.Total)
 
 	if err != nil {
 		return domain.Invoice{}, ErrorInvoiceNotFound
@@ -77,35 +119,112 @@ func (r *invoiceRepository) Get(ctx context.Context, id int) (domain.Invoice, er
 	return invoice, nil
 }
 
-func (r *invoiceRepository) StoreBulk(ctx context.Context, invoices []domain.Invoice) ([]domain.Invoice, error) {
-	valueStrings := make([]string, 0, len(invoices))
-	valueArgs := make([]interface{}, 0, len(invoices)*4)
+func (r *invoiceRepository) GetAll(ctx context.Context) ([]domain.Invoice, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetAllInvoicesQuery)
 
-	for _, invoice := range invoices {
-		valueStrings = append(valueStrings, "(?, ?, ?, ?)")
-		valueArgs = append(valueArgs, invoice.Id)
-		valueArgs = append(valueArgs, invoice.Customer_id)
-		valueArgs = append(valueArgs, invoice.Datetime)
-		valueArgs = append(valueArgs, invoice.Total)
+	if err != nil {
+		return nil, err
 	}
 
-	stmtString := fmt.Sprintf("INSERT INTO invoices (id, customer_id, datetime, total) VALUES %s", strings.Join(valueStrings, ","))
-	stmt, err := r.db.PrepareContext(ctx, stmtString)
-	if err != nil {
-		return nil, ErrorInvoicePrepareStoreStatement
+	defer rows.Close()
+
+	var invoices []domain.Invoice
+
+	for rows.Next() {
+		var invoice domain.Invoice
+		err = rows.Scan(&invoice.Id, &invoice.Customer_id, &invoice.Datetime, &invoice.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		invoices = append(invoices, invoice)
 	}
 
-	defer stmt.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invoices, nil
+}
 
-	result, err := r.db.Exec(stmtString, valueArgs...)
+// List returns the keyset page of invoices of spec, with the cursors of the
+// pages around it.
+func (r *invoiceRepository) List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Invoice, web.Cursors, error) {
+	keyset, err := spec.Keyset(InvoiceQueryColumns, InvoiceSortColumns, InvoiceDefaultSort, codec)
 
 	if err != nil {
-		return nil, ErrorInvoiceExecStoreStatement
+		return nil, web.Cursors{}, err
 	}
 
-	_, err = result.RowsAffected()
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, fmt.Sprintf(ListInvoicesQuery, keyset.Clauses.Where, keyset.Clauses.OrderBy, keyset.Clauses.Limit), keyset.Clauses.Args...)
+
 	if err != nil {
-		return nil, err
+		return nil, web.Cursors{}, err
+	}
+
+	defer rows.Close()
+
+	var fetched []domain.Invoice
+	var keys [][]string
+
+	for rows.Next() {
+		var invoice domain.Invoice
+		err = rows.Scan(&invoice.Id, &invoice.Customer_id, &invoice.Datetime, &invoice.Total)
+		if err != nil {
+			return nil, web.Cursors{}, err
+		}
+
+		fetched = append(fetched, invoice)
+		keys = append(keys, invoiceKey(invoice, keyset.Fields))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	indexes, cursors := keyset.Page(keys)
+	invoices := make([]domain.Invoice, 0, len(indexes))
+	for _, index := range indexes {
+		invoices = append(invoices, fetched[index])
+	}
+
+	return invoices, cursors, nil
+}
+
+// invoiceKey returns the values of the given fields of invoice, its position
+// in a keyset listing.
+func invoiceKey(invoice domain.Invoice, fields []string) []string {
+	key := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			key = append(key, strconv.Itoa(invoice.Id))
+		case "customer_id":
+			key = append(key, strconv.Itoa(invoice.Customer_id))
+		case "datetime":
+			key = append(key, invoice.Datetime)
+		case "total":
+			key = append(key, strconv.FormatFloat(invoice.Total, 'f', -1, 64))
+		}
+	}
+
+	return key
+}
+
+func (r *invoiceRepository) StoreBulk(ctx context.Context, invoices []domain.Invoice) ([]domain.Invoice, error) {
+	rows := make([][]interface{}, 0, len(invoices))
+	for _, invoice := range invoices {
+		rows = append(rows, []interface{}{invoice.Id, invoice.Customer_id, invoice.Datetime, invoice.Total})
+	}
+
+	err := r.db.InsertBulk(ctx, "invoices", StoreInvoicesColumns, rows)
+
+	if errors.Is(err, storage.ErrorStoragePrepareBulk) {
+		return nil, ErrorInvoicePrepareStoreStatement.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, ErrorInvoiceExecStoreStatement.Wrap(err)
 	}
 
 	return invoices, nil
@@ -115,15 +234,19 @@ func (r *invoiceRepository) UpdateTotal(ctx context.Context, invoice domain.Invo
 	stmt, err := r.db.PrepareContext(ctx, UpdateInvoiceStatement)
 
 	if err != nil {
-		return domain.Invoice{}, ErrorInvoicePrepareUpdateStatement
+		return domain.Invoice{}, ErrorInvoicePrepareUpdateStatement.Wrap(err)
 	}
 
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(ctx, invoice.Total, invoice.Id)
+	var result sql.Result
+	err = r.db.Retry(ctx, func() error {
+		result, err = stmt.ExecContext(ctx, invoice.Total, invoice.Id)
+		return err
+	})
 
 	if err != nil {
-		return domain.Invoice{}, ErrorInvoiceExecUpdateStatement
+		return domain.Invoice{}, ErrorInvoiceExecUpdateStatement.Wrap(err)
 	}
 
 	_, err = result.RowsAffected()
@@ -140,12 +263,14 @@ func (r *invoiceRepository) CalculateTotal(ctx context.Context, ids []int) ([]do
 	query := CalculateTotalInvoiceQuery
 	query = strings.ReplaceAll(query, "replace_with_invoices_ids", "("+strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ","), "[]")+")")
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query)
 
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var invoiceTotals []domain.InvoiceTotalDTO
 	for rows.Next() {
 		var invoiceAux domain.InvoiceTotalDTO
@@ -158,5 +283,76 @@ func (r *invoiceRepository) CalculateTotal(ctx context.Context, ids []int) ([]do
 		invoiceTotals = append(invoiceTotals, invoiceAux)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return invoiceTotals, nil
 }
+
+// GetPurchases returns the customer and datetime of every invoice, only for
+// customers in the given situation when situation is not empty.
+func (r *invoiceRepository) GetPurchases(ctx context.Context, situation string) ([]domain.InvoicePurchaseDTO, error) {
+	query := GetPurchasesQuery
+	args := []interface{}{}
+	if situation != "" {
+		query = GetPurchasesBySituationQuery
+		args = append(args, situation)
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var purchases []domain.InvoicePurchaseDTO
+
+	for rows.Next() {
+		var purchase domain.InvoicePurchaseDTO
+		err = rows.Scan(&purchase.CustomerId, &purchase.Datetime)
+		if err != nil {
+			return nil, err
+		}
+
+		purchases = append(purchases, purchase)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return purchases, nil
+}
+
+// GetDailyRevenue returns the invoices total of every day with invoices,
+// ordered by day.
+func (r *invoiceRepository) GetDailyRevenue(ctx context.Context) ([]domain.RevenuePointDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetDailyRevenueQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var dailyRevenue []domain.RevenuePointDTO
+
+	for rows.Next() {
+		var revenuePoint domain.RevenuePointDTO
+		err = rows.Scan(&revenuePoint.Date, &revenuePoint.Revenue)
+		if err != nil {
+			return nil, err
+		}
+
+		dailyRevenue = append(dailyRevenue, revenuePoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dailyRevenue, nil
+}
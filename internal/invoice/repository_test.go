@@ -2,6 +2,8 @@ package invoice
 
 import (
 	"context"
+	"log"
+	"os"
 	"testing"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/customer"
@@ -9,9 +11,26 @@ import (
 	"github.com/matias-ziliotto/HackthonGo/internal/product"
 	"github.com/matias-ziliotto/HackthonGo/internal/sale"
 	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
 	"github.com/stretchr/testify/assert"
 )
 
+// dialect is the one of the DB of the config, the repositories run on
+var dialect = storage.MySQL
+
+func TestMain(m *testing.M) {
+	configDialect, remove, err := sql.RegisterConfigTxDb([]string{"-env", "../../cmd/server/.env"})
+	if err == nil {
+		dialect = configDialect
+	} else {
+		log.Printf("repository tests need a db: %v", err)
+	}
+
+	code := m.Run()
+	remove()
+	os.Exit(code)
+}
+
 var invoicesToStoreAndGet = []domain.Invoice{
 	{
 		Id:          40000,
@@ -92,13 +111,11 @@ var sales = []domain.Sale{
 
 func TestInvoiceGet(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
@@ -112,10 +129,8 @@ func TestInvoiceGet(t *testing.T) {
 
 func TestInvoiceGetNotFound(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.Get(context.Background(), 99999)
@@ -127,13 +142,11 @@ func TestInvoiceGetNotFound(t *testing.T) {
 
 func TestInvoiceStoreBulk(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
@@ -146,10 +159,8 @@ func TestInvoiceStoreBulk(t *testing.T) {
 
 func TestInvoiceStoreBulkErrorPrepare(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), invoicesToStoreErrorPrepare)
@@ -161,13 +172,11 @@ func TestInvoiceStoreBulkErrorPrepare(t *testing.T) {
 
 func TestInvoiceStoreBulkError(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
@@ -180,13 +189,11 @@ func TestInvoiceStoreBulkError(t *testing.T) {
 
 func TestInvoiceUpdateTotal(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
@@ -202,13 +209,11 @@ func TestInvoiceUpdateTotal(t *testing.T) {
 
 func TestInvoiceGetAllTotalEmpty(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
@@ -222,23 +227,21 @@ func TestInvoiceGetAllTotalEmpty(t *testing.T) {
 
 func TestInvoiceCalculateTotal(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewInvoiceRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
-	repositoryProduct := product.NewProductRepository(db)
+	repositoryProduct := product.NewProductRepositoryWithDialect(db, dialect)
 	_, err = repositoryProduct.StoreBulk(context.Background(), products) // insert dummy product
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
 	_, _ = repository.StoreBulk(context.Background(), invoicesToStoreAndGet)
 
-	repositorySale := sale.NewSaleRepository(db)
+	repositorySale := sale.NewSaleRepositoryWithDialect(db, dialect)
 	_, err = repositorySale.StoreBulk(context.Background(), sales) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
@@ -249,3 +252,41 @@ func TestInvoiceCalculateTotal(t *testing.T) {
 	assert.True(t, len(result) > 0, "result should has more than 0 results")
 	assert.Nil(t, err, "error should be nil")
 }
+
+func TestInvoiceGetPurchases(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
+
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	assert.Nil(t, err, "error should be nil")
+	_, err = repository.StoreBulk(context.Background(), invoicesToStore)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	result, err := repository.GetPurchases(context.Background(), "Activo")
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Contains(t, result, domain.InvoicePurchaseDTO{CustomerId: 1000, Datetime: "2022-01-06 11:11:11"}, "result should have the stored invoice")
+}
+
+func TestInvoiceGetDailyRevenue(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewInvoiceRepositoryWithDialect(db, dialect)
+
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	assert.Nil(t, err, "error should be nil")
+	_, err = repository.StoreBulk(context.Background(), invoicesToStore)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	result, err := repository.GetDailyRevenue(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Contains(t, result, domain.RevenuePointDTO{Date: "2022-01-06", Revenue: 901.5}, "result should have the stored invoices day")
+}
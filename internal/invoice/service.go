@@ -5,7 +5,9 @@ import (
 	"strconv"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
 	"github.com/matias-ziliotto/HackthonGo/pkg/file"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
@@ -14,18 +16,46 @@ var (
 
 type InvoiceService interface {
 	Get(ctx context.Context, id int) (domain.Invoice, error)
+	List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Invoice, web.Cursors, error)
 	StoreBulk(ctx context.Context) ([]domain.Invoice, error)
 	UpdateTotal(ctx context.Context) ([]domain.InvoiceTotalDTO, error)
+	RecalculateTotal(ctx context.Context, id int) (domain.InvoiceTotalDTO, error)
+	GetCohorts(ctx context.Context, situation string) ([]domain.CohortDTO, error)
+	GetForecast(ctx context.Context, config ForecastConfig) (domain.ForecastDTO, error)
 }
 
 func NewInvoiceService(pr InvoiceRepository) InvoiceService {
 	return &invoiceService{
 		repository: pr,
+		txtPath:    InvoiceTxtPath,
+	}
+}
+
+// NewInvoiceServiceWithSummaries returns an InvoiceService whose forecasts
+// read the daily summaries, enough for both the day and week granularities.
+// Cohorts need every purchase, so they keep reading the invoices.
+func NewInvoiceServiceWithSummaries(pr InvoiceRepository, sr summary.SummaryRepository) InvoiceService {
+	return &invoiceService{
+		repository: pr,
+		summaries:  sr,
+		txtPath:    InvoiceTxtPath,
+	}
+}
+
+// NewInvoiceServiceWithDataFile is NewInvoiceServiceWithSummaries loading the
+// invoices from txtPath instead of InvoiceTxtPath.
+func NewInvoiceServiceWithDataFile(pr InvoiceRepository, sr summary.SummaryRepository, txtPath string) InvoiceService {
+	return &invoiceService{
+		repository: pr,
+		summaries:  sr,
+		txtPath:    txtPath,
 	}
 }
 
 type invoiceService struct {
 	repository InvoiceRepository
+	summaries  summary.SummaryRepository
+	txtPath    string
 }
 
 func (s *invoiceService) Get(ctx context.Context, id int) (domain.Invoice, error) {
@@ -38,8 +68,18 @@ func (s *invoiceService) Get(ctx context.Context, id int) (domain.Invoice, error
 	return invoice, nil
 }
 
+func (s *invoiceService) List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Invoice, web.Cursors, error) {
+	invoices, cursors, err := s.repository.List(ctx, spec, codec)
+
+	if err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	return invoices, cursors, nil
+}
+
 func (s *invoiceService) StoreBulk(ctx context.Context) ([]domain.Invoice, error) {
-	data, err := file.ReadFile(InvoiceTxtPath)
+	data, err := file.ReadFile(s.txtPath)
 
 	if err != nil {
 		return nil, err
@@ -110,3 +150,48 @@ func (s *invoiceService) UpdateTotal(ctx context.Context) ([]domain.InvoiceTotal
 
 	return invoicesTotals, nil
 }
+
+// RecalculateTotal updates the total of an invoice from its sales, even if
+// it already had one.
+func (s *invoiceService) RecalculateTotal(ctx context.Context, id int) (domain.InvoiceTotalDTO, error) {
+	invoicesTotals, err := s.repository.CalculateTotal(ctx, []int{id})
+	if err != nil {
+		return domain.InvoiceTotalDTO{}, err
+	}
+
+	if len(invoicesTotals) == 0 {
+		return domain.InvoiceTotalDTO{}, ErrorInvoiceNotFound
+	}
+
+	_, err = s.repository.UpdateTotal(ctx, domain.Invoice{Id: invoicesTotals[0].Id, Total: invoicesTotals[0].Total})
+	if err != nil {
+		return domain.InvoiceTotalDTO{}, err
+	}
+
+	return invoicesTotals[0], nil
+}
+
+func (s *invoiceService) GetCohorts(ctx context.Context, situation string) ([]domain.CohortDTO, error) {
+	purchases, err := s.repository.GetPurchases(ctx, situation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return CalculateCohorts(purchases)
+}
+
+func (s *invoiceService) GetForecast(ctx context.Context, config ForecastConfig) (domain.ForecastDTO, error) {
+	getDailyRevenue := s.repository.GetDailyRevenue
+	if s.summaries != nil {
+		getDailyRevenue = s.summaries.DailyRevenue
+	}
+
+	dailyRevenue, err := getDailyRevenue(ctx)
+
+	if err != nil {
+		return domain.ForecastDTO{}, err
+	}
+
+	return CalculateForecast(dailyRevenue, config)
+}
@@ -3,10 +3,14 @@ package invoice
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -218,3 +222,287 @@ func TestServiceInvoiceUpdateTotalError(t *testing.T) {
 	assert.Error(t, err, "should exists an error")
 	assert.Nil(t, result, "result should be nil")
 }
+
+func TestServiceInvoiceGetCohorts(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	rows := mock.NewRows([]string{"customer_id", "datetime"})
+	rows.AddRow(1, "2021-10-05 10:00:00")
+	rows.AddRow(1, "2021-12-14 12:24:27")
+	rows.AddRow(2, "2021-10-20 7:32:40")
+	rows.AddRow(2, "2021-11-20 10:00:00")
+	rows.AddRow(3, "2021-11-02 10:00:00")
+	mock.ExpectQuery("SELECT invoices.customer_id").WithArgs("Activo").WillReturnRows(rows)
+
+	// Act
+	result, err := invoiceService.GetCohorts(context.Background(), "Activo")
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []domain.CohortDTO{
+		{Cohort: "2021-10", Customers: 2, Retained: []int{2, 1, 1}, Retention: []float64{1, 0.5, 0.5}},
+		{Cohort: "2021-11", Customers: 1, Retained: []int{1, 0}, Retention: []float64{1, 0}},
+	}, result, "result should be equal to expected result")
+	assert.Equal(t, [][]string{
+		{"cohort", "customers", "month_0", "month_1", "month_2"},
+		{"2021-10", "2", "1.0000", "0.5000", "0.5000"},
+		{"2021-11", "1", "1.0000", "0.0000", ""},
+	}, CohortsToCSV(result), "csv should be equal to expected csv")
+}
+
+func TestServiceInvoiceGetCohortsError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	mock.ExpectQuery("SELECT invoices.customer_id").WillReturnError(errors.New("error"))
+
+	// Act
+	result, err := invoiceService.GetCohorts(context.Background(), "")
+
+	// Assert
+	assert.Error(t, err, "should exists an error")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceInvoiceGetForecastMovingAverage(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	rows := mock.NewRows([]string{"day", "total"})
+	rows.AddRow("2021-12-01", 100.0)
+	rows.AddRow("2021-12-02", 200.0)
+	rows.AddRow("2021-12-04", 300.0)
+	mock.ExpectQuery("SELECT DATE_FORMAT").WillReturnRows(rows)
+
+	// Act
+	result, err := invoiceService.GetForecast(context.Background(), ForecastConfig{Window: 2, Horizon: 2})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, len(result.Forecast), "len of forecast should be equal to horizon")
+	assert.Equal(t, "2021-12-05", result.Forecast[0].Date, "forecast should start the day after the last invoice")
+	assert.InDelta(t, 150.0, result.Forecast[0].Value, 0.0001, "forecast should be the average of the last 2 days, including the empty one")
+	assert.True(t, result.Forecast[1].Upper-result.Forecast[1].Value > result.Forecast[0].Upper-result.Forecast[0].Value, "interval should widen with the horizon")
+	assert.Nil(t, result.Backtest, "backtest should be nil")
+}
+
+func TestServiceInvoiceGetForecastSeasonalNaiveBacktest(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	rows := mock.NewRows([]string{"day", "total"})
+	for day := 1; day <= 14; day++ {
+		rows.AddRow(fmt.Sprintf("2021-12-%02d", day), float64(100*(day%7+1)))
+	}
+	mock.ExpectQuery("SELECT DATE_FORMAT").WillReturnRows(rows)
+
+	// Act
+	result, err := invoiceService.GetForecast(context.Background(), ForecastConfig{
+		Model:    ForecastModelSeasonalNaive,
+		Horizon:  7,
+		Backtest: true,
+	})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.InDelta(t, 200.0, result.Forecast[0].Value, 0.0001, "forecast should repeat the last season")
+	assert.Equal(t, 7, len(result.Backtest.Points), "backtest should have horizon points")
+	assert.InDelta(t, 0.0, result.Backtest.MAPE, 0.0001, "a perfectly seasonal series should have no error")
+	assert.InDelta(t, 0.0, result.Backtest.RMSE, 0.0001, "a perfectly seasonal series should have no error")
+}
+
+func TestServiceInvoiceGetForecastHoltWintersWeekly(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	rows := mock.NewRows([]string{"day", "total"})
+	for week := 0; week < 8; week++ {
+		rows.AddRow(time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, week*7+2).Format(DateLayout), float64(1000+week*10))
+	}
+	mock.ExpectQuery("SELECT DATE_FORMAT").WillReturnRows(rows)
+
+	// Act
+	result, err := invoiceService.GetForecast(context.Background(), ForecastConfig{
+		Model:       ForecastModelHoltWinters,
+		Granularity: GranularityWeek,
+		Horizon:     2,
+	})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, "2021-12-27", result.Forecast[0].Date, "weekly forecast should start on the monday after the last week")
+	assert.True(t, result.Forecast[1].Value > result.Forecast[0].Value, "forecast should follow the trend")
+}
+
+func TestServiceInvoiceGetForecastNotEnoughData(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	rows := mock.NewRows([]string{"day", "total"})
+	rows.AddRow("2021-12-01", 100.0)
+	mock.ExpectQuery("SELECT DATE_FORMAT").WillReturnRows(rows)
+
+	// Act
+	_, err = invoiceService.GetForecast(context.Background(), ForecastConfig{Model: ForecastModelHoltWinters})
+
+	// Assert
+	assert.Equal(t, ErrorForecastNotEnoughData, err, "error should be not enough data")
+}
+
+func TestServiceInvoiceGetForecastInvalidModel(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	mock.ExpectQuery("SELECT DATE_FORMAT").WillReturnRows(mock.NewRows([]string{"day", "total"}))
+
+	// Act
+	_, err = invoiceService.GetForecast(context.Background(), ForecastConfig{Model: "arima"})
+
+	// Assert
+	assert.Equal(t, ErrorForecastInvalidModel, err, "error should be invalid model")
+}
+
+func TestServiceInvoiceGetForecastError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	mock.ExpectQuery("SELECT DATE_FORMAT").WillReturnError(errors.New("error"))
+
+	// Act
+	_, err = invoiceService.GetForecast(context.Background(), ForecastConfig{})
+
+	// Assert
+	assert.Error(t, err, "should exists an error")
+}
+
+func TestServiceInvoiceRecalculateTotal(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	rowsCalculateTotal := mock.NewRows([]string{"id", "total"})
+	rowsCalculateTotal.AddRow(72, 150.0)
+	mock.ExpectQuery("SELECT DISTINCT").WillReturnRows(rowsCalculateTotal)
+	mock.ExpectPrepare("UPDATE invoices SET total")
+	mock.ExpectExec("UPDATE invoices SET total").WithArgs(150.0, 72).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	result, err := invoiceService.RecalculateTotal(context.Background(), 72)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, domain.InvoiceTotalDTO{Id: 72, Total: 150.0}, result, "result should be equal to expected result")
+}
+
+func TestServiceInvoiceRecalculateTotalNotFound(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+
+	mock.ExpectQuery("SELECT DISTINCT").WillReturnRows(mock.NewRows([]string{"id", "total"}))
+
+	// Act
+	_, err = invoiceService.RecalculateTotal(context.Background(), 72)
+
+	// Assert
+	assert.Equal(t, ErrorInvoiceNotFound, err, "error should be not found")
+}
+
+func TestServiceInvoiceGetForecastWithSummaries(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceServiceWithSummaries(invoiceRepository, summary.NewSummaryRepository(db))
+
+	rows := mock.NewRows([]string{"day", "total"})
+	rows.AddRow("2021-12-01", 100.0)
+	rows.AddRow("2021-12-02", 200.0)
+	mock.ExpectQuery("FROM daily_situation_revenue").WillReturnRows(rows)
+
+	// Act
+	result, err := invoiceService.GetForecast(context.Background(), ForecastConfig{Window: 2, Horizon: 1})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.InDelta(t, 150.0, result.Forecast[0].Value, 0.0001, "forecast should be the average of the summarized days")
+	assert.Nil(t, mock.ExpectationsWereMet(), "invoices should not be read")
+}
+
+func TestServiceInvoiceListCursorsByDatetime(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+	codec := web.NewCursorCodec([]byte("secret"))
+	spec := web.QuerySpec{Size: 1, Sort: []web.Sort{{Field: "datetime", Desc: true}}}
+
+	rows := mock.NewRows([]string{"id", "customer_id", "datetime", "total"})
+	rows.AddRow(1001, 1000, "2022-01-06 11:11:12", 300.5)
+	rows.AddRow(1000, 1000, "2022-01-06 11:11:11", 200.5)
+	mock.ExpectQuery(`FROM invoices  ORDER BY datetime DESC, id ASC LIMIT 2`).WillReturnRows(rows)
+	rows = mock.NewRows([]string{"id", "customer_id", "datetime", "total"})
+	rows.AddRow(1000, 1000, "2022-01-06 11:11:11", 200.5)
+	mock.ExpectQuery(`FROM invoices WHERE \(\(datetime < \?\) OR \(datetime = \? AND id > \?\)\) ORDER BY datetime DESC, id ASC LIMIT 2`).
+		WithArgs("2022-01-06 11:11:12", "2022-01-06 11:11:12", "1001").WillReturnRows(rows)
+
+	// Act
+	firstPage, firstCursors, err := invoiceService.List(context.Background(), spec, codec)
+	assert.Nil(t, err, "error should be nil")
+	spec.Cursor = firstCursors.Next
+	secondPage, _, err := invoiceService.List(context.Background(), spec, codec)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 1001, firstPage[0].Id, "first page should have the last invoice")
+	assert.Equal(t, 1000, secondPage[0].Id, "second page should have the previous invoice")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestServiceInvoiceListCursorOfOtherSort(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	invoiceRepository := NewInvoiceRepository(db)
+	invoiceService := NewInvoiceService(invoiceRepository)
+	codec := web.NewCursorCodec([]byte("secret"))
+	cursor := codec.Encode(web.Cursor{Sort: "id", Values: []string{"1000"}})
+
+	// Act
+	result, _, err := invoiceService.List(context.Background(), web.QuerySpec{Size: 1, Cursor: cursor, Sort: []web.Sort{{Field: "datetime"}}}, codec)
+
+	// Assert
+	assert.Equal(t, web.ErrorQueryInvalidCursor, err, "error should be invalid cursor")
+	assert.Nil(t, result, "result should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "no query should run")
+}
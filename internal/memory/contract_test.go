@@ -0,0 +1,530 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/customer"
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
+	"github.com/matias-ziliotto/HackthonGo/internal/memory"
+	"github.com/matias-ziliotto/HackthonGo/internal/product"
+	"github.com/matias-ziliotto/HackthonGo/internal/sale"
+	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+// The contract every repository implementation keeps, run against the memory
+// ones and the SQL ones. The SQL backend is skipped when there is no db.
+
+// dialect is the one of the DB of the config, the SQL repositories run on
+var dialect = storage.MySQL
+
+func TestMain(m *testing.M) {
+	configDialect, remove, err := sql.RegisterConfigTxDb([]string{"-env", "../../cmd/server/.env"})
+	if err == nil {
+		dialect = configDialect
+	} else {
+		log.Printf("sql contract tests need a db: %v", err)
+	}
+
+	code := m.Run()
+	remove()
+	os.Exit(code)
+}
+
+type repositories struct {
+	products  product.ProductRepository
+	customers customer.CustomerRepository
+	invoices  invoice.InvoiceRepository
+	sales     sale.SaleRepository
+}
+
+type backend struct {
+	name string
+	open func(t *testing.T) repositories
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		open: func(t *testing.T) repositories {
+			store := memory.NewStore()
+
+			return repositories{
+				products:  product.NewProductMemoryRepository(store),
+				customers: customer.NewCustomerMemoryRepository(store),
+				invoices:  invoice.NewInvoiceMemoryRepository(store),
+				sales:     sale.NewSaleMemoryRepository(store),
+			}
+		},
+	},
+	{
+		name: "sql",
+		open: func(t *testing.T) repositories {
+			db := sql.InitTxSqlDbOrSkip(t)
+
+			return repositories{
+				products:  product.NewProductRepositoryWithDialect(db, dialect),
+				customers: customer.NewCustomerRepositoryWithDialect(db, dialect),
+				invoices:  invoice.NewInvoiceRepositoryWithDialect(db, dialect),
+				sales:     sale.NewSaleRepositoryWithDialect(db, dialect),
+			}
+		},
+	},
+}
+
+var contractProducts = []domain.Product{
+	{Id: 7001, Description: "Arroz", Price: 10.5},
+	{Id: 7002, Description: "Leche", Price: 2.5},
+	{Id: 7003, Description: "Pan", Price: 4},
+}
+
+var contractCustomers = []domain.Customer{
+	{Id: 7101, FirstName: "Ana", LastName: "Diaz", Situation: "Activo"},
+	{Id: 7102, FirstName: "Bruno", LastName: "Perez", Situation: "Inactivo"},
+}
+
+var contractInvoices = []domain.Invoice{
+	{Id: 7201, Customer_id: 7101, Datetime: "2022-01-05 10:00:00", Total: 0},
+	{Id: 7202, Customer_id: 7101, Datetime: "2022-01-06 09:30:00", Total: 15},
+	{Id: 7203, Customer_id: 7102, Datetime: "2022-01-06 18:00:00", Total: 4.5},
+}
+
+var contractSales = []domain.Sale{
+	{Id: 7301, Invoice_id: 7201, Product_id: 7001, Quantity: 2},
+	{Id: 7302, Invoice_id: 7201, Product_id: 7002, Quantity: 4},
+	{Id: 7303, Invoice_id: 7202, Product_id: 7001, Quantity: 1},
+	{Id: 7304, Invoice_id: 7203, Product_id: 7003, Quantity: 1.5},
+}
+
+// runContract runs contract once per backend, on empty repositories.
+func runContract(t *testing.T, contract func(t *testing.T, r repositories)) {
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			contract(t, b.open(t))
+		})
+	}
+}
+
+// seed stores the contract rows.
+func seed(t *testing.T, r repositories) {
+	ctx := context.Background()
+
+	_, err := r.products.StoreBulk(ctx, contractProducts)
+	assert.Nil(t, err, "error should be nil")
+	_, err = r.customers.StoreBulk(ctx, contractCustomers)
+	assert.Nil(t, err, "error should be nil")
+	_, err = r.invoices.StoreBulk(ctx, contractInvoices)
+	assert.Nil(t, err, "error should be nil")
+	_, err = r.sales.StoreBulk(ctx, contractSales)
+	assert.Nil(t, err, "error should be nil")
+}
+
+// seedAlone seeds r, skipping the reports contracts when the db has rows of
+// its own, since they aggregate whole tables.
+func seedAlone(t *testing.T, r repositories) {
+	existing, err := r.products.GetAll(context.Background(), "")
+	assert.Nil(t, err, "error should be nil")
+	if len(existing) > 0 {
+		t.Skip("reports contracts need an empty db")
+	}
+
+	seed(t, r)
+}
+
+func TestContractGetNotFound(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+
+		// Act
+		_, productErr := r.products.Get(ctx, 99999)
+		_, customerErr := r.customers.Get(ctx, 99999)
+		_, invoiceErr := r.invoices.Get(ctx, 99999)
+		_, saleErr := r.sales.Get(ctx, 99999)
+		_, heldErr := r.sales.GetHeld(ctx, 99999)
+		deleteErr := r.sales.DeleteHeld(ctx, 99999)
+
+		// Assert
+		assert.Equal(t, product.ErrorProductNotFound, productErr, "error should be not found")
+		assert.Equal(t, customer.ErrorCustomerNotFound, customerErr, "error should be not found")
+		assert.Equal(t, invoice.ErrorInvoiceNotFound, invoiceErr, "error should be not found")
+		assert.Equal(t, sale.ErrorSaleNotFound, saleErr, "error should be not found")
+		assert.Equal(t, sale.ErrorSaleNotFound, heldErr, "error should be not found")
+		assert.Equal(t, sale.ErrorSaleNotFound, deleteErr, "error should be not found")
+	})
+}
+
+func TestContractStoreBulkAndGet(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+
+		// Act
+		storedProduct, productErr := r.products.Get(ctx, 7001)
+		storedCustomer, customerErr := r.customers.Get(ctx, 7102)
+		storedInvoice, invoiceErr := r.invoices.Get(ctx, 7202)
+		storedSale, saleErr := r.sales.Get(ctx, 7304)
+
+		// Assert
+		assert.Nil(t, productErr, "error should be nil")
+		assert.Nil(t, customerErr, "error should be nil")
+		assert.Nil(t, invoiceErr, "error should be nil")
+		assert.Nil(t, saleErr, "error should be nil")
+		assert.Equal(t, contractProducts[0], storedProduct, "product should be the stored one")
+		assert.Equal(t, contractCustomers[1], storedCustomer, "customer should be the stored one")
+		assert.Equal(t, contractInvoices[1], storedInvoice, "invoice should be the stored one")
+		assert.Equal(t, contractSales[3], storedSale, "sale should be the stored one")
+	})
+}
+
+func TestContractStoreAfterStoreBulk(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+
+		// Act
+		stored, err := r.products.Store(ctx, domain.Product{Description: "Yerba", Price: 7})
+		storedProduct, getErr := r.products.Get(ctx, stored.Id)
+
+		// Assert
+		assert.Nil(t, err, "error should be nil")
+		assert.Greater(t, stored.Id, contractProducts[2].Id, "the id should follow the ones stored in bulk")
+		assert.Nil(t, getErr, "error should be nil")
+		assert.Equal(t, stored, storedProduct, "product should be the stored one")
+	})
+}
+
+func TestContractStoreBulkErrors(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+		duplicated := []domain.Product{{Id: 7004, Description: "Yerba", Price: 3}, {Id: 7001, Description: "Arroz", Price: 10.5}}
+		orphan := []domain.Sale{{Id: 7305, Invoice_id: 99999, Product_id: 7001, Quantity: 1}}
+
+		// Act
+		_, emptyErr := r.customers.StoreBulk(ctx, []domain.Customer{})
+		_, duplicatedErr := r.products.StoreBulk(ctx, duplicated)
+		_, notStoredErr := r.products.Get(ctx, 7004)
+		_, orphanErr := r.sales.StoreBulk(ctx, orphan)
+		_, orphanInvoiceErr := r.invoices.StoreBulk(ctx, []domain.Invoice{{Id: 7204, Customer_id: 99999, Datetime: "2022-01-07 10:00:00"}})
+
+		// Assert
+		assert.ErrorIs(t, emptyErr, customer.ErrorCustomerPrepareStoreStatement, "error should be the prepare one")
+		assert.ErrorIs(t, duplicatedErr, product.ErrorProductExecStoreStatement, "error should be the exec one")
+		assert.Equal(t, product.ErrorProductNotFound, notStoredErr, "a failed bulk should store nothing")
+		assert.ErrorIs(t, orphanErr, sale.ErrorSaleExecStoreStatement, "sales should need their invoice")
+		assert.ErrorIs(t, orphanInvoiceErr, invoice.ErrorInvoiceExecStoreStatement, "invoices should need their customer")
+	})
+}
+
+func TestContractUpdate(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+
+		// Act
+		_, classErr := r.products.UpdateClass(ctx, domain.Product{Id: 7002, Class: "B"})
+		_, totalErr := r.invoices.UpdateTotal(ctx, domain.Invoice{Id: 7201, Total: 31})
+		classified, _ := r.products.GetAll(ctx, "B")
+		updated, _ := r.invoices.Get(ctx, 7201)
+
+		// Assert
+		assert.Nil(t, classErr, "error should be nil")
+		assert.Nil(t, totalErr, "error should be nil")
+		assert.Equal(t, []domain.Product{{Id: 7002, Description: "Leche", Price: 2.5, Class: "B"}}, classified, "only the updated product should have the class")
+		assert.Equal(t, 31.0, updated.Total, "invoice total should be updated")
+	})
+}
+
+func TestContractUpdateClassBulk(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+
+		// Act
+		_, err := r.products.UpdateClassBulk(ctx, []domain.Product{{Id: 7001, Class: "A"}, {Id: 7002, Class: "C"}, {Id: 7003, Class: "A"}})
+		classA, _ := r.products.GetAll(ctx, "A")
+		classC, _ := r.products.GetAll(ctx, "C")
+
+		// Assert
+		assert.Nil(t, err, "error should be nil")
+		assert.Equal(t, []int{7001, 7003}, productIds(classA), "the products of a class should be updated together")
+		assert.Equal(t, []int{7002}, productIds(classC))
+	})
+}
+
+func productIds(products []domain.Product) []int {
+	ids := []int{}
+	for _, product := range products {
+		ids = append(ids, product.Id)
+	}
+
+	return ids
+}
+
+func TestContractProductsReports(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seedAlone(t, r)
+
+		// Act
+		mostSelled, mostSelledErr := r.products.ProductsMostSelled(ctx)
+		revenue, revenueErr := r.products.ProductsRevenue(ctx)
+
+		// Assert
+		assert.Nil(t, mostSelledErr, "error should be nil")
+		assert.Nil(t, revenueErr, "error should be nil")
+		assert.Equal(t, domain.ProductMostSelledDTO{Description: "Arroz", Total: 21}, mostSelled[0], "most selled should be first")
+		assert.ElementsMatch(t, []domain.ProductMostSelledDTO{
+			{Description: "Arroz", Total: 21},
+			{Description: "Leche", Total: 2.5},
+			{Description: "Pan", Total: 4},
+		}, mostSelled, "every product sold should be there")
+		assert.Equal(t, []domain.ProductRevenueDTO{
+			{Id: 7001, Description: "Arroz", Revenue: 31.5},
+			{Id: 7002, Description: "Leche", Revenue: 10},
+			{Id: 7003, Description: "Pan", Revenue: 6},
+		}, revenue, "revenue should be ordered by revenue")
+	})
+}
+
+func TestContractCustomersReports(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seedAlone(t, r)
+
+		// Act
+		totals, totalsErr := r.customers.GetTotalByCondition(ctx)
+		cheaper, cheaperErr := r.customers.GetCustomerCheaperProducts(ctx)
+		rfm, rfmErr := r.customers.GetRFMValues(ctx)
+
+		// Assert
+		assert.Nil(t, totalsErr, "error should be nil")
+		assert.Nil(t, cheaperErr, "error should be nil")
+		assert.Nil(t, rfmErr, "error should be nil")
+		assert.ElementsMatch(t, []domain.CustomerTotalByConditionDTO{
+			{Situation: "Activo", Total: 15},
+			{Situation: "Inactivo", Total: 4.5},
+		}, totals, "totals should be by situation")
+		assert.Equal(t, []domain.CustomerCheaperProductDTO{
+			{FirstName: "Ana", LastName: "Diaz"},
+			{FirstName: "Bruno", LastName: "Perez"},
+			{FirstName: "Ana", LastName: "Diaz"},
+		}, cheaper, "customers should be ordered by product price")
+		assert.Equal(t, []domain.CustomerRFMValuesDTO{
+			{Customer: contractCustomers[0], LastPurchase: "2022-01-06 09:30:00", Frequency: 2, Monetary: 15},
+			{Customer: contractCustomers[1], LastPurchase: "2022-01-06 18:00:00", Frequency: 1, Monetary: 4.5},
+		}, rfm, "rfm values should be by customer")
+	})
+}
+
+func TestContractInvoicesReports(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seedAlone(t, r)
+
+		// Act
+		empty, emptyErr := r.invoices.GetAllTotalEmpty(ctx)
+		totals, totalsErr := r.invoices.CalculateTotal(ctx, []int{7201, 7202})
+		purchases, purchasesErr := r.invoices.GetPurchases(ctx, "")
+		inactive, inactiveErr := r.invoices.GetPurchases(ctx, "Inactivo")
+		daily, dailyErr := r.invoices.GetDailyRevenue(ctx)
+
+		// Assert
+		assert.Nil(t, emptyErr, "error should be nil")
+		assert.Nil(t, totalsErr, "error should be nil")
+		assert.Nil(t, purchasesErr, "error should be nil")
+		assert.Nil(t, inactiveErr, "error should be nil")
+		assert.Nil(t, dailyErr, "error should be nil")
+		assert.Equal(t, []int{7201}, empty, "only the invoice without total should be empty")
+		assert.ElementsMatch(t, []domain.InvoiceTotalDTO{{Id: 7201, Total: 31}, {Id: 7202, Total: 10.5}}, totals, "totals should be of the given invoices")
+		assert.Equal(t, []domain.InvoicePurchaseDTO{
+			{CustomerId: 7101, Datetime: "2022-01-05 10:00:00"},
+			{CustomerId: 7101, Datetime: "2022-01-06 09:30:00"},
+			{CustomerId: 7102, Datetime: "2022-01-06 18:00:00"},
+		}, purchases, "purchases should be ordered by customer and datetime")
+		assert.Equal(t, []domain.InvoicePurchaseDTO{{CustomerId: 7102, Datetime: "2022-01-06 18:00:00"}}, inactive, "purchases should be of the situation")
+		assert.Equal(t, []domain.RevenuePointDTO{
+			{Date: "2022-01-05", Revenue: 0},
+			{Date: "2022-01-06", Revenue: 19.5},
+		}, daily, "revenue should be by day")
+	})
+}
+
+func TestContractSalesBasketItems(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seedAlone(t, r)
+
+		// Act
+		result, err := r.sales.GetBasketItems(ctx, "2022-01-06 00:00:00", "2022-01-06 23:59:59")
+
+		// Assert
+		assert.Nil(t, err, "error should be nil")
+		assert.Equal(t, []domain.SaleBasketItemDTO{
+			{InvoiceId: 7202, ProductId: 7001, Description: "Arroz"},
+			{InvoiceId: 7203, ProductId: 7003, Description: "Pan"},
+		}, result, "items should be of the invoices of the day")
+	})
+}
+
+func TestContractHeldSales(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+		held := domain.HeldSaleDTO{Sale: domain.Sale{Id: 7305, Invoice_id: 7202, Product_id: 7003, Quantity: 90}, Reason: "quantity outlier"}
+		heldAgain := domain.HeldSaleDTO{Sale: domain.Sale{Id: 7305, Invoice_id: 7203, Product_id: 7002, Quantity: 1}, Reason: "duplicated"}
+
+		// Act
+		_, storeErr := r.sales.StoreHeldBulk(ctx, []domain.HeldSaleDTO{held})
+		_, storeAgainErr := r.sales.StoreHeldBulk(ctx, []domain.HeldSaleDTO{heldAgain})
+		result, getErr := r.sales.GetHeld(ctx, 7305)
+		deleteErr := r.sales.DeleteHeld(ctx, 7305)
+		_, deletedErr := r.sales.GetHeld(ctx, 7305)
+
+		// Assert
+		assert.Nil(t, storeErr, "error should be nil")
+		assert.Nil(t, storeAgainErr, "error should be nil")
+		assert.Nil(t, getErr, "error should be nil")
+		assert.Equal(t, domain.HeldSaleDTO{Sale: held.Sale, Reason: "duplicated"}, result, "a sale held again should only keep the latest reason")
+		assert.Nil(t, deleteErr, "error should be nil")
+		assert.Equal(t, sale.ErrorSaleNotFound, deletedErr, "held sale should be deleted")
+	})
+}
+
+func TestContractReleaseHeld(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+		held := domain.HeldSaleDTO{Sale: domain.Sale{Id: 7305, Invoice_id: 7202, Product_id: 7003, Quantity: 90}, Reason: "quantity outlier"}
+		_, err := r.sales.StoreHeldBulk(ctx, []domain.HeldSaleDTO{held})
+		assert.Nil(t, err, "error should be nil")
+
+		// Act
+		releaseErr := r.sales.ReleaseHeld(ctx, held.Sale)
+		stored, getErr := r.sales.Get(ctx, 7305)
+		_, heldErr := r.sales.GetHeld(ctx, 7305)
+		releaseAgainErr := r.sales.ReleaseHeld(ctx, held.Sale)
+
+		// Assert
+		assert.Nil(t, releaseErr, "error should be nil")
+		assert.Nil(t, getErr, "error should be nil")
+		assert.Equal(t, held.Sale, stored, "held sale should be stored")
+		assert.Equal(t, sale.ErrorSaleNotFound, heldErr, "held sale should be deleted")
+		assert.Error(t, releaseAgainErr, "a sale released should not be released again")
+	})
+}
+
+func TestContractList(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+		codec := web.NewCursorCodec([]byte("secret"))
+		productsSpec := web.QuerySpec{Page: 1, Size: 2, Sort: []web.Sort{{Field: "price", Desc: true}}}.WithFilter("id", web.OperatorGte, "7001")
+		customersSpec := web.QuerySpec{Page: 1, Size: 10}.WithFilter("last_name", web.OperatorLike, "per")
+		invoicesSpec := web.QuerySpec{Size: 2, Sort: []web.Sort{{Field: "datetime"}}}.WithFilter("customer_id", web.OperatorIn, "7101", "7102")
+
+		// Act
+		products, productsTotal, productsErr := r.products.List(ctx, productsSpec)
+		customers, customersTotal, customersErr := r.customers.List(ctx, customersSpec)
+		firstInvoices, cursors, firstErr := r.invoices.List(ctx, invoicesSpec, codec)
+		invoicesSpec.Cursor = cursors.Next
+		lastInvoices, lastCursors, lastErr := r.invoices.List(ctx, invoicesSpec, codec)
+
+		// Assert
+		assert.Nil(t, productsErr, "error should be nil")
+		assert.Nil(t, customersErr, "error should be nil")
+		assert.Nil(t, firstErr, "error should be nil")
+		assert.Nil(t, lastErr, "error should be nil")
+		assert.Equal(t, []domain.Product{contractProducts[0], contractProducts[2]}, products, "products should be the most expensive")
+		assert.Equal(t, 3, productsTotal, "total should count every product matching")
+		assert.Equal(t, []domain.Customer{contractCustomers[1]}, customers, "customers should match the last name")
+		assert.Equal(t, 1, customersTotal, "total should count every customer matching")
+		assert.Equal(t, contractInvoices[:2], firstInvoices, "first page should have the oldest invoices")
+		assert.Equal(t, contractInvoices[2:], lastInvoices, "last page should have the rest")
+		assert.Empty(t, lastCursors.Next, "last page should not have next cursor")
+	})
+}
+
+func TestContractListNotSortedByFloats(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+		codec := web.NewCursorCodec([]byte("secret"))
+
+		// Act
+		_, _, salesErr := r.sales.List(ctx, web.QuerySpec{Size: 2, Sort: []web.Sort{{Field: "quantity"}}}, codec)
+		_, _, invoicesErr := r.invoices.List(ctx, web.QuerySpec{Size: 2, Sort: []web.Sort{{Field: "total", Desc: true}}}, codec)
+
+		// Assert
+		assert.ErrorIs(t, salesErr, web.ErrorQueryInvalidSort, "sales should not be sorted by quantity")
+		assert.ErrorIs(t, invoicesErr, web.ErrorQueryInvalidSort, "invoices should not be sorted by total")
+	})
+}
+
+func TestContractListLikeMatchesWildcardsAsIs(t *testing.T) {
+	runContract(t, func(t *testing.T, r repositories) {
+		// Arrange
+		ctx := context.Background()
+		seed(t, r)
+		spec := web.QuerySpec{Page: 1, Size: 10}.WithFilter("last_name", web.OperatorLike, "%")
+
+		// Act
+		customers, total, err := r.customers.List(ctx, spec)
+
+		// Assert
+		assert.Nil(t, err, "error should be nil")
+		assert.Empty(t, customers, "no last name has a %")
+		assert.Equal(t, 0, total, "total should be 0")
+	})
+}
+
+func TestMemoryConcurrentAccess(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := memory.NewStore()
+	repository := customer.NewCustomerMemoryRepository(store)
+	var wg sync.WaitGroup
+
+	// Act
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			repository.StoreBulk(ctx, []domain.Customer{{Id: i + 1, FirstName: fmt.Sprint("Name ", i), Situation: "Activo"}})
+		}(i)
+		go func() {
+			defer wg.Done()
+			repository.GetAll(ctx)
+			repository.GetTotalByCondition(ctx)
+		}()
+	}
+	wg.Wait()
+	result, err := repository.GetAll(ctx)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Len(t, result, 20, "every customer should be stored")
+}
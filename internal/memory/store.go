@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+)
+
+var (
+	// Errors
+	ErrorMemoryNoRows       = errors.New("no rows to insert")
+	ErrorMemoryDuplicateKey = errors.New("duplicate key")
+	ErrorMemoryForeignKey   = errors.New("foreign key constraint fails")
+)
+
+// Store is the in-memory DB the memory repositories share, so their reports
+// join the tables like the SQL ones. It is safe for concurrent use.
+type Store struct {
+	mu     sync.RWMutex
+	tables Tables
+}
+
+// Tables are the rows of the store by id.
+type Tables struct {
+	Products  map[int]domain.Product
+	Customers map[int]domain.Customer
+	Invoices  map[int]domain.Invoice
+	Sales     map[int]domain.Sale
+	HeldSales map[int]domain.HeldSaleDTO
+}
+
+func NewStore() *Store {
+	return &Store{
+		tables: Tables{
+			Products:  map[int]domain.Product{},
+			Customers: map[int]domain.Customer{},
+			Invoices:  map[int]domain.Invoice{},
+			Sales:     map[int]domain.Sale{},
+			HeldSales: map[int]domain.HeldSaleDTO{},
+		},
+	}
+}
+
+// Read runs read with the tables locked for reading, read must not change
+// them nor keep them once it returns.
+func (s *Store) Read(read func(tables *Tables)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	read(&s.tables)
+}
+
+// Write runs write with the tables locked. Like a SQL statement, write must
+// check every row before changing any, so a failed write changes nothing.
+func (s *Store) Write(write func(tables *Tables) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return write(&s.tables)
+}
+
+// SortedProducts returns the products ordered by id.
+func (t *Tables) SortedProducts() []domain.Product {
+	products := make([]domain.Product, 0, len(t.Products))
+	for _, product := range t.Products {
+		products = append(products, product)
+	}
+
+	sort.Slice(products, func(i, j int) bool { return products[i].Id < products[j].Id })
+
+	return products
+}
+
+// SortedCustomers returns the customers ordered by id.
+func (t *Tables) SortedCustomers() []domain.Customer {
+	customers := make([]domain.Customer, 0, len(t.Customers))
+	for _, customer := range t.Customers {
+		customers = append(customers, customer)
+	}
+
+	sort.Slice(customers, func(i, j int) bool { return customers[i].Id < customers[j].Id })
+
+	return customers
+}
+
+// SortedInvoices returns the invoices ordered by id.
+func (t *Tables) SortedInvoices() []domain.Invoice {
+	invoices := make([]domain.Invoice, 0, len(t.Invoices))
+	for _, invoice := range t.Invoices {
+		invoices = append(invoices, invoice)
+	}
+
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].Id < invoices[j].Id })
+
+	return invoices
+}
+
+// SortedSales returns the sales ordered by id.
+func (t *Tables) SortedSales() []domain.Sale {
+	sales := make([]domain.Sale, 0, len(t.Sales))
+	for _, sale := range t.Sales {
+		sales = append(sales, sale)
+	}
+
+	sort.Slice(sales, func(i, j int) bool { return sales[i].Id < sales[j].Id })
+
+	return sales
+}
+
+// SortedHeldSales returns the held sales ordered by id.
+func (t *Tables) SortedHeldSales() []domain.HeldSaleDTO {
+	heldSales := make([]domain.HeldSaleDTO, 0, len(t.HeldSales))
+	for _, heldSale := range t.HeldSales {
+		heldSales = append(heldSales, heldSale)
+	}
+
+	sort.Slice(heldSales, func(i, j int) bool { return heldSales[i].Sale.Id < heldSales[j].Sale.Id })
+
+	return heldSales
+}
+
+// CheckIds fails with ErrorMemoryDuplicateKey when an id of ids is repeated
+// or exists reports true for it.
+func CheckIds(ids []int, exists func(id int) bool) error {
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if seen[id] || exists(id) {
+			return ErrorMemoryDuplicateKey
+		}
+
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// Round rounds value to places decimals like SQL ROUND, half away from
+// zero.
+func Round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,101 @@
+package product
+
+import (
+	"sort"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+const (
+	// Classes
+	ClassA = "A"
+	ClassB = "B"
+	ClassC = "C"
+
+	DefaultABCCutOffA = 0.8
+	DefaultABCCutOffB = 0.95
+)
+
+var (
+	// Errors
+	ErrorABCInvalidCutOffs = web.NewError(web.KindValidation, "abc_invalid_cut_offs", "cut-offs must satisfy 0 < a < b <= 1")
+	ErrorABCInvalidClass   = web.NewError(web.KindValidation, "abc_invalid_class", "class must be A, B or C")
+)
+
+type ABCConfig struct {
+	CutOffA float64 // cumulative revenue share covered by class A, DefaultABCCutOffA when 0
+	CutOffB float64 // cumulative revenue share covered by classes A and B, DefaultABCCutOffB when 0
+}
+
+func (c ABCConfig) withDefaults() (ABCConfig, error) {
+	if c.CutOffA == 0 {
+		c.CutOffA = DefaultABCCutOffA
+	}
+
+	if c.CutOffB == 0 {
+		c.CutOffB = DefaultABCCutOffB
+	}
+
+	if c.CutOffA <= 0 || c.CutOffA >= c.CutOffB || c.CutOffB > 1 {
+		return ABCConfig{}, ErrorABCInvalidCutOffs
+	}
+
+	return c, nil
+}
+
+// ValidClass checks class is one of the ABC classes.
+func ValidClass(class string) bool {
+	return class == ClassA || class == ClassB || class == ClassC
+}
+
+// CalculateABC ranks products by revenue and classifies them by cumulative
+// revenue share. A product belongs to the class whose cut-off was not reached
+// before it, so the product that crosses a cut-off stays in the upper class.
+// Products without revenue are always C.
+func CalculateABC(productsRevenue []domain.ProductRevenueDTO, config ABCConfig) ([]domain.ProductABCDTO, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]domain.ProductRevenueDTO, len(productsRevenue))
+	copy(sorted, productsRevenue)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Revenue > sorted[j].Revenue
+	})
+
+	var totalRevenue float64
+	for _, productRevenue := range sorted {
+		totalRevenue += productRevenue.Revenue
+	}
+
+	productsABC := make([]domain.ProductABCDTO, 0, len(sorted))
+	var cumulativeShare float64
+
+	for _, productRevenue := range sorted {
+		productABC := domain.ProductABCDTO{
+			Id:          productRevenue.Id,
+			Description: productRevenue.Description,
+			Revenue:     productRevenue.Revenue,
+			Class:       ClassC,
+		}
+
+		if totalRevenue > 0 && productRevenue.Revenue > 0 {
+			switch {
+			case cumulativeShare < config.CutOffA:
+				productABC.Class = ClassA
+			case cumulativeShare < config.CutOffB:
+				productABC.Class = ClassB
+			}
+
+			productABC.Share = productRevenue.Revenue / totalRevenue
+			cumulativeShare += productABC.Share
+		}
+
+		productABC.CumulativeShare = cumulativeShare
+		productsABC = append(productsABC, productABC)
+	}
+
+	return productsABC, nil
+}
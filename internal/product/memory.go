@@ -0,0 +1,230 @@
+package product
+
+import (
+	"context"
+	"sort"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/memory"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// NewProductMemoryRepository returns a ProductRepository keeping the
+// products in store, answering like the SQL one.
+func NewProductMemoryRepository(store *memory.Store) ProductRepository {
+	return &productMemoryRepository{
+		store: store,
+	}
+}
+
+type productMemoryRepository struct {
+	store *memory.Store
+}
+
+func (r *productMemoryRepository) Get(ctx context.Context, id int) (domain.Product, error) {
+	var product domain.Product
+	var ok bool
+	r.store.Read(func(tables *memory.Tables) {
+		product, ok = tables.Products[id]
+	})
+
+	if !ok {
+		return domain.Product{}, ErrorProductNotFound
+	}
+
+	return product, nil
+}
+
+func (r *productMemoryRepository) GetAll(ctx context.Context, class string) ([]domain.Product, error) {
+	var products []domain.Product
+	r.store.Read(func(tables *memory.Tables) {
+		for _, product := range tables.SortedProducts() {
+			if class == "" || product.Class == class {
+				products = append(products, product)
+			}
+		}
+	})
+
+	return products, nil
+}
+
+func (r *productMemoryRepository) List(ctx context.Context, spec web.QuerySpec) ([]domain.Product, int, error) {
+	var all []domain.Product
+	r.store.Read(func(tables *memory.Tables) {
+		all = tables.SortedProducts()
+	})
+
+	rows := make([]web.Row, 0, len(all))
+	for _, product := range all {
+		rows = append(rows, productRow(product))
+	}
+
+	indexes, total, err := spec.Select(ProductQueryColumns, ProductDefaultSort, rows)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	products := make([]domain.Product, 0, len(indexes))
+	for _, index := range indexes {
+		products = append(products, all[index])
+	}
+
+	return products, total, nil
+}
+
+// Store stores product without class and with the id after the biggest
+// one, like the SQL insert.
+func (r *productMemoryRepository) Store(ctx context.Context, product domain.Product) (domain.Product, error) {
+	r.store.Write(func(tables *memory.Tables) error {
+		product.Id = 1
+		for id := range tables.Products {
+			if id >= product.Id {
+				product.Id = id + 1
+			}
+		}
+
+		product.Class = ""
+		tables.Products[product.Id] = product
+
+		return nil
+	})
+
+	return product, nil
+}
+
+// StoreBulk stores the products without class, like the SQL insert.
+func (r *productMemoryRepository) StoreBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error) {
+	if len(products) == 0 {
+		return nil, ErrorProductPrepareStoreStatement.Wrap(memory.ErrorMemoryNoRows)
+	}
+
+	err := r.store.Write(func(tables *memory.Tables) error {
+		ids := make([]int, 0, len(products))
+		for _, product := range products {
+			ids = append(ids, product.Id)
+		}
+
+		if err := memory.CheckIds(ids, func(id int) bool { _, ok := tables.Products[id]; return ok }); err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			product.Class = ""
+			tables.Products[product.Id] = product
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, ErrorProductExecStoreStatement.Wrap(err)
+	}
+
+	return products, nil
+}
+
+func (r *productMemoryRepository) UpdateClass(ctx context.Context, product domain.Product) (domain.Product, error) {
+	r.store.Write(func(tables *memory.Tables) error {
+		if stored, ok := tables.Products[product.Id]; ok {
+			stored.Class = product.Class
+			tables.Products[product.Id] = stored
+		}
+
+		return nil
+	})
+
+	return product, nil
+}
+
+func (r *productMemoryRepository) UpdateClassBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error) {
+	r.store.Write(func(tables *memory.Tables) error {
+		for _, product := range products {
+			if stored, ok := tables.Products[product.Id]; ok {
+				stored.Class = product.Class
+				tables.Products[product.Id] = stored
+			}
+		}
+
+		return nil
+	})
+
+	return products, nil
+}
+
+// ProductsMostSelled returns the 5 products with more sales, the ones with
+// the lowest id first on ties.
+func (r *productMemoryRepository) ProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error) {
+	type productSales struct {
+		product domain.Product
+		count   int
+	}
+
+	var ranking []productSales
+	r.store.Read(func(tables *memory.Tables) {
+		counts := map[int]int{}
+		for _, sale := range tables.Sales {
+			counts[sale.Product_id]++
+		}
+
+		for _, product := range tables.SortedProducts() {
+			if counts[product.Id] > 0 {
+				ranking = append(ranking, productSales{product: product, count: counts[product.Id]})
+			}
+		}
+	})
+
+	sort.SliceStable(ranking, func(i, j int) bool { return ranking[i].count > ranking[j].count })
+	if len(ranking) > 5 {
+		ranking = ranking[:5]
+	}
+
+	var productsMostSelled []domain.ProductMostSelledDTO
+	for _, productSales := range ranking {
+		productsMostSelled = append(productsMostSelled, domain.ProductMostSelledDTO{
+			Description: productSales.product.Description,
+			Total:       memory.Round(productSales.product.Price*float64(productSales.count), 1),
+		})
+	}
+
+	return productsMostSelled, nil
+}
+
+func (r *productMemoryRepository) ProductsRevenue(ctx context.Context) ([]domain.ProductRevenueDTO, error) {
+	var productsRevenue []domain.ProductRevenueDTO
+	r.store.Read(func(tables *memory.Tables) {
+		revenues := map[int]float64{}
+		for _, sale := range tables.Sales {
+			revenues[sale.Product_id] += tables.Products[sale.Product_id].Price * sale.Quantity
+		}
+
+		for _, product := range tables.SortedProducts() {
+			productsRevenue = append(productsRevenue, domain.ProductRevenueDTO{
+				Id:          product.Id,
+				Description: product.Description,
+				Revenue:     memory.Round(revenues[product.Id], 2),
+			})
+		}
+	})
+
+	sort.SliceStable(productsRevenue, func(i, j int) bool { return productsRevenue[i].Revenue > productsRevenue[j].Revenue })
+
+	return productsRevenue, nil
+}
+
+func productRow(product domain.Product) web.Row {
+	return func(field string) interface{} {
+		switch field {
+		case "id":
+			return product.Id
+		case "description":
+			return product.Description
+		case "price":
+			return product.Price
+		case "class":
+			return product.Class
+		}
+
+		return nil
+	}
+}
@@ -8,39 +8,81 @@ import (
 	"strings"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
 	// Db queries & statements
-	GetProductQuery            = "SELECT id, description, price FROM products WHERE id = ?"
-	GetProductsMostSelledQuery = "SELECT COUNT(products.id) as count_total, products.description, ROUND(SUM(products.price), 1) as total FROM products INNER JOIN sales ON sales.product_id = products.id GROUP BY products.id ORDER BY count_total DESC LIMIT 5;"
-	StoreProductStatement      = "INSERT INTO products(description, price) VALUES(?, ?)"
+	GetProductQuery              = "SELECT id, description, price, class FROM products WHERE id = ?"
+	GetAllProductsQuery          = "SELECT id, description, price, class FROM products ORDER BY id"
+	GetAllProductsByClassQuery   = "SELECT id, description, price, class FROM products WHERE class = ? ORDER BY id"
+	GetProductsMostSelledQuery   = "SELECT COUNT(products.id) as count_total, products.description, ROUND(SUM(products.price), 1) as total FROM products INNER JOIN sales ON sales.product_id = products.id GROUP BY products.id ORDER BY count_total DESC LIMIT 5;"
+	GetProductsRevenueQuery      = "SELECT products.id, products.description, ROUND(COALESCE(SUM(products.price * sales.quantity), 0), 2) as revenue FROM products LEFT JOIN sales ON sales.product_id = products.id GROUP BY products.id, products.description ORDER BY revenue DESC, products.id ASC;"
+	StoreProductStatement        = "INSERT INTO products(description, price) VALUES(?, ?)"
+	UpdateProductClassStatement  = "UPDATE products SET class = ? WHERE id = ?"
+	UpdateProductsClassStatement = "UPDATE products SET class = ? WHERE id IN (%s)"
+	ListProductsQuery            = "SELECT id, description, price, class FROM products %s %s %s"
+	CountProductsQuery           = "SELECT COUNT(*) FROM products %s"
+
+	// Bulk insert columns
+	StoreProductsColumns = []string{"id", "description", "price"}
+
+	// Listing allow-list
+	ProductQueryColumns = web.QueryColumns{
+		"id":          "id",
+		"description": "description",
+		"price":       "price",
+		"class":       "class",
+	}
+	ProductDefaultSort = []web.Sort{{Field: "id"}}
 
 	// Errors
-	ErrorProductNotFound              = errors.New("product not found")
-	ErrorProductPrepareStoreStatement = errors.New("can not prepare store statement")
-	ErrorProductExecStoreStatement    = errors.New("error executing store statement")
+	ErrorProductNotFound               = web.NewError(web.KindNotFound, "product_not_found", "product not found")
+	ErrorProductPrepareStoreStatement  = web.NewError(web.KindInternal, "product_store_prepare_failed", "can not prepare store statement")
+	ErrorProductExecStoreStatement     = web.NewError(web.KindInternal, "product_store_failed", "error executing store statement")
+	ErrorProductPrepareUpdateStatement = web.NewError(web.KindInternal, "product_update_prepare_failed", "can not prepare update statement")
+	ErrorProductExecUpdateStatement    = web.NewError(web.KindInternal, "product_update_failed", "error executing update statement")
+	ErrorProductBeginUpdate            = web.NewError(web.KindInternal, "product_update_begin_failed", "can not begin products update")
+	ErrorProductCommitUpdate           = web.NewError(web.KindInternal, "product_update_commit_failed", "can not commit products update")
+)
+
+const (
+	// updateClassBatchSize is the most ids an update of the classes sets at once
+	updateClassBatchSize = 500
 )
 
 type ProductRepository interface {
 	Get(ctx context.Context, id int) (domain.Product, error)
+	GetAll(ctx context.Context, class string) ([]domain.Product, error)
+	List(ctx context.Context, spec web.QuerySpec) ([]domain.Product, int, error)
+	Store(ctx context.Context, product domain.Product) (domain.Product, error)
 	StoreBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error)
+	UpdateClass(ctx context.Context, product domain.Product) (domain.Product, error)
+	UpdateClassBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error)
 	ProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error)
+	ProductsRevenue(ctx context.Context) ([]domain.ProductRevenueDTO, error)
 }
 
 func NewProductRepository(db *sql.DB) ProductRepository {
+	return NewProductRepositoryWithDialect(db, storage.MySQL)
+}
+
+// NewProductRepositoryWithDialect returns a ProductRepository running its queries
+// in dialect.
+func NewProductRepositoryWithDialect(db *sql.DB, dialect storage.Dialect) ProductRepository {
 	return &productRepository{
-		db: db,
+		db: storage.New(db, dialect),
 	}
 }
 
 type productRepository struct {
-	db *sql.DB
+	db *storage.DB
 }
 
 func (r *productRepository) Get(ctx context.Context, id int) (domain.Product, error) {
 	var product domain.Product
-	err := r.db.QueryRowContext(ctx, GetProductQuery, id).Scan(&product.Id, &product.Description, &product.Price)
+	err := r.db.Reader(ctx).QueryRowContext(ctx, GetProductQuery, id).Scan(&product.Id, &product.Description, &product.Price, &product.Class)
 
 	if err != nil {
 		return domain.Product{}, ErrorProductNotFound
@@ -49,32 +91,164 @@ func (r *productRepository) Get(ctx context.Context, id int) (domain.Product, er
 	return product, nil
 }
 
-func (r *productRepository) StoreBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error) {
-	valueStrings := make([]string, 0, len(products))
-	valueArgs := make([]interface{}, 0, len(products)*3)
+// GetAll returns every product, only the ones with the given ABC class when
+// class is not empty.
+func (r *productRepository) GetAll(ctx context.Context, class string) ([]domain.Product, error) {
+	query := GetAllProductsQuery
+	args := []interface{}{}
+	if class != "" {
+		query = GetAllProductsByClassQuery
+		args = append(args, class)
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var products []domain.Product
+
+	for rows.Next() {
+		var product domain.Product
+		err = rows.Scan(&product.Id, &product.Description, &product.Price, &product.Class)
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, product)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// List returns the page of products of spec and how many products match its
+// filters.
+func (r *productRepository) List(ctx context.Context, spec web.QuerySpec) ([]domain.Product, int, error) {
+	clauses, err := spec.SQL(ProductQueryColumns, ProductDefaultSort)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = r.db.Reader(ctx).QueryRowContext(ctx, fmt.Sprintf(CountProductsQuery, clauses.Where), clauses.Args...).Scan(&total)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, fmt.Sprintf(ListProductsQuery, clauses.Where, clauses.OrderBy, clauses.Limit), clauses.Args...)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	products := []domain.Product{}
+
+	for rows.Next() {
+		var product domain.Product
+		err = rows.Scan(&product.Id, &product.Description, &product.Price, &product.Class)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		products = append(products, product)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// Store stores product with the next id, the one it returns.
+func (r *productRepository) Store(ctx context.Context, product domain.Product) (domain.Product, error) {
+	id, err := r.db.InsertId(ctx, StoreProductStatement, product.Description, product.Price)
+
+	if err != nil {
+		return domain.Product{}, ErrorProductExecStoreStatement.Wrap(err)
+	}
+
+	product.Id = int(id)
+	product.Class = ""
+
+	return product, nil
+}
 
+func (r *productRepository) StoreBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error) {
+	rows := make([][]interface{}, 0, len(products))
 	for _, product := range products {
-		valueStrings = append(valueStrings, "(?, ?, ?)")
-		valueArgs = append(valueArgs, product.Id)
-		valueArgs = append(valueArgs, product.Description)
-		valueArgs = append(valueArgs, product.Price)
+		rows = append(rows, []interface{}{product.Id, product.Description, product.Price})
+	}
+
+	err := r.db.InsertBulk(ctx, "products", StoreProductsColumns, rows)
+
+	if errors.Is(err, storage.ErrorStoragePrepareBulk) {
+		return nil, ErrorProductPrepareStoreStatement.Wrap(err)
 	}
 
-	stmtString := fmt.Sprintf("INSERT INTO products (id, description, price) VALUES %s", strings.Join(valueStrings, ","))
-	stmt, err := r.db.PrepareContext(ctx, stmtString)
 	if err != nil {
-		return nil, ErrorProductPrepareStoreStatement
+		return nil, ErrorProductExecStoreStatement.Wrap(err)
+	}
+
+	return products, nil
+}
+
+func (r *productRepository) UpdateClass(ctx context.Context, product domain.Product) (domain.Product, error) {
+	stmt, err := r.db.PrepareContext(ctx, UpdateProductClassStatement)
+
+	if err != nil {
+		return domain.Product{}, ErrorProductPrepareUpdateStatement.Wrap(err)
 	}
 
 	defer stmt.Close()
 
-	result, err := r.db.Exec(stmtString, valueArgs...)
+	var result sql.Result
+	err = r.db.Retry(ctx, func() error {
+		result, err = stmt.ExecContext(ctx, product.Class, product.Id)
+		return err
+	})
 
 	if err != nil {
-		return nil, ErrorProductExecStoreStatement
+		return domain.Product{}, ErrorProductExecUpdateStatement.Wrap(err)
 	}
 
 	_, err = result.RowsAffected()
+
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	return product, nil
+}
+
+// UpdateClassBulk stores the class of every product in a single transaction,
+// updating the products of a class together. A transaction rolled back by a
+// deadlock is run again whole.
+func (r *productRepository) UpdateClassBulk(ctx context.Context, products []domain.Product) ([]domain.Product, error) {
+	var classes []string
+	idsByClass := map[string][]interface{}{}
+	for _, product := range products {
+		if _, ok := idsByClass[product.Class]; !ok {
+			classes = append(classes, product.Class)
+		}
+		idsByClass[product.Class] = append(idsByClass[product.Class], product.Id)
+	}
+
+	err := r.db.Retry(ctx, func() error {
+		return r.updateClassBulkTx(ctx, classes, idsByClass)
+	})
+
 	if err != nil {
 		return nil, err
 	}
@@ -82,13 +256,50 @@ func (r *productRepository) StoreBulk(ctx context.Context, products []domain.Pro
 	return products, nil
 }
 
+func (r *productRepository) updateClassBulkTx(ctx context.Context, classes []string, idsByClass map[string][]interface{}) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrorProductBeginUpdate.Wrap(err)
+	}
+
+	defer tx.Rollback()
+
+	for _, class := range classes {
+		ids := idsByClass[class]
+		for start := 0; start < len(ids); start += updateClassBatchSize {
+			end := start + updateClassBatchSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", end-start), ", ")
+			args := append([]interface{}{class}, ids[start:end]...)
+
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(UpdateProductsClassStatement, placeholders), args...)
+
+			if err != nil {
+				return ErrorProductExecUpdateStatement.Wrap(err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrorProductCommitUpdate.Wrap(err)
+	}
+
+	return nil
+}
+
 func (r *productRepository) ProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error) {
-	rows, err := r.db.QueryContext(ctx, GetProductsMostSelledQuery)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetProductsMostSelledQuery)
 
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var productsMostSelled []domain.ProductMostSelledDTO
 
 	for rows.Next() {
@@ -102,5 +313,37 @@ func (r *productRepository) ProductsMostSelled(ctx context.Context) ([]domain.Pr
 		productsMostSelled = append(productsMostSelled, productMostSelled)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return productsMostSelled, nil
 }
+
+func (r *productRepository) ProductsRevenue(ctx context.Context) ([]domain.ProductRevenueDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetProductsRevenueQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var productsRevenue []domain.ProductRevenueDTO
+
+	for rows.Next() {
+		var productRevenue domain.ProductRevenueDTO
+		err = rows.Scan(&productRevenue.Id, &productRevenue.Description, &productRevenue.Revenue)
+		if err != nil {
+			return nil, err
+		}
+
+		productsRevenue = append(productsRevenue, productRevenue)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return productsRevenue, nil
+}
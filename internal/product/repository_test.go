@@ -2,6 +2,9 @@ package product
 
 import (
 	"context"
+	"log"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/customer"
@@ -9,9 +12,27 @@ import (
 	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
 	"github.com/matias-ziliotto/HackthonGo/internal/sale"
 	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
+// dialect is the one of the DB of the config, the repositories run on
+var dialect = storage.MySQL
+
+func TestMain(m *testing.M) {
+	configDialect, remove, err := sql.RegisterConfigTxDb([]string{"-env", "../../cmd/server/.env"})
+	if err == nil {
+		dialect = configDialect
+	} else {
+		log.Printf("repository tests need a db: %v", err)
+	}
+
+	code := m.Run()
+	remove()
+	os.Exit(code)
+}
+
 var productsToStoreAndGet = []domain.Product{
 	{
 		Id:          40000,
@@ -82,10 +103,8 @@ var sales = []domain.Sale{
 
 func TestProductGet(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewProductRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
 
 	// Act
 	productStored, _ := repository.StoreBulk(context.Background(), productsToStoreAndGet)
@@ -98,10 +117,8 @@ func TestProductGet(t *testing.T) {
 
 func TestProductGetNotFound(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewProductRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.Get(context.Background(), 99999)
@@ -113,10 +130,8 @@ func TestProductGetNotFound(t *testing.T) {
 
 func TestProductStoreBulk(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewProductRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), productsToStore)
@@ -128,10 +143,8 @@ func TestProductStoreBulk(t *testing.T) {
 
 func TestProductStoreBulkErrorPrepare(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewProductRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), productsToStoreErrorPrepare)
@@ -143,10 +156,8 @@ func TestProductStoreBulkErrorPrepare(t *testing.T) {
 
 func TestProductStoreBulkError(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewProductRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), productsToStoreErrorStore)
@@ -158,23 +169,21 @@ func TestProductStoreBulkError(t *testing.T) {
 
 func TestProductProductsMostSelled(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewProductRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 
-	repositoryInvoice := invoice.NewInvoiceRepository(db)
+	repositoryInvoice := invoice.NewInvoiceRepositoryWithDialect(db, dialect)
 	_, err = repositoryInvoice.StoreBulk(context.Background(), invoices) // insert dummy invoice
 	assert.Nil(t, err, "error should be nil")
 
 	// Act
 	_, _ = repository.StoreBulk(context.Background(), productsToStoreAndGet)
 
-	repositorySale := sale.NewSaleRepository(db)
+	repositorySale := sale.NewSaleRepositoryWithDialect(db, dialect)
 	_, err = repositorySale.StoreBulk(context.Background(), sales) // insert dummy sale
 	assert.Nil(t, err, "error should be nil")
 
@@ -184,3 +193,127 @@ func TestProductProductsMostSelled(t *testing.T) {
 	assert.True(t, len(result) > 0, "result should has more than 0 results")
 	assert.Nil(t, err, "error should be nil")
 }
+
+func TestProductGetAllByClass(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
+
+	_, err := repository.StoreBulk(context.Background(), productsToStore)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	_, err = repository.UpdateClass(context.Background(), domain.Product{Id: 1000, Class: ClassA})
+	assert.Nil(t, err, "error should be nil")
+	result, err := repository.GetAll(context.Background(), ClassA)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Contains(t, result, domain.Product{Id: 1000, Description: "Descripcion 1000", Price: 1000.0, Class: ClassA}, "result should have the classified product")
+}
+
+func TestProductProductsRevenue(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
+
+	_, err := repository.StoreBulk(context.Background(), productsToStore)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	result, err := repository.ProductsRevenue(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, len(result) >= 3, "result should has at least the stored products")
+}
+
+func TestProductList(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
+
+	_, err := repository.StoreBulk(context.Background(), productsToStore)
+	assert.Nil(t, err, "error should be nil")
+	spec := web.QuerySpec{Page: 1, Size: 2, Sort: []web.Sort{{Field: "price", Desc: true}}}
+	spec = spec.WithFilter("description", web.OperatorLike, "Descripcion 100")
+
+	// Act
+	result, total, err := repository.List(context.Background(), spec)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 3, total, "total should count every filtered product")
+	assert.Equal(t, 2, len(result), "len of result should be the page size")
+	assert.Equal(t, 1002, result[0].Id, "first product should be the most expensive")
+}
+
+func TestProductListInvalidSort(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewProductRepositoryWithDialect(db, dialect)
+
+	// Act
+	result, _, err := repository.List(context.Background(), web.QuerySpec{Page: 1, Size: 10, Sort: []web.Sort{{Field: "password"}}})
+
+	// Assert
+	assert.ErrorIs(t, err, web.ErrorQueryInvalidSort, "error should be invalid sort")
+	assert.Nil(t, result, "result should be nil")
+}
+
+// seedSQLite migrates the SQLite DB at path and stores products in it.
+func seedSQLite(t *testing.T, path string, products []domain.Product) {
+	db, err := storage.Open(storage.DriverSQLite, path)
+	assert.Nil(t, err, "error should be nil")
+	defer db.Close()
+
+	assert.Nil(t, db.Migrate(context.Background()), "error should be nil")
+	if len(products) > 0 {
+		_, err = NewProductRepositoryWithDialect(db.DB, db.Dialect).StoreBulk(context.Background(), products)
+		assert.Nil(t, err, "error should be nil")
+	}
+}
+
+func TestProductGetFromReplica(t *testing.T) {
+	// Arrange, the replica is not replicating, it has the product and the
+	// primary does not
+	primaryPath := filepath.Join(t.TempDir(), "primary.db")
+	replicaPath := filepath.Join(t.TempDir(), "replica.db")
+	seedSQLite(t, primaryPath, nil)
+	seedSQLite(t, replicaPath, productsToStoreAndGet)
+
+	db, err := storage.OpenWithOptions(context.Background(), storage.DriverSQLite, primaryPath, storage.Options{Replicas: []string{replicaPath}})
+	assert.Nil(t, err, "error should be nil")
+	defer db.Close()
+	repository := NewProductRepositoryWithDialect(db.DB, db.Dialect)
+
+	// Act
+	replicaResult, replicaErr := repository.Get(context.Background(), productsToStoreAndGet[0].Id)
+	_, primaryErr := repository.Get(storage.WithPrimary(context.Background()), productsToStoreAndGet[0].Id)
+
+	// Assert
+	assert.Nil(t, replicaErr, "error should be nil")
+	assert.Equal(t, productsToStoreAndGet[0], replicaResult, "reads should go to the replica")
+	assert.ErrorIs(t, primaryErr, ErrorProductNotFound, "reads with primary should go to the primary")
+	assert.True(t, db.PoolStats().Replicas[0].Healthy, "replica should be healthy")
+}
+
+func TestProductGetReplicaUnhealthy(t *testing.T) {
+	// Arrange
+	primaryPath := filepath.Join(t.TempDir(), "primary.db")
+	seedSQLite(t, primaryPath, productsToStoreAndGet)
+
+	missingReplica := filepath.Join(t.TempDir(), "missing", "replica.db")
+	db, err := storage.OpenWithOptions(context.Background(), storage.DriverSQLite, primaryPath, storage.Options{Replicas: []string{missingReplica}})
+	assert.Nil(t, err, "error should be nil")
+	defer db.Close()
+	repository := NewProductRepositoryWithDialect(db.DB, db.Dialect)
+
+	// Act
+	result, err := repository.Get(context.Background(), productsToStoreAndGet[0].Id)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, productsToStoreAndGet[0], result, "reads should fall back to the primary")
+	assert.False(t, db.PoolStats().Replicas[0].Healthy, "replica should be unhealthy")
+}
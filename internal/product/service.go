@@ -5,7 +5,9 @@ import (
 	"strconv"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
 	"github.com/matias-ziliotto/HackthonGo/pkg/file"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
@@ -14,18 +16,45 @@ var (
 
 type ProductService interface {
 	Get(ctx context.Context, id int) (domain.Product, error)
+	GetAll(ctx context.Context, class string) ([]domain.Product, error)
+	List(ctx context.Context, spec web.QuerySpec) ([]domain.Product, int, error)
 	StoreBulk(ctx context.Context) ([]domain.Product, error)
 	GetProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error)
+	GetABCClassification(ctx context.Context, config ABCConfig) ([]domain.ProductABCDTO, error)
+	UpdateABCClassification(ctx context.Context, config ABCConfig) ([]domain.ProductABCDTO, error)
 }
 
 func NewProductService(pr ProductRepository) ProductService {
 	return &productService{
 		repository: pr,
+		txtPath:    ProductTxtPath,
+	}
+}
+
+// NewProductServiceWithSummaries returns a ProductService whose revenue
+// reports read the daily summaries instead of every sale.
+func NewProductServiceWithSummaries(pr ProductRepository, sr summary.SummaryRepository) ProductService {
+	return &productService{
+		repository: pr,
+		summaries:  sr,
+		txtPath:    ProductTxtPath,
+	}
+}
+
+// NewProductServiceWithDataFile is NewProductServiceWithSummaries loading the
+// products from txtPath instead of ProductTxtPath.
+func NewProductServiceWithDataFile(pr ProductRepository, sr summary.SummaryRepository, txtPath string) ProductService {
+	return &productService{
+		repository: pr,
+		summaries:  sr,
+		txtPath:    txtPath,
 	}
 }
 
 type productService struct {
 	repository ProductRepository
+	summaries  summary.SummaryRepository
+	txtPath    string
 }
 
 func (s *productService) Get(ctx context.Context, id int) (domain.Product, error) {
@@ -38,8 +67,32 @@ func (s *productService) Get(ctx context.Context, id int) (domain.Product, error
 	return product, nil
 }
 
+func (s *productService) GetAll(ctx context.Context, class string) ([]domain.Product, error) {
+	if class != "" && !ValidClass(class) {
+		return nil, ErrorABCInvalidClass
+	}
+
+	products, err := s.repository.GetAll(ctx, class)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (s *productService) List(ctx context.Context, spec web.QuerySpec) ([]domain.Product, int, error) {
+	products, total, err := s.repository.List(ctx, spec)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
 func (s *productService) StoreBulk(ctx context.Context) ([]domain.Product, error) {
-	data, err := file.ReadFile(ProductTxtPath)
+	data, err := file.ReadFile(s.txtPath)
 
 	if err != nil {
 		return nil, err
@@ -82,7 +135,12 @@ func (s *productService) StoreBulk(ctx context.Context) ([]domain.Product, error
 }
 
 func (s *productService) GetProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error) {
-	productsMostSelled, err := s.repository.ProductsMostSelled(ctx)
+	getProductsMostSelled := s.repository.ProductsMostSelled
+	if s.summaries != nil {
+		getProductsMostSelled = s.summaries.ProductsMostSelled
+	}
+
+	productsMostSelled, err := getProductsMostSelled(ctx)
 
 	if err != nil {
 		return nil, err
@@ -90,3 +148,45 @@ func (s *productService) GetProductsMostSelled(ctx context.Context) ([]domain.Pr
 
 	return productsMostSelled, nil
 }
+
+func (s *productService) GetABCClassification(ctx context.Context, config ABCConfig) ([]domain.ProductABCDTO, error) {
+	getProductsRevenue := s.repository.ProductsRevenue
+	if s.summaries != nil {
+		getProductsRevenue = s.summaries.ProductsRevenue
+	}
+
+	productsRevenue, err := getProductsRevenue(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return CalculateABC(productsRevenue, config)
+}
+
+// UpdateABCClassification calculates the ABC classification and stores the
+// class of every product, all of them or none.
+func (s *productService) UpdateABCClassification(ctx context.Context, config ABCConfig) ([]domain.ProductABCDTO, error) {
+	productsABC, err := s.GetABCClassification(ctx, config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(productsABC) == 0 {
+		return productsABC, nil
+	}
+
+	products := make([]domain.Product, 0, len(productsABC))
+	for _, productABC := range productsABC {
+		products = append(products, domain.Product{Id: productABC.Id, Class: productABC.Class})
+	}
+
+	_, err = s.repository.UpdateClassBulk(ctx, products)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return productsABC, nil
+}
@@ -3,10 +3,15 @@ package product
 import (
 	"context"
 	"errors"
+	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/summary"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,8 +30,8 @@ func TestServiceProductGet(t *testing.T) {
 	productRepository := NewProductRepository(db)
 	productService := NewProductService(productRepository)
 
-	rows := mock.NewRows([]string{"id", "description", "price"})
-	rows.AddRow(1000, "Mate", 1250.5)
+	rows := mock.NewRows([]string{"id", "description", "price", "class"})
+	rows.AddRow(1000, "Mate", 1250.5, "")
 	mock.ExpectQuery(GetProductQuery).WithArgs(1000).WillReturnRows(rows)
 
 	// Act
@@ -133,3 +138,267 @@ func TestServiceProductGetMostSelledError(t *testing.T) {
 	assert.Error(t, err, "should exists an error")
 	assert.Nil(t, result, "result should be nil")
 }
+
+func TestServiceProductGetMostSelledRetriesDeadlock(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	pool := storage.NewWithOptions(db, storage.MySQL, storage.Options{Retries: 2})
+	defer pool.Close()
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "price"})
+	rows.AddRow(1, "Mate", 1250.5)
+	mock.ExpectQuery("SELECT COUNT").WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(rows)
+
+	// Act
+	result, err := productService.GetProductsMostSelled(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, len(result) > 0, "result should has more than 0 results")
+	assert.Equal(t, int64(1), pool.PoolStats().Retries, "the deadlock should be retried once")
+}
+
+func TestServiceProductGetAll(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "price", "class"})
+	rows.AddRow(1000, "Mate", 1250.5, "A")
+	mock.ExpectQuery("SELECT id, description, price, class FROM products WHERE class").WithArgs("A").WillReturnRows(rows)
+
+	// Act
+	result, err := productService.GetAll(context.Background(), "A")
+
+	// Assert
+	assert.Equal(t, []domain.Product{{Id: 1000, Description: "Mate", Price: 1250.5, Class: "A"}}, result, "result should be equal to expected result")
+	assert.Nil(t, err, "error should be nil")
+}
+
+func TestServiceProductGetAllInterrupted(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "price", "class"})
+	rows.AddRow(1000, "Mate", 1250.5, "A")
+	rows.AddRow(1001, "Yerba", 800.0, "A")
+	rows.RowError(1, context.DeadlineExceeded)
+	mock.ExpectQuery("SELECT id, description, price, class FROM products WHERE class").WithArgs("A").WillReturnRows(rows).RowsWillBeClosed()
+
+	// Act
+	result, err := productService.GetAll(context.Background(), "A")
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "error should be the one stopping the rows")
+	assert.Nil(t, result, "result should not be the rows read before")
+	assert.Nil(t, mock.ExpectationsWereMet(), "rows should be closed")
+}
+
+func TestServiceProductGetAllInvalidClass(t *testing.T) {
+	// Arrange
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	// Act
+	result, err := productService.GetAll(context.Background(), "Z")
+
+	// Assert
+	assert.Equal(t, ErrorABCInvalidClass, err, "error should be invalid class")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceProductGetABCClassification(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "revenue"})
+	rows.AddRow(1, "Mate", 700.0)
+	rows.AddRow(2, "Yerba", 200.0)
+	rows.AddRow(3, "Bombilla", 60.0)
+	rows.AddRow(4, "Termo", 40.0)
+	rows.AddRow(5, "Azucar", 0.0)
+	mock.ExpectQuery("SELECT products.id").WillReturnRows(rows)
+
+	// Act
+	result, err := productService.GetABCClassification(context.Background(), ABCConfig{})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	classes := []string{}
+	for _, productABC := range result {
+		classes = append(classes, productABC.Class)
+	}
+	assert.Equal(t, []string{"A", "A", "B", "C", "C"}, classes, "classes should follow the cumulative share")
+	assert.InDelta(t, 0.96, result[2].CumulativeShare, 0.0001, "cumulative share should be 0.96")
+}
+
+func TestServiceProductGetABCClassificationInvalidCutOffs(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "revenue"})
+	mock.ExpectQuery("SELECT products.id").WillReturnRows(rows)
+
+	// Act
+	result, err := productService.GetABCClassification(context.Background(), ABCConfig{CutOffA: 0.9, CutOffB: 0.5})
+
+	// Assert
+	assert.Equal(t, ErrorABCInvalidCutOffs, err, "error should be invalid cut-offs")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceProductUpdateABCClassification(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "revenue"})
+	rows.AddRow(1, "Mate", 900.0)
+	rows.AddRow(3, "Bombilla", 850.0)
+	rows.AddRow(2, "Yerba", 100.0)
+	mock.ExpectQuery("SELECT products.id").WillReturnRows(rows)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET class = ? WHERE id IN (?, ?)")).WithArgs("A", 1, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET class = ? WHERE id IN (?)")).WithArgs("B", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Act
+	result, err := productService.UpdateABCClassification(context.Background(), ABCConfig{})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 3, len(result), "len of result should be equal to 3")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every class should be stored in a transaction")
+}
+
+func TestServiceProductUpdateABCClassificationError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	rows := mock.NewRows([]string{"id", "description", "revenue"})
+	rows.AddRow(1, "Mate", 900.0)
+	rows.AddRow(2, "Yerba", 100.0)
+	mock.ExpectQuery("SELECT products.id").WillReturnRows(rows)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products SET class").WithArgs("A", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE products SET class").WithArgs("B", 2).WillReturnError(errors.New("error"))
+	mock.ExpectRollback()
+
+	// Act
+	result, err := productService.UpdateABCClassification(context.Background(), ABCConfig{})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorProductExecUpdateStatement, "error should be update failed")
+	assert.Nil(t, result, "result should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the classes stored should be rolled back")
+}
+
+func TestServiceProductGetMostSelledWithSummaries(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductServiceWithSummaries(productRepository, summary.NewSummaryRepository(db))
+
+	rows := mock.NewRows([]string{"count_total", "description", "total"})
+	rows.AddRow(30, "Mate", 3015.0)
+	rows.AddRow(20, "Yerba", 500.0)
+	mock.ExpectQuery("INNER JOIN daily_product_revenue").WillReturnRows(rows)
+
+	// Act
+	result, err := productService.GetProductsMostSelled(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []domain.ProductMostSelledDTO{{Description: "Mate", Total: 3015.0}, {Description: "Yerba", Total: 500.0}}, result, "result should come from the summaries")
+	assert.Nil(t, mock.ExpectationsWereMet(), "sales should not be read")
+}
+
+func TestServiceProductGetABCClassificationWithSummaries(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductServiceWithSummaries(productRepository, summary.NewSummaryRepository(db))
+
+	rows := mock.NewRows([]string{"id", "description", "revenue"})
+	rows.AddRow(1, "Mate", 900.0)
+	rows.AddRow(2, "Yerba", 100.0)
+	mock.ExpectQuery("LEFT JOIN daily_product_revenue").WillReturnRows(rows)
+
+	// Act
+	result, err := productService.GetABCClassification(context.Background(), ABCConfig{})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, len(result), "len of result should be 2")
+	assert.Equal(t, ClassA, result[0].Class, "first product should be A")
+	assert.Nil(t, mock.ExpectationsWereMet(), "sales should not be read")
+}
+
+func TestServiceProductList(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	spec := web.QuerySpec{Page: 2, Size: 1, Sort: []web.Sort{{Field: "price", Desc: true}}}
+	spec = spec.WithFilter("class", web.OperatorEq, ClassA)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE class = \?`).WithArgs(ClassA).WillReturnRows(mock.NewRows([]string{"count"}).AddRow(2))
+	rows := mock.NewRows([]string{"id", "description", "price", "class"})
+	rows.AddRow(2, "Yerba", 200.0, ClassA)
+	mock.ExpectQuery(`SELECT id, description, price, class FROM products WHERE class = \? ORDER BY price DESC, id ASC LIMIT 1 OFFSET 1`).WithArgs(ClassA).WillReturnRows(rows)
+
+	// Act
+	result, total, err := productService.List(context.Background(), spec)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, total, "total should be the count of every page")
+	assert.Equal(t, []domain.Product{{Id: 2, Description: "Yerba", Price: 200.0, Class: ClassA}}, result, "result should be the second page")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestServiceProductListInvalidFilter(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	productRepository := NewProductRepository(db)
+	productService := NewProductService(productRepository)
+
+	spec := web.QuerySpec{Page: 1, Size: 10}.WithFilter("price; DROP TABLE products", web.OperatorEq, "1")
+
+	// Act
+	result, _, err := productService.List(context.Background(), spec)
+
+	// Assert
+	assert.ErrorIs(t, err, web.ErrorQueryInvalidFilter, "error should be invalid filter")
+	assert.True(t, web.IsQueryError(err), "error should be a query error")
+	assert.Nil(t, result, "result should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "no query should run")
+}
@@ -0,0 +1,49 @@
+package sale
+
+import (
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+)
+
+const (
+	AnomalySourceSaleQuantity = "sale_quantity"
+)
+
+// SaleAnomalies flags the sales with an outlier quantity. With
+// config.PerGroup every product gets its own baseline.
+func SaleAnomalies(sales []domain.Sale, config outlier.Config) ([]domain.AnomalyDTO, error) {
+	config, err := config.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	observations := make([]outlier.Observation, 0, len(sales))
+	for _, sale := range sales {
+		observations = append(observations, outlier.Observation{
+			Id:    sale.Id,
+			Group: sale.Product_id,
+			Value: sale.Quantity,
+		})
+	}
+
+	outliers, err := outlier.Detect(observations, config)
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies := make([]domain.AnomalyDTO, 0, len(outliers))
+	for _, saleOutlier := range outliers {
+		anomalies = append(anomalies, domain.AnomalyDTO{
+			Source:    AnomalySourceSaleQuantity,
+			Id:        saleOutlier.Id,
+			ProductId: saleOutlier.Group,
+			Value:     saleOutlier.Value,
+			Score:     saleOutlier.Score,
+			Lower:     saleOutlier.Lower,
+			Upper:     saleOutlier.Upper,
+			Method:    config.Method,
+		})
+	}
+
+	return anomalies, nil
+}
@@ -0,0 +1,255 @@
+package sale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+const (
+	// DefaultBasketMinSupport keeps the itemsets apriori counts bounded, with
+	// no minimum every combination of every invoice would be frequent
+	DefaultBasketMinSupport = 0.01
+	DefaultBasketMaxItems   = 2
+	MaxBasketMaxItems       = 4
+
+	// Datetime bounds used when the basket config has no date filter
+	BasketMinDatetime = "1000-01-01 00:00:00"
+	BasketMaxDatetime = "9999-12-31 23:59:59"
+)
+
+var (
+	// Errors
+	ErrorBasketInvalidMaxItems   = web.NewError(web.KindValidation, "basket_invalid_max_items", "max items must be between 2 and 4")
+	ErrorBasketInvalidThresholds = web.NewError(web.KindValidation, "basket_invalid_thresholds", "support and confidence must be between 0 and 1 and lift can not be negative")
+)
+
+type BasketConfig struct {
+	MinSupport    float64 // share of invoices that must contain the whole itemset, DefaultBasketMinSupport when 0
+	MinConfidence float64 // share of invoices with the antecedent that also have the consequent
+	MinLift       float64
+	MaxItems      int    // biggest itemset size, DefaultBasketMaxItems when 0
+	From          string // invoices.datetime lower bound, inclusive
+	To            string // invoices.datetime upper bound, inclusive
+	Limit         int    // maximum number of associations, all of them when 0
+}
+
+func (c BasketConfig) withDefaults() (BasketConfig, error) {
+	if c.MinSupport == 0 {
+		c.MinSupport = DefaultBasketMinSupport
+	}
+
+	if c.MaxItems == 0 {
+		c.MaxItems = DefaultBasketMaxItems
+	}
+
+	if c.MaxItems < 2 || c.MaxItems > MaxBasketMaxItems {
+		return BasketConfig{}, ErrorBasketInvalidMaxItems
+	}
+
+	if c.MinSupport < 0 || c.MinSupport > 1 || c.MinConfidence < 0 || c.MinConfidence > 1 || c.MinLift < 0 {
+		return BasketConfig{}, ErrorBasketInvalidThresholds
+	}
+
+	if c.From == "" {
+		c.From = BasketMinDatetime
+	}
+
+	if c.To == "" {
+		c.To = BasketMaxDatetime
+	}
+
+	return c, nil
+}
+
+// CalculateAssociations runs apriori over the invoices in items and returns
+// the rules "antecedent => consequent" of every frequent itemset, sorted by
+// lift, confidence and number of invoices.
+func CalculateAssociations(items []domain.SaleBasketItemDTO, config BasketConfig) ([]domain.ProductAssociationDTO, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	products := map[int]domain.BasketProductDTO{}
+	invoiceProducts := map[int][]int{}
+	for _, item := range items {
+		products[item.ProductId] = domain.BasketProductDTO{Id: item.ProductId, Description: item.Description}
+		invoiceProducts[item.InvoiceId] = append(invoiceProducts[item.InvoiceId], item.ProductId)
+	}
+
+	totalInvoices := len(invoiceProducts)
+	if totalInvoices == 0 {
+		return []domain.ProductAssociationDTO{}, nil
+	}
+
+	transactions := make([][]int, 0, totalInvoices)
+	for _, productIds := range invoiceProducts {
+		transactions = append(transactions, uniqueSorted(productIds))
+	}
+
+	// counts has the number of invoices of every frequent itemset, by itemsetKey
+	counts := map[string]int{}
+	for size := 1; size <= config.MaxItems; size++ {
+		sizeCounts := map[string]int{}
+		for _, transaction := range transactions {
+			forEachCombination(transaction, size, func(itemset []int) {
+				if size > 1 && !subsetsAreFrequent(itemset, counts) {
+					return
+				}
+
+				sizeCounts[itemsetKey(itemset)]++
+			})
+		}
+
+		frequent := 0
+		for key, count := range sizeCounts {
+			if float64(count)/float64(totalInvoices) >= config.MinSupport {
+				counts[key] = count
+				frequent++
+			}
+		}
+
+		if frequent == 0 {
+			break
+		}
+	}
+
+	associations := []domain.ProductAssociationDTO{}
+	for key, count := range counts {
+		itemset := parseItemsetKey(key)
+		if len(itemset) < 2 {
+			continue
+		}
+
+		for i, consequentId := range itemset {
+			antecedentIds := make([]int, 0, len(itemset)-1)
+			antecedentIds = append(antecedentIds, itemset[:i]...)
+			antecedentIds = append(antecedentIds, itemset[i+1:]...)
+
+			support := float64(count) / float64(totalInvoices)
+			confidence := float64(count) / float64(counts[itemsetKey(antecedentIds)])
+			lift := confidence / (float64(counts[itemsetKey([]int{consequentId})]) / float64(totalInvoices))
+
+			if confidence < config.MinConfidence || lift < config.MinLift {
+				continue
+			}
+
+			antecedent := make([]domain.BasketProductDTO, 0, len(antecedentIds))
+			for _, antecedentId := range antecedentIds {
+				antecedent = append(antecedent, products[antecedentId])
+			}
+
+			associations = append(associations, domain.ProductAssociationDTO{
+				Antecedent: antecedent,
+				Consequent: products[consequentId],
+				Invoices:   count,
+				Support:    support,
+				Confidence: confidence,
+				Lift:       lift,
+			})
+		}
+	}
+
+	sort.Slice(associations, func(i, j int) bool {
+		a, b := associations[i], associations[j]
+		if a.Lift != b.Lift {
+			return a.Lift > b.Lift
+		}
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		if a.Invoices != b.Invoices {
+			return a.Invoices > b.Invoices
+		}
+		return associationKey(a) < associationKey(b)
+	})
+
+	if config.Limit > 0 && len(associations) > config.Limit {
+		associations = associations[:config.Limit]
+	}
+
+	return associations, nil
+}
+
+func uniqueSorted(ids []int) []int {
+	sort.Ints(ids)
+
+	unique := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// forEachCombination calls fn with every sorted combination of size elements
+// of ids. The slice passed to fn is reused between calls.
+func forEachCombination(ids []int, size int, fn func([]int)) {
+	combination := make([]int, size)
+
+	var walk func(start int, depth int)
+	walk = func(start int, depth int) {
+		if depth == size {
+			fn(combination)
+			return
+		}
+
+		for i := start; i <= len(ids)-(size-depth); i++ {
+			combination[depth] = ids[i]
+			walk(i+1, depth+1)
+		}
+	}
+
+	walk(0, 0)
+}
+
+// subsetsAreFrequent checks the apriori property: every subset one item
+// smaller than itemset must already be frequent.
+func subsetsAreFrequent(itemset []int, counts map[string]int) bool {
+	subset := make([]int, 0, len(itemset)-1)
+	for i := range itemset {
+		subset = subset[:0]
+		subset = append(subset, itemset[:i]...)
+		subset = append(subset, itemset[i+1:]...)
+
+		if _, ok := counts[itemsetKey(subset)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func itemsetKey(itemset []int) string {
+	ids := make([]string, len(itemset))
+	for i, id := range itemset {
+		ids[i] = strconv.Itoa(id)
+	}
+
+	return strings.Join(ids, ",")
+}
+
+func parseItemsetKey(key string) []int {
+	var itemset []int
+	for _, id := range strings.Split(key, ",") {
+		productId, _ := strconv.Atoi(id)
+		itemset = append(itemset, productId)
+	}
+
+	return itemset
+}
+
+func associationKey(association domain.ProductAssociationDTO) string {
+	ids := make([]int, 0, len(association.Antecedent)+1)
+	for _, product := range association.Antecedent {
+		ids = append(ids, product.Id)
+	}
+
+	return itemsetKey(append(ids, association.Consequent.Id))
+}
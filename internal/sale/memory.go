@@ -0,0 +1,268 @@
+package sale
+
+import (
+	"context"
+	"sort"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/internal/memory"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+// NewSaleMemoryRepository returns a SaleRepository keeping the sales and
+// the held sales in store, answering like the SQL one.
+func NewSaleMemoryRepository(store *memory.Store) SaleRepository {
+	return &saleMemoryRepository{
+		store: store,
+	}
+}
+
+type saleMemoryRepository struct {
+	store *memory.Store
+}
+
+func (r *saleMemoryRepository) Get(ctx context.Context, id int) (domain.Sale, error) {
+	var sale domain.Sale
+	var ok bool
+	r.store.Read(func(tables *memory.Tables) {
+		sale, ok = tables.Sales[id]
+	})
+
+	if !ok {
+		return domain.Sale{}, ErrorSaleNotFound
+	}
+
+	return sale, nil
+}
+
+func (r *saleMemoryRepository) StoreBulk(ctx context.Context, sales []domain.Sale) ([]domain.Sale, error) {
+	if len(sales) == 0 {
+		return nil, ErrorSalePrepareStoreStatement.Wrap(memory.ErrorMemoryNoRows)
+	}
+
+	err := r.store.Write(func(tables *memory.Tables) error {
+		ids := make([]int, 0, len(sales))
+		for _, sale := range sales {
+			if err := checkSaleReferences(tables, sale); err != nil {
+				return err
+			}
+
+			ids = append(ids, sale.Id)
+		}
+
+		if err := memory.CheckIds(ids, func(id int) bool { _, ok := tables.Sales[id]; return ok }); err != nil {
+			return err
+		}
+
+		for _, sale := range sales {
+			tables.Sales[sale.Id] = sale
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, ErrorSaleExecStoreStatement.Wrap(err)
+	}
+
+	return sales, nil
+}
+
+func (r *saleMemoryRepository) GetAll(ctx context.Context) ([]domain.Sale, error) {
+	var sales []domain.Sale
+	r.store.Read(func(tables *memory.Tables) {
+		sales = append(sales, tables.SortedSales()...)
+	})
+
+	return sales, nil
+}
+
+func (r *saleMemoryRepository) List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Sale, web.Cursors, error) {
+	keyset, err := spec.Keyset(SaleQueryColumns, SaleSortColumns, SaleDefaultSort, codec)
+
+	if err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	var all []domain.Sale
+	r.store.Read(func(tables *memory.Tables) {
+		all = tables.SortedSales()
+	})
+
+	rows := make([]web.Row, 0, len(all))
+	for _, sale := range all {
+		rows = append(rows, saleRow(sale))
+	}
+
+	var fetched []domain.Sale
+	var keys [][]string
+	for _, index := range keyset.Select(rows) {
+		fetched = append(fetched, all[index])
+		keys = append(keys, saleKey(all[index], keyset.Fields))
+	}
+
+	indexes, cursors := keyset.Page(keys)
+	sales := make([]domain.Sale, 0, len(indexes))
+	for _, index := range indexes {
+		sales = append(sales, fetched[index])
+	}
+
+	return sales, cursors, nil
+}
+
+func (r *saleMemoryRepository) GetBasketItems(ctx context.Context, from string, to string) ([]domain.SaleBasketItemDTO, error) {
+	seen := map[domain.SaleBasketItemDTO]bool{}
+	var basketItems []domain.SaleBasketItemDTO
+	r.store.Read(func(tables *memory.Tables) {
+		for _, sale := range tables.Sales {
+			invoice, ok := tables.Invoices[sale.Invoice_id]
+			if !ok || invoice.Datetime < from || invoice.Datetime > to {
+				continue
+			}
+
+			product, ok := tables.Products[sale.Product_id]
+			if !ok {
+				continue
+			}
+
+			basketItem := domain.SaleBasketItemDTO{InvoiceId: sale.Invoice_id, ProductId: sale.Product_id, Description: product.Description}
+			if !seen[basketItem] {
+				seen[basketItem] = true
+				basketItems = append(basketItems, basketItem)
+			}
+		}
+	})
+
+	sort.Slice(basketItems, func(i, j int) bool {
+		if basketItems[i].InvoiceId != basketItems[j].InvoiceId {
+			return basketItems[i].InvoiceId < basketItems[j].InvoiceId
+		}
+
+		return basketItems[i].ProductId < basketItems[j].ProductId
+	})
+
+	return basketItems, nil
+}
+
+func (r *saleMemoryRepository) GetHeld(ctx context.Context, id int) (domain.HeldSaleDTO, error) {
+	var heldSale domain.HeldSaleDTO
+	var ok bool
+	r.store.Read(func(tables *memory.Tables) {
+		heldSale, ok = tables.HeldSales[id]
+	})
+
+	if !ok {
+		return domain.HeldSaleDTO{}, ErrorSaleNotFound
+	}
+
+	return heldSale, nil
+}
+
+func (r *saleMemoryRepository) GetAllHeld(ctx context.Context) ([]domain.HeldSaleDTO, error) {
+	var heldSales []domain.HeldSaleDTO
+	r.store.Read(func(tables *memory.Tables) {
+		heldSales = append(heldSales, tables.SortedHeldSales()...)
+	})
+
+	return heldSales, nil
+}
+
+// StoreHeldBulk holds the given sales for review, a sale held again keeps
+// the latest reason and nothing else of the new one.
+func (r *saleMemoryRepository) StoreHeldBulk(ctx context.Context, heldSales []domain.HeldSaleDTO) ([]domain.HeldSaleDTO, error) {
+	if len(heldSales) == 0 {
+		return nil, ErrorSalePrepareStoreStatement.Wrap(memory.ErrorMemoryNoRows)
+	}
+
+	err := r.store.Write(func(tables *memory.Tables) error {
+		for _, heldSale := range heldSales {
+			if err := checkSaleReferences(tables, heldSale.Sale); err != nil {
+				return err
+			}
+		}
+
+		for _, heldSale := range heldSales {
+			if stored, ok := tables.HeldSales[heldSale.Sale.Id]; ok {
+				stored.Reason = heldSale.Reason
+				tables.HeldSales[heldSale.Sale.Id] = stored
+				continue
+			}
+
+			tables.HeldSales[heldSale.Sale.Id] = heldSale
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, ErrorSaleExecStoreStatement.Wrap(err)
+	}
+
+	return heldSales, nil
+}
+
+func (r *saleMemoryRepository) DeleteHeld(ctx context.Context, id int) error {
+	return r.store.Write(func(tables *memory.Tables) error {
+		if _, ok := tables.HeldSales[id]; !ok {
+			return ErrorSaleNotFound
+		}
+
+		delete(tables.HeldSales, id)
+
+		return nil
+	})
+}
+
+// ReleaseHeld stores the held sale and deletes it from the held sales, both
+// or neither.
+func (r *saleMemoryRepository) ReleaseHeld(ctx context.Context, sale domain.Sale) error {
+	return r.store.Write(func(tables *memory.Tables) error {
+		if err := checkSaleReferences(tables, sale); err != nil {
+			return ErrorSaleExecStoreStatement.Wrap(err)
+		}
+
+		if _, ok := tables.Sales[sale.Id]; ok {
+			return ErrorSaleExecStoreStatement.Wrap(memory.ErrorMemoryDuplicateKey)
+		}
+
+		if _, ok := tables.HeldSales[sale.Id]; !ok {
+			return ErrorSaleNotFound
+		}
+
+		tables.Sales[sale.Id] = sale
+		delete(tables.HeldSales, sale.Id)
+
+		return nil
+	})
+}
+
+// checkSaleReferences fails with ErrorMemoryForeignKey when the invoice or
+// the product of sale are not stored.
+func checkSaleReferences(tables *memory.Tables, sale domain.Sale) error {
+	if _, ok := tables.Invoices[sale.Invoice_id]; !ok {
+		return memory.ErrorMemoryForeignKey
+	}
+
+	if _, ok := tables.Products[sale.Product_id]; !ok {
+		return memory.ErrorMemoryForeignKey
+	}
+
+	return nil
+}
+
+func saleRow(sale domain.Sale) web.Row {
+	return func(field string) interface{} {
+		switch field {
+		case "id":
+			return sale.Id
+		case "invoice_id":
+			return sale.Invoice_id
+		case "product_id":
+			return sale.Product_id
+		case "quantity":
+			return sale.Quantity
+		}
+
+		return nil
+	}
+}
@@ -5,72 +5,314 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
 	// Db queries & statements
-	GetSaleQuery       = "SELECT id, invoice_id, product_id, quantity FROM sales WHERE id = ?"
-	StoreSaleStatement = "INSERT INTO sales(invoice_id, product_id, quantity) VALUES(?, ?, ?)"
+	GetSaleQuery             = "SELECT id, invoice_id, product_id, quantity FROM sales WHERE id = ?"
+	GetSalesBasketItemsQuery = "SELECT DISTINCT sales.invoice_id, sales.product_id, products.description FROM sales INNER JOIN invoices ON invoices.id = sales.invoice_id INNER JOIN products ON products.id = sales.product_id WHERE invoices.datetime >= ? AND invoices.datetime <= ? ORDER BY sales.invoice_id, sales.product_id;"
+	GetAllSalesQuery         = "SELECT id, invoice_id, product_id, quantity FROM sales ORDER BY id"
+	GetHeldSaleQuery         = "SELECT id, invoice_id, product_id, quantity, reason FROM sales_held WHERE id = ?"
+	GetAllHeldSalesQuery     = "SELECT id, invoice_id, product_id, quantity, reason FROM sales_held ORDER BY id"
+	StoreSaleStatement       = "INSERT INTO sales(invoice_id, product_id, quantity) VALUES(?, ?, ?)"
+	ReleaseSaleStatement     = "INSERT INTO sales(id, invoice_id, product_id, quantity) VALUES(?, ?, ?, ?)"
+	DeleteHeldSaleStatement  = "DELETE FROM sales_held WHERE id = ?"
+	ListSalesQuery           = "SELECT id, invoice_id, product_id, quantity FROM sales %s %s %s"
+
+	// Bulk insert columns
+	StoreSalesColumns = []string{"id", "invoice_id", "product_id", "quantity"}
+
+	// Listing allow-list
+	SaleQueryColumns = web.QueryColumns{
+		"id":         "id",
+		"invoice_id": "invoice_id",
+		"product_id": "product_id",
+		"quantity":   "quantity",
+	}
+	// The quantities are floats, the cursors can not point at them
+	SaleSortColumns = web.QueryColumns{
+		"id":         "id",
+		"invoice_id": "invoice_id",
+		"product_id": "product_id",
+	}
+	SaleDefaultSort = []web.Sort{{Field: "id"}}
 
 	// Errors
-	ErrorSaleNotFound              = errors.New("sale not found")
-	ErrorSalePrepareStoreStatement = errors.New("can not prepare store statement")
-	ErrorSaleExecStoreStatement    = errors.New("error executing store statement")
+	ErrorSaleNotFound               = web.NewError(web.KindNotFound, "sale_not_found", "sale not found")
+	ErrorSalePrepareStoreStatement  = web.NewError(web.KindInternal, "sale_store_prepare_failed", "can not prepare store statement")
+	ErrorSaleExecStoreStatement     = web.NewError(web.KindInternal, "sale_store_failed", "error executing store statement")
+	ErrorSalePrepareDeleteStatement = web.NewError(web.KindInternal, "sale_delete_prepare_failed", "can not prepare delete statement")
+	ErrorSaleExecDeleteStatement    = web.NewError(web.KindInternal, "sale_delete_failed", "error executing delete statement")
+	ErrorSaleAlreadyStored          = web.NewError(web.KindConflict, "sale_already_stored", "a sale with the same id is already stored")
+	ErrorSaleBeginRelease           = web.NewError(web.KindInternal, "sale_release_begin_failed", "can not begin held sale release")
+	ErrorSaleCommitRelease          = web.NewError(web.KindInternal, "sale_release_commit_failed", "can not commit held sale release")
 )
 
 type SaleRepository interface {
 	Get(ctx context.Context, id int) (domain.Sale, error)
 	StoreBulk(ctx context.Context, sales []domain.Sale) ([]domain.Sale, error)
+	GetAll(ctx context.Context) ([]domain.Sale, error)
+	List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Sale, web.Cursors, error)
+	GetBasketItems(ctx context.Context, from string, to string) ([]domain.SaleBasketItemDTO, error)
+	GetHeld(ctx context.Context, id int) (domain.HeldSaleDTO, error)
+	GetAllHeld(ctx context.Context) ([]domain.HeldSaleDTO, error)
+	StoreHeldBulk(ctx context.Context, heldSales []domain.HeldSaleDTO) ([]domain.HeldSaleDTO, error)
+	DeleteHeld(ctx context.Context, id int) error
+	ReleaseHeld(ctx context.Context, sale domain.Sale) error
 }
 
 func NewSaleRepository(db *sql.DB) SaleRepository {
+	return NewSaleRepositoryWithDialect(db, storage.MySQL)
+}
+
+// NewSaleRepositoryWithDialect returns a SaleRepository running its queries
+// in dialect.
+func NewSaleRepositoryWithDialect(db *sql.DB, dialect storage.Dialect) SaleRepository {
 	return &saleRepository{
-		db: db,
+		db: storage.New(db, dialect),
 	}
 }
 
 type saleRepository struct {
-	db *sql.DB
+	db *storage.DB
 }
 
 func (r *saleRepository) Get(ctx context.Context, id int) (domain.Sale, error) {
 	var sale domain.Sale
-	err := r.db.QueryRowContext(ctx, GetSaleQuery, id).Scan(&sale.Id, &sale.Invoice_id, &sale.Product_id, &sale.Quantity)
+	err := r.db.Reader(ctx).QueryRowContext(ctx, GetSaleQuery, id).Scan(&sale.Id, &sale.Invoice_id, &sale.Product_id, &sale.Quantity)
 
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		return domain.Sale{}, ErrorSaleNotFound
 	}
 
+	if err != nil {
+		return domain.Sale{}, err
+	}
+
 	return sale, nil
 }
 
 func (r *saleRepository) StoreBulk(ctx context.Context, sales []domain.Sale) ([]domain.Sale, error) {
-	valueStrings := make([]string, 0, len(sales))
-	valueArgs := make([]interface{}, 0, len(sales)*4)
-
+	rows := make([][]interface{}, 0, len(sales))
 	for _, sale := range sales {
-		valueStrings = append(valueStrings, "(?, ?, ?, ?)")
-		valueArgs = append(valueArgs, sale.Id)
-		valueArgs = append(valueArgs, sale.Invoice_id)
-		valueArgs = append(valueArgs, sale.Product_id)
-		valueArgs = append(valueArgs, sale.Quantity)
+		rows = append(rows, []interface{}{sale.Id, sale.Invoice_id, sale.Product_id, sale.Quantity})
+	}
+
+	err := r.db.InsertBulk(ctx, "sales", StoreSalesColumns, rows)
+
+	if errors.Is(err, storage.ErrorStoragePrepareBulk) {
+		return nil, ErrorSalePrepareStoreStatement.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, ErrorSaleExecStoreStatement.Wrap(err)
+	}
+
+	return sales, nil
+}
+
+// List returns the keyset page of sales of spec, with the cursors of the
+// pages around it.
+func (r *saleRepository) List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Sale, web.Cursors, error) {
+	keyset, err := spec.Keyset(SaleQueryColumns, SaleSortColumns, SaleDefaultSort, codec)
+
+	if err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, fmt.Sprintf(ListSalesQuery, keyset.Clauses.Where, keyset.Clauses.OrderBy, keyset.Clauses.Limit), keyset.Clauses.Args...)
+
+	if err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	defer rows.Close()
+
+	var fetched []domain.Sale
+	var keys [][]string
+
+	for rows.Next() {
+		var sale domain.Sale
+		err = rows.Scan(&sale.Id, &sale.Invoice_id, &sale.Product_id, &sale.Quantity)
+		if err != nil {
+			return nil, web.Cursors{}, err
+		}
+
+		fetched = append(fetched, sale)
+		keys = append(keys, saleKey(sale, keyset.Fields))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	indexes, cursors := keyset.Page(keys)
+	sales := make([]domain.Sale, 0, len(indexes))
+	for _, index := range indexes {
+		sales = append(sales, fetched[index])
+	}
+
+	return sales, cursors, nil
+}
+
+// saleKey returns the values of the given fields of sale, its position in a
+// keyset listing.
+func saleKey(sale domain.Sale, fields []string) []string {
+	key := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			key = append(key, strconv.Itoa(sale.Id))
+		case "invoice_id":
+			key = append(key, strconv.Itoa(sale.Invoice_id))
+		case "product_id":
+			key = append(key, strconv.Itoa(sale.Product_id))
+		case "quantity":
+			key = append(key, strconv.FormatFloat(sale.Quantity, 'f', -1, 64))
+		}
+	}
+
+	return key
+}
+
+// GetBasketItems returns the distinct products of every invoice with datetime
+// between from and to, both inclusive.
+func (r *saleRepository) GetBasketItems(ctx context.Context, from string, to string) ([]domain.SaleBasketItemDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetSalesBasketItemsQuery, from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var basketItems []domain.SaleBasketItemDTO
+
+	for rows.Next() {
+		var basketItem domain.SaleBasketItemDTO
+		err = rows.Scan(&basketItem.InvoiceId, &basketItem.ProductId, &basketItem.Description)
+		if err != nil {
+			return nil, err
+		}
+
+		basketItems = append(basketItems, basketItem)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return basketItems, nil
+}
+
+func (r *saleRepository) GetAll(ctx context.Context) ([]domain.Sale, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetAllSalesQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var sales []domain.Sale
+
+	for rows.Next() {
+		var sale domain.Sale
+		err = rows.Scan(&sale.Id, &sale.Invoice_id, &sale.Product_id, &sale.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		sales = append(sales, sale)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+func (r *saleRepository) GetHeld(ctx context.Context, id int) (domain.HeldSaleDTO, error) {
+	var heldSale domain.HeldSaleDTO
+	err := r.db.Reader(ctx).QueryRowContext(ctx, GetHeldSaleQuery, id).Scan(&heldSale.Sale.Id, &heldSale.Sale.Invoice_id, &heldSale.Sale.Product_id, &heldSale.Sale.Quantity, &heldSale.Reason)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.HeldSaleDTO{}, ErrorSaleNotFound
+	}
+
+	if err != nil {
+		return domain.HeldSaleDTO{}, err
+	}
+
+	return heldSale, nil
+}
+
+func (r *saleRepository) GetAllHeld(ctx context.Context) ([]domain.HeldSaleDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetAllHeldSalesQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var heldSales []domain.HeldSaleDTO
+
+	for rows.Next() {
+		var heldSale domain.HeldSaleDTO
+		err = rows.Scan(&heldSale.Sale.Id, &heldSale.Sale.Invoice_id, &heldSale.Sale.Product_id, &heldSale.Sale.Quantity, &heldSale.Reason)
+		if err != nil {
+			return nil, err
+		}
+
+		heldSales = append(heldSales, heldSale)
 	}
 
-	stmtString := fmt.Sprintf("INSERT INTO sales (id, invoice_id, product_id, quantity) VALUES %s", strings.Join(valueStrings, ","))
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return heldSales, nil
+}
+
+// StoreHeldBulk holds the given sales for review, a sale held again keeps
+// the latest reason.
+func (r *saleRepository) StoreHeldBulk(ctx context.Context, heldSales []domain.HeldSaleDTO) ([]domain.HeldSaleDTO, error) {
+	valueStrings := make([]string, 0, len(heldSales))
+	valueArgs := make([]interface{}, 0, len(heldSales)*5)
+
+	for _, heldSale := range heldSales {
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?)")
+		valueArgs = append(valueArgs, heldSale.Sale.Id)
+		valueArgs = append(valueArgs, heldSale.Sale.Invoice_id)
+		valueArgs = append(valueArgs, heldSale.Sale.Product_id)
+		valueArgs = append(valueArgs, heldSale.Sale.Quantity)
+		valueArgs = append(valueArgs, heldSale.Reason)
+	}
+
+	stmtString := fmt.Sprintf("INSERT INTO sales_held (id, invoice_id, product_id, quantity, reason) VALUES %s %s", strings.Join(valueStrings, ","), r.db.Dialect.Upsert([]string{"id"}, []string{"reason"}))
 	stmt, err := r.db.PrepareContext(ctx, stmtString)
 	if err != nil {
-		return nil, ErrorSalePrepareStoreStatement
+		return nil, ErrorSalePrepareStoreStatement.Wrap(err)
 	}
 
 	defer stmt.Close()
 
-	result, err := r.db.Exec(stmtString, valueArgs...)
+	var result sql.Result
+	err = r.db.Retry(ctx, func() error {
+		result, err = stmt.ExecContext(ctx, valueArgs...)
+		return err
+	})
 
 	if err != nil {
-		return nil, ErrorSaleExecStoreStatement
+		return nil, ErrorSaleExecStoreStatement.Wrap(err)
 	}
 
 	_, err = result.RowsAffected()
@@ -78,5 +320,88 @@ func (r *saleRepository) StoreBulk(ctx context.Context, sales []domain.Sale) ([]
 		return nil, err
 	}
 
-	return sales, nil
+	return heldSales, nil
+}
+
+func (r *saleRepository) DeleteHeld(ctx context.Context, id int) error {
+	stmt, err := r.db.PrepareContext(ctx, DeleteHeldSaleStatement)
+
+	if err != nil {
+		return ErrorSalePrepareDeleteStatement.Wrap(err)
+	}
+
+	defer stmt.Close()
+
+	var result sql.Result
+	err = r.db.Retry(ctx, func() error {
+		result, err = stmt.ExecContext(ctx, id)
+		return err
+	})
+
+	if err != nil {
+		return ErrorSaleExecDeleteStatement.Wrap(err)
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrorSaleNotFound
+	}
+
+	return nil
+}
+
+// ReleaseHeld stores the held sale and deletes it from the held sales in a
+// single transaction, failing with ErrorSaleNotFound when it is not held
+// anymore. A transaction rolled back by a deadlock is run again whole.
+func (r *saleRepository) ReleaseHeld(ctx context.Context, sale domain.Sale) error {
+	return r.db.Retry(ctx, func() error {
+		return r.releaseHeldTx(ctx, sale)
+	})
+}
+
+func (r *saleRepository) releaseHeldTx(ctx context.Context, sale domain.Sale) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrorSaleBeginRelease.Wrap(err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, ReleaseSaleStatement, sale.Id, sale.Invoice_id, sale.Product_id, sale.Quantity)
+
+	if err != nil {
+		return ErrorSaleExecStoreStatement.Wrap(err)
+	}
+
+	if err = tx.SetSequence(ctx, "sales"); err != nil {
+		return ErrorSaleExecStoreStatement.Wrap(err)
+	}
+
+	result, err := tx.ExecContext(ctx, DeleteHeldSaleStatement, sale.Id)
+
+	if err != nil {
+		return ErrorSaleExecDeleteStatement.Wrap(err)
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrorSaleNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrorSaleCommitRelease.Wrap(err)
+	}
+
+	return nil
 }
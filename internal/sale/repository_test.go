@@ -2,6 +2,8 @@ package sale
 
 import (
 	"context"
+	"log"
+	"os"
 	"testing"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/customer"
@@ -9,9 +11,27 @@ import (
 	"github.com/matias-ziliotto/HackthonGo/internal/invoice"
 	"github.com/matias-ziliotto/HackthonGo/internal/product"
 	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
+// dialect is the one of the DB of the config, the repositories run on
+var dialect = storage.MySQL
+
+func TestMain(m *testing.M) {
+	configDialect, remove, err := sql.RegisterConfigTxDb([]string{"-env", "../../cmd/server/.env"})
+	if err == nil {
+		dialect = configDialect
+	} else {
+		log.Printf("repository tests need a db: %v", err)
+	}
+
+	code := m.Run()
+	remove()
+	os.Exit(code)
+}
+
 var salesToStoreAndGet = []domain.Sale{
 	{
 		Id:         400000,
@@ -87,16 +107,14 @@ var products = []domain.Product{
 
 func TestSaleGet(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewSaleRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	repositoryInvoice := invoice.NewInvoiceRepository(db)
-	repositoryProduct := product.NewProductRepository(db)
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	repositoryInvoice := invoice.NewInvoiceRepositoryWithDialect(db, dialect)
+	repositoryProduct := product.NewProductRepositoryWithDialect(db, dialect)
 
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 	_, err = repositoryInvoice.StoreBulk(context.Background(), invoices) // insert dummy invoice
 	assert.Nil(t, err, "error should be nil")
@@ -114,10 +132,8 @@ func TestSaleGet(t *testing.T) {
 
 func TestSaleGetNotFound(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewSaleRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.Get(context.Background(), 99999)
@@ -129,16 +145,14 @@ func TestSaleGetNotFound(t *testing.T) {
 
 func TestSaleStoreBulk(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewSaleRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	repositoryInvoice := invoice.NewInvoiceRepository(db)
-	repositoryProduct := product.NewProductRepository(db)
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	repositoryInvoice := invoice.NewInvoiceRepositoryWithDialect(db, dialect)
+	repositoryProduct := product.NewProductRepositoryWithDialect(db, dialect)
 
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 	_, err = repositoryInvoice.StoreBulk(context.Background(), invoices) // insert dummy invoice
 	assert.Nil(t, err, "error should be nil")
@@ -155,10 +169,8 @@ func TestSaleStoreBulk(t *testing.T) {
 
 func TestSaleStoreBulkErrorPrepare(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewSaleRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
 
 	// Act
 	result, err := repository.StoreBulk(context.Background(), salesToStoreErrorPrepare)
@@ -170,16 +182,14 @@ func TestSaleStoreBulkErrorPrepare(t *testing.T) {
 
 func TestSaleStoreBulkError(t *testing.T) {
 	// Arrange
-	db, err := sql.InitTxSqlDb()
-	assert.Nil(t, err, "error should be nil")
-	defer db.Close()
-	repository := NewSaleRepository(db)
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
 
-	repositoryCustomer := customer.NewCustomerRepository(db)
-	repositoryInvoice := invoice.NewInvoiceRepository(db)
-	repositoryProduct := product.NewProductRepository(db)
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	repositoryInvoice := invoice.NewInvoiceRepositoryWithDialect(db, dialect)
+	repositoryProduct := product.NewProductRepositoryWithDialect(db, dialect)
 
-	_, err = repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
 	assert.Nil(t, err, "error should be nil")
 	_, err = repositoryInvoice.StoreBulk(context.Background(), invoices) // insert dummy invoice
 	assert.Nil(t, err, "error should be nil")
@@ -193,3 +203,95 @@ func TestSaleStoreBulkError(t *testing.T) {
 	assert.Error(t, err, "should exist an error")
 	assert.Nil(t, result, "result should be nil")
 }
+
+func TestSaleGetBasketItems(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
+
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	repositoryInvoice := invoice.NewInvoiceRepositoryWithDialect(db, dialect)
+	repositoryProduct := product.NewProductRepositoryWithDialect(db, dialect)
+
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	assert.Nil(t, err, "error should be nil")
+	_, err = repositoryInvoice.StoreBulk(context.Background(), invoices) // insert dummy invoice
+	assert.Nil(t, err, "error should be nil")
+	_, err = repositoryProduct.StoreBulk(context.Background(), products) // insert dummy product
+	assert.Nil(t, err, "error should be nil")
+	_, err = repository.StoreBulk(context.Background(), salesToStore)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	result, err := repository.GetBasketItems(context.Background(), "2022-01-06 00:00:00", "2022-01-06 23:59:59")
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Contains(t, result, domain.SaleBasketItemDTO{InvoiceId: 1000, ProductId: 2000, Description: "Descripcion x"}, "result should have the stored sale product")
+}
+
+func TestSaleHeld(t *testing.T) {
+	// Arrange
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
+
+	repositoryCustomer := customer.NewCustomerRepositoryWithDialect(db, dialect)
+	repositoryInvoice := invoice.NewInvoiceRepositoryWithDialect(db, dialect)
+	repositoryProduct := product.NewProductRepositoryWithDialect(db, dialect)
+
+	_, err := repositoryCustomer.StoreBulk(context.Background(), customers) // insert dummy customer
+	assert.Nil(t, err, "error should be nil")
+	_, err = repositoryInvoice.StoreBulk(context.Background(), invoices) // insert dummy invoice
+	assert.Nil(t, err, "error should be nil")
+	_, err = repositoryProduct.StoreBulk(context.Background(), products) // insert dummy product
+	assert.Nil(t, err, "error should be nil")
+
+	heldSale := domain.HeldSaleDTO{Sale: salesToStoreAndGet[0], Reason: "quantity outlier"}
+
+	// Act
+	_, err = repository.StoreHeldBulk(context.Background(), []domain.HeldSaleDTO{heldSale})
+	assert.Nil(t, err, "error should be nil")
+	result, err := repository.GetHeld(context.Background(), heldSale.Sale.Id)
+	assert.Nil(t, err, "error should be nil")
+	errDelete := repository.DeleteHeld(context.Background(), heldSale.Sale.Id)
+	_, errNotFound := repository.GetHeld(context.Background(), heldSale.Sale.Id)
+
+	// Assert
+	assert.Equal(t, heldSale, result, "result should be equal held sale stored")
+	assert.Nil(t, errDelete, "error should be nil")
+	assert.Equal(t, ErrorSaleNotFound, errNotFound, "held sale should be deleted")
+}
+
+func TestSaleListCursors(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSaleRepositoryWithDialect(db, dialect)
+	codec := web.NewCursorCodec([]byte("secret"))
+
+	_, err := customer.NewCustomerRepositoryWithDialect(db, dialect).StoreBulk(ctx, customers) // insert dummy customer
+	assert.Nil(t, err, "error should be nil")
+	_, err = invoice.NewInvoiceRepositoryWithDialect(db, dialect).StoreBulk(ctx, invoices) // insert dummy invoice
+	assert.Nil(t, err, "error should be nil")
+	_, err = product.NewProductRepositoryWithDialect(db, dialect).StoreBulk(ctx, products) // insert dummy product
+	assert.Nil(t, err, "error should be nil")
+	_, err = repository.StoreBulk(ctx, salesToStore)
+	assert.Nil(t, err, "error should be nil")
+	spec := web.QuerySpec{Size: 2, Sort: []web.Sort{{Field: "id", Desc: true}}}.WithFilter("invoice_id", web.OperatorEq, "1000")
+
+	// Act
+	firstPage, firstCursors, err := repository.List(ctx, spec, codec)
+	assert.Nil(t, err, "error should be nil")
+	spec.Cursor = firstCursors.Next
+	secondPage, secondCursors, err := repository.List(ctx, spec, codec)
+	assert.Nil(t, err, "error should be nil")
+	spec.Cursor = secondCursors.Prev
+	previousPage, _, err := repository.List(ctx, spec, codec)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []domain.Sale{salesToStore[2], salesToStore[1]}, firstPage, "first page should have the last sales")
+	assert.Equal(t, []domain.Sale{salesToStore[0]}, secondPage, "second page should have the rest")
+	assert.Empty(t, secondCursors.Next, "last page should not have next cursor")
+	assert.Equal(t, firstPage, previousPage, "previous page should be the first page again")
+}
@@ -2,10 +2,14 @@ package sale
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
 	"github.com/matias-ziliotto/HackthonGo/pkg/file"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 )
 
 var (
@@ -15,16 +19,33 @@ var (
 type SaleService interface {
 	Get(ctx context.Context, id int) (domain.Sale, error)
 	StoreBulk(ctx context.Context) ([]domain.Sale, error)
+	StoreBulkWithAnomalies(ctx context.Context, config outlier.Config, hold bool) ([]domain.Sale, []domain.AnomalyDTO, error)
+	GetAllHeld(ctx context.Context) ([]domain.HeldSaleDTO, error)
+	List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Sale, web.Cursors, error)
+	ReleaseHeld(ctx context.Context, id int) (domain.Sale, error)
+	RejectHeld(ctx context.Context, id int) error
+	GetProductAssociations(ctx context.Context, config BasketConfig) ([]domain.ProductAssociationDTO, error)
 }
 
 func NewSaleService(pr SaleRepository) SaleService {
 	return &saleService{
 		repository: pr,
+		txtPath:    SaleTxtPath,
+	}
+}
+
+// NewSaleServiceWithDataFile is NewSaleService loading the sales from txtPath
+// instead of SaleTxtPath.
+func NewSaleServiceWithDataFile(pr SaleRepository, txtPath string) SaleService {
+	return &saleService{
+		repository: pr,
+		txtPath:    txtPath,
 	}
 }
 
 type saleService struct {
 	repository SaleRepository
+	txtPath    string
 }
 
 func (s *saleService) Get(ctx context.Context, id int) (domain.Sale, error) {
@@ -38,7 +59,172 @@ func (s *saleService) Get(ctx context.Context, id int) (domain.Sale, error) {
 }
 
 func (s *saleService) StoreBulk(ctx context.Context) ([]domain.Sale, error) {
-	data, err := file.ReadFile(SaleTxtPath)
+	sales, err := s.readNewSales(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sales) == 0 {
+		return sales, nil
+	}
+
+	salesSaved, err := s.repository.StoreBulk(ctx, sales)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return salesSaved, nil
+}
+
+// StoreBulkWithAnomalies stores the new sales of the file like StoreBulk and
+// returns the ones with an outlier quantity, using the stored sales as part of
+// the baseline. When hold is true flagged sales are kept in the held sales for
+// review instead of being stored.
+func (s *saleService) StoreBulkWithAnomalies(ctx context.Context, config outlier.Config, hold bool) ([]domain.Sale, []domain.AnomalyDTO, error) {
+	sales, err := s.readNewSales(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(sales) == 0 {
+		return sales, nil, nil
+	}
+
+	storedSales, err := s.repository.GetAll(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allAnomalies, err := SaleAnomalies(append(storedSales, sales...), config)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newSales := map[int]domain.Sale{}
+	for _, sale := range sales {
+		newSales[sale.Id] = sale
+	}
+
+	var anomalies []domain.AnomalyDTO
+	var heldSales []domain.HeldSaleDTO
+	for _, anomaly := range allAnomalies {
+		sale, ok := newSales[anomaly.Id]
+		if !ok {
+			continue
+		}
+
+		anomalies = append(anomalies, anomaly)
+		if hold {
+			heldSales = append(heldSales, domain.HeldSaleDTO{
+				Sale:   sale,
+				Reason: fmt.Sprintf("quantity %g outside [%g, %g] (%s, score %.2f)", anomaly.Value, anomaly.Lower, anomaly.Upper, anomaly.Method, anomaly.Score),
+			})
+			delete(newSales, anomaly.Id)
+		}
+	}
+
+	salesToStore := make([]domain.Sale, 0, len(newSales))
+	for _, sale := range sales {
+		if _, ok := newSales[sale.Id]; ok {
+			salesToStore = append(salesToStore, sale)
+		}
+	}
+
+	if len(heldSales) > 0 {
+		_, err = s.repository.StoreHeldBulk(ctx, heldSales)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(salesToStore) == 0 {
+		return salesToStore, anomalies, nil
+	}
+
+	salesSaved, err := s.repository.StoreBulk(ctx, salesToStore)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return salesSaved, anomalies, nil
+}
+
+func (s *saleService) List(ctx context.Context, spec web.QuerySpec, codec *web.CursorCodec) ([]domain.Sale, web.Cursors, error) {
+	sales, cursors, err := s.repository.List(ctx, spec, codec)
+
+	if err != nil {
+		return nil, web.Cursors{}, err
+	}
+
+	return sales, cursors, nil
+}
+
+func (s *saleService) GetAllHeld(ctx context.Context) ([]domain.HeldSaleDTO, error) {
+	heldSales, err := s.repository.GetAllHeld(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return heldSales, nil
+}
+
+// ReleaseHeld stores a held sale and removes it from the held sales, both or
+// neither. It fails with ErrorSaleAlreadyStored when a sale with its id was
+// stored since.
+func (s *saleService) ReleaseHeld(ctx context.Context, id int) (domain.Sale, error) {
+	heldSale, err := s.repository.GetHeld(ctx, id)
+
+	if err != nil {
+		return domain.Sale{}, err
+	}
+
+	_, err = s.repository.Get(ctx, heldSale.Sale.Id)
+
+	if err == nil {
+		return domain.Sale{}, ErrorSaleAlreadyStored
+	}
+
+	if !errors.Is(err, ErrorSaleNotFound) {
+		return domain.Sale{}, err
+	}
+
+	err = s.repository.ReleaseHeld(ctx, heldSale.Sale)
+
+	if err != nil {
+		return domain.Sale{}, err
+	}
+
+	return heldSale.Sale, nil
+}
+
+// RejectHeld discards a held sale.
+func (s *saleService) RejectHeld(ctx context.Context, id int) error {
+	return s.repository.DeleteHeld(ctx, id)
+}
+
+// readNewSales parses the sales file and returns the sales that are neither
+// stored nor held for review.
+func (s *saleService) readNewSales(ctx context.Context) ([]domain.Sale, error) {
+	heldSales, err := s.repository.GetAllHeld(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	heldIds := map[int]bool{}
+	for _, heldSale := range heldSales {
+		heldIds[heldSale.Sale.Id] = true
+	}
+
+	data, err := file.ReadFile(s.txtPath)
 
 	if err != nil {
 		return nil, err
@@ -52,7 +238,7 @@ func (s *saleService) StoreBulk(ctx context.Context) ([]domain.Sale, error) {
 		invoiceId, errInvoiceId := strconv.Atoi(line[2])
 		quantity, errQuantity := strconv.ParseFloat(line[3], 64)
 
-		if errId == nil && errInvoiceId == nil && errProductId == nil && errQuantity == nil {
+		if errId == nil && errInvoiceId == nil && errProductId == nil && errQuantity == nil && !heldIds[id] {
 			emptySale := domain.Sale{}
 			saleAux := domain.Sale{
 				Id:         id,
@@ -69,15 +255,20 @@ func (s *saleService) StoreBulk(ctx context.Context) ([]domain.Sale, error) {
 		}
 	}
 
-	if len(sales) == 0 {
-		return sales, nil
+	return sales, nil
+}
+
+func (s *saleService) GetProductAssociations(ctx context.Context, config BasketConfig) ([]domain.ProductAssociationDTO, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
 	}
 
-	salesSaved, err := s.repository.StoreBulk(ctx, sales)
+	basketItems, err := s.repository.GetBasketItems(ctx, config.From, config.To)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return salesSaved, nil
+	return CalculateAssociations(basketItems, config)
 }
@@ -2,11 +2,14 @@ package sale
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/outlier"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -62,6 +65,7 @@ func TestServiceSaleStoreBulk(t *testing.T) {
 	saleRepository := NewSaleRepository(db)
 	saleService := NewSaleService(saleRepository)
 
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held").WillReturnRows(mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"}))
 	for i := 1; i <= 1000; i++ {
 		mock.ExpectQuery(GetSaleQuery).WithArgs(i).WillReturnError(ErrorSaleNotFound)
 	}
@@ -84,6 +88,7 @@ func TestServiceSaleStoreBulkError(t *testing.T) {
 	saleRepository := NewSaleRepository(db)
 	saleService := NewSaleService(saleRepository)
 
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held").WillReturnRows(mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"}))
 	for i := 1; i <= 1000; i++ {
 		mock.ExpectQuery(GetSaleQuery).WithArgs(i).WillReturnError(ErrorSaleNotFound)
 	}
@@ -98,3 +103,377 @@ func TestServiceSaleStoreBulkError(t *testing.T) {
 	assert.Error(t, err, "should exists an error")
 	assert.Nil(t, result, "result should be nil")
 }
+
+func TestServiceSaleGetProductAssociations(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	rows := mock.NewRows([]string{"invoice_id", "product_id", "description"})
+	rows.AddRow(1, 1, "Mate")
+	rows.AddRow(1, 2, "Yerba")
+	rows.AddRow(2, 1, "Mate")
+	rows.AddRow(2, 2, "Yerba")
+	rows.AddRow(3, 1, "Mate")
+	rows.AddRow(4, 3, "Bombilla")
+	mock.ExpectQuery("SELECT DISTINCT sales.invoice_id").WithArgs(BasketMinDatetime, BasketMaxDatetime).WillReturnRows(rows)
+
+	// Act
+	result, err := saleService.GetProductAssociations(context.Background(), BasketConfig{MinSupport: 0.5})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 2, len(result), "len of result should be equal to 2")
+	assert.Equal(t, 2, result[0].Antecedent[0].Id, "best rule should be yerba => mate")
+	assert.Equal(t, 1, result[0].Consequent.Id, "best rule should be yerba => mate")
+	assert.Equal(t, 2, result[0].Invoices, "rule should appear in 2 invoices")
+	assert.InDelta(t, 0.5, result[0].Support, 0.0001, "support should be 0.5")
+	assert.InDelta(t, 1.0, result[0].Confidence, 0.0001, "confidence should be 1")
+	assert.InDelta(t, 4.0/3.0, result[0].Lift, 0.0001, "lift should be 4/3")
+	assert.InDelta(t, 2.0/3.0, result[1].Confidence, 0.0001, "confidence of mate => yerba should be 2/3")
+}
+
+func TestServiceSaleGetProductAssociationsThreeItems(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	rows := mock.NewRows([]string{"invoice_id", "product_id", "description"})
+	for invoiceId := 1; invoiceId <= 2; invoiceId++ {
+		rows.AddRow(invoiceId, 1, "Mate")
+		rows.AddRow(invoiceId, 2, "Yerba")
+		rows.AddRow(invoiceId, 3, "Bombilla")
+	}
+	mock.ExpectQuery("SELECT DISTINCT sales.invoice_id").WithArgs("2021-12-01 00:00:00", "2021-12-31 23:59:59").WillReturnRows(rows)
+
+	// Act
+	result, err := saleService.GetProductAssociations(context.Background(), BasketConfig{
+		MaxItems: 3,
+		From:     "2021-12-01 00:00:00",
+		To:       "2021-12-31 23:59:59",
+	})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 9, len(result), "len of result should be 6 pair rules and 3 triple rules")
+}
+
+func TestServiceSaleGetProductAssociationsDefaultMinSupport(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	rows := mock.NewRows([]string{"invoice_id", "product_id", "description"})
+	rows.AddRow(1, 1, "Mate")
+	rows.AddRow(1, 2, "Yerba")
+	for invoiceId := 2; invoiceId <= 101; invoiceId++ {
+		rows.AddRow(invoiceId, 3, "Bombilla")
+	}
+	mock.ExpectQuery("SELECT DISTINCT sales.invoice_id").WithArgs(BasketMinDatetime, BasketMaxDatetime).WillReturnRows(rows)
+
+	// Act
+	result, err := saleService.GetProductAssociations(context.Background(), BasketConfig{})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Empty(t, result, "a pair in 1 of 101 invoices should not be frequent")
+}
+
+func TestServiceSaleGetProductAssociationsInvalidMaxItems(t *testing.T) {
+	// Arrange
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	// Act
+	result, err := saleService.GetProductAssociations(context.Background(), BasketConfig{MaxItems: 10})
+
+	// Assert
+	assert.Equal(t, ErrorBasketInvalidMaxItems, err, "error should be invalid max items")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceSaleGetProductAssociationsError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	mock.ExpectQuery("SELECT DISTINCT sales.invoice_id").WillReturnError(errors.New("error"))
+
+	// Act
+	result, err := saleService.GetProductAssociations(context.Background(), BasketConfig{})
+
+	// Assert
+	assert.Error(t, err, "should exists an error")
+	assert.Nil(t, result, "result should be nil")
+}
+
+func TestServiceSaleStoreBulkSkipsHeld(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	heldRows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"})
+	heldRows.AddRow(2, 72, 53, 44618, "quantity outlier")
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held").WillReturnRows(heldRows)
+	for i := 1; i <= 1000; i++ {
+		if i != 2 {
+			mock.ExpectQuery(GetSaleQuery).WithArgs(i).WillReturnError(ErrorSaleNotFound)
+		}
+	}
+
+	mock.ExpectPrepare("INSERT INTO sales")
+	mock.ExpectExec("INSERT INTO sales").WillReturnResult(sqlmock.NewResult(999, 999))
+
+	// Act
+	result, err := saleService.StoreBulk(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, 999, len(result), "held sale should not be stored")
+}
+
+func TestServiceSaleStoreBulkWithAnomaliesHold(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held").WillReturnRows(mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"}))
+	for i := 1; i <= 1000; i++ {
+		mock.ExpectQuery(GetSaleQuery).WithArgs(i).WillReturnError(ErrorSaleNotFound)
+	}
+	mock.ExpectQuery(GetAllSalesQuery).WillReturnRows(mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"}))
+
+	mock.ExpectPrepare("INSERT INTO sales_held")
+	mock.ExpectExec("INSERT INTO sales_held").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("INSERT INTO sales")
+	mock.ExpectExec("INSERT INTO sales").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	result, anomalies, err := saleService.StoreBulkWithAnomalies(context.Background(), outlier.Config{}, true)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.True(t, len(anomalies) > 0, "sales file should have anomalies")
+	saleAnomaly := anomalies[indexOfAnomaly(anomalies, 2)]
+	assert.Equal(t, 2, saleAnomaly.Id, "sale 2 quantity should be an anomaly")
+	assert.Equal(t, 53, saleAnomaly.ProductId, "anomaly should have the sale product")
+	assert.Equal(t, outlier.MethodIQR, saleAnomaly.Method, "default method should be iqr")
+	assert.True(t, saleAnomaly.Value > saleAnomaly.Upper, "quantity should be over the upper bound")
+	assert.Equal(t, 1000-len(anomalies), len(result), "anomalies should be held instead of stored")
+	assert.Nil(t, mock.ExpectationsWereMet(), "held sales should be stored")
+}
+
+func TestServiceSaleStoreBulkWithAnomaliesInvalidMethod(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held").WillReturnRows(mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"}))
+	for i := 1; i <= 1000; i++ {
+		mock.ExpectQuery(GetSaleQuery).WithArgs(i).WillReturnError(ErrorSaleNotFound)
+	}
+	mock.ExpectQuery(GetAllSalesQuery).WillReturnRows(mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"}))
+
+	// Act
+	result, anomalies, err := saleService.StoreBulkWithAnomalies(context.Background(), outlier.Config{Method: "mad"}, true)
+
+	// Assert
+	assert.Equal(t, outlier.ErrorInvalidMethod, err, "error should be invalid method")
+	assert.Nil(t, result, "result should be nil")
+	assert.Nil(t, anomalies, "anomalies should be nil")
+}
+
+func TestServiceSaleReleaseHeld(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	rows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"})
+	rows.AddRow(2, 72, 53, 44618, "quantity outlier")
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held WHERE id").WithArgs(2).WillReturnRows(rows)
+	mock.ExpectQuery(GetSaleQuery).WithArgs(2).WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO sales").WithArgs(2, 72, 53, 44618.0).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("DELETE FROM sales_held").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Act
+	result, err := saleService.ReleaseHeld(context.Background(), 2)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, domain.Sale{Id: 2, Invoice_id: 72, Product_id: 53, Quantity: 44618}, result, "result should be the released sale")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the sale should be stored and deleted from the held ones in a transaction")
+}
+
+func TestServiceSaleReleaseHeldRollback(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	rows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"})
+	rows.AddRow(2, 72, 53, 44618, "quantity outlier")
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held WHERE id").WithArgs(2).WillReturnRows(rows)
+	mock.ExpectQuery(GetSaleQuery).WithArgs(2).WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO sales").WithArgs(2, 72, 53, 44618.0).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("DELETE FROM sales_held").WithArgs(2).WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	// Act
+	result, err := saleService.ReleaseHeld(context.Background(), 2)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorSaleExecDeleteStatement, "error should be delete failed")
+	assert.Equal(t, domain.Sale{}, result, "result should be empty")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the stored sale should be rolled back")
+}
+
+func TestServiceSaleReleaseHeldGetError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+	expectedError := errors.New("connection lost")
+
+	rows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"})
+	rows.AddRow(2, 72, 53, 44618, "quantity outlier")
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held WHERE id").WithArgs(2).WillReturnRows(rows)
+	mock.ExpectQuery(GetSaleQuery).WithArgs(2).WillReturnError(expectedError)
+
+	// Act
+	result, err := saleService.ReleaseHeld(context.Background(), 2)
+
+	// Assert
+	assert.ErrorIs(t, err, expectedError, "error should be the one of the query")
+	assert.Equal(t, domain.Sale{}, result, "result should be empty")
+	assert.Nil(t, mock.ExpectationsWereMet(), "the sale should not be stored")
+}
+
+func TestServiceSaleReleaseHeldConflict(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	heldRows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity", "reason"})
+	heldRows.AddRow(2, 72, 53, 44618, "quantity outlier")
+	mock.ExpectQuery("SELECT id, invoice_id, product_id, quantity, reason FROM sales_held WHERE id").WithArgs(2).WillReturnRows(heldRows)
+	saleRows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"})
+	saleRows.AddRow(2, 72, 53, 3)
+	mock.ExpectQuery(GetSaleQuery).WithArgs(2).WillReturnRows(saleRows)
+
+	// Act
+	result, err := saleService.ReleaseHeld(context.Background(), 2)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorSaleAlreadyStored, "error should be already stored")
+	assert.Equal(t, web.KindConflict, web.AsAppError(err).Kind, "error should be a conflict")
+	assert.Equal(t, domain.Sale{}, result, "result should be empty")
+}
+
+func TestServiceSaleRejectHeldNotFound(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+
+	mock.ExpectPrepare("DELETE FROM sales_held")
+	mock.ExpectExec("DELETE FROM sales_held").WithArgs(99999).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	err = saleService.RejectHeld(context.Background(), 99999)
+
+	// Assert
+	assert.Equal(t, ErrorSaleNotFound, err, "error should be not found")
+}
+
+func indexOfAnomaly(anomalies []domain.AnomalyDTO, id int) int {
+	for i, anomaly := range anomalies {
+		if anomaly.Id == id {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func TestServiceSaleListCursors(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+	codec := web.NewCursorCodec([]byte("secret"))
+
+	rows := mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"})
+	rows.AddRow(1, 1, 1, 1.0)
+	rows.AddRow(2, 1, 2, 1.0)
+	rows.AddRow(3, 1, 3, 1.0)
+	mock.ExpectQuery(`SELECT id, invoice_id, product_id, quantity FROM sales  ORDER BY id ASC LIMIT 3`).WillReturnRows(rows)
+	rows = mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"})
+	rows.AddRow(3, 1, 3, 1.0)
+	mock.ExpectQuery(`FROM sales WHERE \(\(id > \?\)\) ORDER BY id ASC LIMIT 3`).WithArgs("2").WillReturnRows(rows)
+	rows = mock.NewRows([]string{"id", "invoice_id", "product_id", "quantity"})
+	rows.AddRow(2, 1, 2, 1.0)
+	rows.AddRow(1, 1, 1, 1.0)
+	mock.ExpectQuery(`FROM sales WHERE \(\(id < \?\)\) ORDER BY id DESC LIMIT 3`).WithArgs("3").WillReturnRows(rows)
+
+	// Act
+	firstPage, firstCursors, err := saleService.List(context.Background(), web.QuerySpec{Page: 1, Size: 2}, codec)
+	assert.Nil(t, err, "error should be nil")
+	secondPage, secondCursors, err := saleService.List(context.Background(), web.QuerySpec{Size: 2, Cursor: firstCursors.Next}, codec)
+	assert.Nil(t, err, "error should be nil")
+	previousPage, previousCursors, err := saleService.List(context.Background(), web.QuerySpec{Size: 2, Cursor: secondCursors.Prev}, codec)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []int{1, 2}, []int{firstPage[0].Id, firstPage[1].Id}, "first page should have the first sales")
+	assert.Empty(t, firstCursors.Prev, "first page should not have prev cursor")
+	assert.Equal(t, 3, secondPage[0].Id, "second page should have the last sale")
+	assert.Empty(t, secondCursors.Next, "last page should not have next cursor")
+	assert.Equal(t, firstPage, previousPage, "previous page should be in listing order")
+	assert.NotEmpty(t, previousCursors.Next, "previous page should have next cursor")
+	assert.Empty(t, previousCursors.Prev, "previous page should not have prev cursor")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestServiceSaleListTamperedCursor(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	saleRepository := NewSaleRepository(db)
+	saleService := NewSaleService(saleRepository)
+	cursor := web.NewCursorCodec([]byte("other secret")).Encode(web.Cursor{Sort: "id", Values: []string{"2"}})
+
+	// Act
+	result, _, err := saleService.List(context.Background(), web.QuerySpec{Size: 2, Cursor: cursor}, web.NewCursorCodec([]byte("secret")))
+
+	// Assert
+	assert.Equal(t, web.ErrorQueryInvalidCursor, err, "error should be invalid cursor")
+	assert.Nil(t, result, "result should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "no query should run")
+}
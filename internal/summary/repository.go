@@ -0,0 +1,274 @@
+package summary
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+var (
+	// Db queries & statements
+	GetInvoicesDaysQuery            = "SELECT DISTINCT DATE_FORMAT(invoices.datetime, '%Y-%m-%d') as invoice_day FROM invoices WHERE invoices.id IN replace_with_invoices_ids ORDER BY invoice_day"
+	GetProductsRevenueQuery         = "SELECT products.id, products.description, ROUND(COALESCE(SUM(daily_product_revenue.revenue), 0), 2) as revenue FROM products LEFT JOIN daily_product_revenue ON daily_product_revenue.product_id = products.id GROUP BY products.id, products.description ORDER BY revenue DESC, products.id ASC;"
+	GetProductsMostSelledQuery      = "SELECT SUM(daily_product_revenue.sales) as count_total, products.description, ROUND(SUM(daily_product_revenue.sales) * products.price, 1) as total FROM products INNER JOIN daily_product_revenue ON daily_product_revenue.product_id = products.id GROUP BY products.id ORDER BY count_total DESC LIMIT 5;"
+	GetTotalByConditionQuery        = "SELECT situation, ROUND(SUM(revenue), 2) FROM daily_situation_revenue GROUP BY situation;"
+	GetDailyRevenueQuery            = "SELECT DATE_FORMAT(day, '%Y-%m-%d'), ROUND(SUM(revenue), 2) FROM daily_situation_revenue GROUP BY day ORDER BY day"
+	DeleteProductRevenueStatement   = "DELETE FROM daily_product_revenue replace_with_days_filter"
+	DeleteSituationRevenueStatement = "DELETE FROM daily_situation_revenue replace_with_days_filter"
+	StoreProductRevenueStatement    = "INSERT INTO daily_product_revenue (day, product_id, quantity, revenue, sales) SELECT DATE(invoices.datetime), sales.product_id, SUM(sales.quantity), SUM(products.price * sales.quantity), COUNT(sales.id) FROM sales INNER JOIN invoices ON invoices.id = sales.invoice_id INNER JOIN products ON products.id = sales.product_id replace_with_days_filter GROUP BY DATE(invoices.datetime), sales.product_id"
+	StoreSituationRevenueStatement  = "INSERT INTO daily_situation_revenue (day, situation, revenue, invoices) SELECT DATE(invoices.datetime), customers.situation, SUM(invoices.total), COUNT(invoices.id) FROM invoices INNER JOIN customers ON customers.id = invoices.customer_id replace_with_days_filter GROUP BY DATE(invoices.datetime), customers.situation"
+
+	// Errors
+	ErrorSummaryBeginRefresh         = web.NewError(web.KindInternal, "summary_refresh_begin_failed", "can not begin summaries refresh")
+	ErrorSummaryExecRefreshStatement = web.NewError(web.KindInternal, "summary_refresh_failed", "error executing summaries refresh statement")
+	ErrorSummaryCommitRefresh        = web.NewError(web.KindInternal, "summary_refresh_commit_failed", "can not commit summaries refresh")
+
+	// refreshStatements run in order, with the days filter of their table
+	refreshStatements = []struct {
+		statement  string
+		daysFilter string
+	}{
+		{DeleteProductRevenueStatement, "WHERE day IN (%s)"},
+		{DeleteSituationRevenueStatement, "WHERE day IN (%s)"},
+		{StoreProductRevenueStatement, "WHERE DATE(invoices.datetime) IN (%s)"},
+		{StoreSituationRevenueStatement, "WHERE DATE(invoices.datetime) IN (%s)"},
+	}
+)
+
+type SummaryRepository interface {
+	GetInvoicesDays(ctx context.Context, invoicesIds []int) ([]string, error)
+	Refresh(ctx context.Context, days []string) error
+	Rebuild(ctx context.Context) error
+	ProductsRevenue(ctx context.Context) ([]domain.ProductRevenueDTO, error)
+	ProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error)
+	TotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error)
+	DailyRevenue(ctx context.Context) ([]domain.RevenuePointDTO, error)
+}
+
+func NewSummaryRepository(db *sql.DB) SummaryRepository {
+	return NewSummaryRepositoryWithDialect(db, storage.MySQL)
+}
+
+// NewSummaryRepositoryWithDialect returns a SummaryRepository running its queries
+// in dialect.
+func NewSummaryRepositoryWithDialect(db *sql.DB, dialect storage.Dialect) SummaryRepository {
+	return &summaryRepository{
+		db: storage.New(db, dialect),
+	}
+}
+
+type summaryRepository struct {
+	db *storage.DB
+}
+
+// GetInvoicesDays returns the days (YYYY-MM-DD) of the given invoices.
+func (r *summaryRepository) GetInvoicesDays(ctx context.Context, invoicesIds []int) ([]string, error) {
+	// replace replace_with_invoices_ids with (id1, id2, id3) in query
+	query := GetInvoicesDaysQuery
+	query = strings.ReplaceAll(query, "replace_with_invoices_ids", "("+strings.Trim(strings.Join(strings.Fields(fmt.Sprint(invoicesIds)), ","), "[]")+")")
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var days []string
+
+	for rows.Next() {
+		var day string
+		err = rows.Scan(&day)
+		if err != nil {
+			return nil, err
+		}
+
+		days = append(days, day)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+// Refresh recalculates the summaries of the given days from the sales and
+// invoices, so it is safe to call it again for a day already summarized.
+func (r *summaryRepository) Refresh(ctx context.Context, days []string) error {
+	if len(days) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(days)), ", ")
+	args := make([]interface{}, 0, len(days))
+	for _, day := range days {
+		args = append(args, day)
+	}
+
+	return r.refresh(ctx, placeholders, args)
+}
+
+// Rebuild recalculates the summaries of every day.
+func (r *summaryRepository) Rebuild(ctx context.Context) error {
+	return r.refresh(ctx, "", nil)
+}
+
+// refresh deletes and recalculates the summaries in a single transaction, so
+// reports never read a day half refreshed. Every day is refreshed when
+// placeholders is empty. A transaction rolled back by a deadlock is run
+// again whole.
+func (r *summaryRepository) refresh(ctx context.Context, placeholders string, args []interface{}) error {
+	return r.db.Retry(ctx, func() error {
+		return r.refreshTx(ctx, placeholders, args)
+	})
+}
+
+func (r *summaryRepository) refreshTx(ctx context.Context, placeholders string, args []interface{}) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return ErrorSummaryBeginRefresh.Wrap(err)
+	}
+
+	defer tx.Rollback()
+
+	for _, refreshStatement := range refreshStatements {
+		filter := ""
+		if placeholders != "" {
+			filter = fmt.Sprintf(refreshStatement.daysFilter, placeholders)
+		}
+		statement := strings.TrimSpace(strings.ReplaceAll(refreshStatement.statement, "replace_with_days_filter", filter))
+
+		_, err = tx.ExecContext(ctx, statement, args...)
+
+		if err != nil {
+			return ErrorSummaryExecRefreshStatement.Wrap(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrorSummaryCommitRefresh.Wrap(err)
+	}
+
+	return nil
+}
+
+func (r *summaryRepository) ProductsRevenue(ctx context.Context) ([]domain.ProductRevenueDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetProductsRevenueQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var productsRevenue []domain.ProductRevenueDTO
+
+	for rows.Next() {
+		var productRevenue domain.ProductRevenueDTO
+		err = rows.Scan(&productRevenue.Id, &productRevenue.Description, &productRevenue.Revenue)
+		if err != nil {
+			return nil, err
+		}
+
+		productsRevenue = append(productsRevenue, productRevenue)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return productsRevenue, nil
+}
+
+func (r *summaryRepository) ProductsMostSelled(ctx context.Context) ([]domain.ProductMostSelledDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetProductsMostSelledQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var productsMostSelled []domain.ProductMostSelledDTO
+
+	for rows.Next() {
+		var productMostSelled domain.ProductMostSelledDTO
+		var count int
+		err = rows.Scan(&count, &productMostSelled.Description, &productMostSelled.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		productsMostSelled = append(productsMostSelled, productMostSelled)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return productsMostSelled, nil
+}
+
+func (r *summaryRepository) TotalByCondition(ctx context.Context) ([]domain.CustomerTotalByConditionDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetTotalByConditionQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var totalsByCondition []domain.CustomerTotalByConditionDTO
+
+	for rows.Next() {
+		var totalByCondition domain.CustomerTotalByConditionDTO
+		err = rows.Scan(&totalByCondition.Situation, &totalByCondition.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		totalsByCondition = append(totalsByCondition, totalByCondition)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totalsByCondition, nil
+}
+
+func (r *summaryRepository) DailyRevenue(ctx context.Context) ([]domain.RevenuePointDTO, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, GetDailyRevenueQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var dailyRevenue []domain.RevenuePointDTO
+
+	for rows.Next() {
+		var revenuePoint domain.RevenuePointDTO
+		err = rows.Scan(&revenuePoint.Date, &revenuePoint.Revenue)
+		if err != nil {
+			return nil, err
+		}
+
+		dailyRevenue = append(dailyRevenue, revenuePoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dailyRevenue, nil
+}
@@ -0,0 +1,103 @@
+package summary
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/matias-ziliotto/HackthonGo/internal/domain"
+	"github.com/matias-ziliotto/HackthonGo/pkg/database/sql"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// dialect is the one of the DB of the config, the repositories run on
+var dialect = storage.MySQL
+
+func TestMain(m *testing.M) {
+	configDialect, remove, err := sql.RegisterConfigTxDb([]string{"-env", "../../cmd/server/.env"})
+	if err == nil {
+		dialect = configDialect
+	} else {
+		log.Printf("repository tests need a db: %v", err)
+	}
+
+	code := m.Run()
+	remove()
+	os.Exit(code)
+}
+
+// summaryData can not be stored with the other repositories, they import
+// this package
+var summaryData = []string{
+	"INSERT INTO customers (id, first_name, last_name, situation) VALUES (60000, 'Coki', 'Argento', 'Activo')",
+	"INSERT INTO products (id, description, price) VALUES (60000, 'Description de producto', 100.5)",
+	"INSERT INTO invoices (id, customer_id, datetime, total) VALUES (60000, 60000, '2031-01-06 11:11:11', 201), (60001, 60000, '2031-01-07 11:11:11', 100.5)",
+	"INSERT INTO sales (id, invoice_id, product_id, quantity) VALUES (60000, 60000, 60000, 2), (60001, 60001, 60000, 1)",
+}
+
+func TestSummaryGetInvoicesDays(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSummaryRepositoryWithDialect(db, dialect)
+	for _, statement := range summaryData {
+		_, err := db.ExecContext(ctx, statement)
+		assert.Nil(t, err, "error should be nil")
+	}
+
+	// Act
+	result, err := repository.GetInvoicesDays(ctx, []int{60000, 60001})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"2031-01-06", "2031-01-07"}, result, "result should be the days of the invoices")
+}
+
+func TestSummaryRefresh(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSummaryRepositoryWithDialect(db, dialect)
+	for _, statement := range summaryData {
+		_, err := db.ExecContext(ctx, statement)
+		assert.Nil(t, err, "error should be nil")
+	}
+
+	// Act
+	err := repository.Refresh(ctx, []string{"2031-01-06"})
+	assert.Nil(t, err, "error should be nil")
+	dailyRevenue, err := repository.DailyRevenue(ctx)
+	assert.Nil(t, err, "error should be nil")
+	productsRevenue, err := repository.ProductsRevenue(ctx)
+	assert.Nil(t, err, "error should be nil")
+
+	// Assert
+	assert.Contains(t, dailyRevenue, domain.RevenuePointDTO{Date: "2031-01-06", Revenue: 201}, "refreshed day should be summarized")
+	assert.NotContains(t, dailyRevenue, domain.RevenuePointDTO{Date: "2031-01-07", Revenue: 100.5}, "other days should not be summarized")
+	assert.Contains(t, productsRevenue, domain.ProductRevenueDTO{Id: 60000, Description: "Description de producto", Revenue: 201}, "product revenue should come from the refreshed day")
+}
+
+func TestSummaryRebuild(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	db := sql.InitTxSqlDbOrSkip(t)
+	repository := NewSummaryRepositoryWithDialect(db, dialect)
+	for _, statement := range summaryData {
+		_, err := db.ExecContext(ctx, statement)
+		assert.Nil(t, err, "error should be nil")
+	}
+
+	// Act
+	err := repository.Rebuild(ctx)
+	assert.Nil(t, err, "error should be nil")
+	productsRevenue, err := repository.ProductsRevenue(ctx)
+	assert.Nil(t, err, "error should be nil")
+	totalsByCondition, err := repository.TotalByCondition(ctx)
+	assert.Nil(t, err, "error should be nil")
+
+	// Assert
+	assert.Contains(t, productsRevenue, domain.ProductRevenueDTO{Id: 60000, Description: "Description de producto", Revenue: 301.5}, "product revenue should have every day")
+	assert.NotEmpty(t, totalsByCondition, "totals by condition should not be empty")
+}
@@ -0,0 +1,55 @@
+package summary
+
+import (
+	"context"
+)
+
+type SummaryService interface {
+	RefreshInvoices(ctx context.Context, invoicesIds []int) ([]string, error)
+	Rebuild(ctx context.Context) error
+}
+
+func NewSummaryService(sr SummaryRepository) SummaryService {
+	return &summaryService{
+		repository: sr,
+	}
+}
+
+type summaryService struct {
+	repository SummaryRepository
+}
+
+// RefreshInvoices refreshes the summaries of the days of the given invoices,
+// the ones whose sales or totals changed, and returns the refreshed days.
+func (s *summaryService) RefreshInvoices(ctx context.Context, invoicesIds []int) ([]string, error) {
+	if len(invoicesIds) == 0 {
+		return nil, nil
+	}
+
+	uniqueIds := make([]int, 0, len(invoicesIds))
+	seen := map[int]bool{}
+	for _, invoiceId := range invoicesIds {
+		if !seen[invoiceId] {
+			seen[invoiceId] = true
+			uniqueIds = append(uniqueIds, invoiceId)
+		}
+	}
+
+	days, err := s.repository.GetInvoicesDays(ctx, uniqueIds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repository.Refresh(ctx, days)
+	if err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+// Rebuild recalculates every summary, needed once for data loaded before the
+// summaries existed.
+func (s *summaryService) Rebuild(ctx context.Context) error {
+	return s.repository.Rebuild(ctx)
+}
@@ -0,0 +1,98 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceSummaryRefreshInvoices(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	summaryRepository := NewSummaryRepository(db)
+	summaryService := NewSummaryService(summaryRepository)
+
+	rows := mock.NewRows([]string{"invoice_day"})
+	rows.AddRow("2022-01-06")
+	rows.AddRow("2022-01-07")
+	mock.ExpectQuery("SELECT DISTINCT DATE_FORMAT").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM daily_product_revenue WHERE day IN").WithArgs("2022-01-06", "2022-01-07").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM daily_situation_revenue WHERE day IN").WithArgs("2022-01-06", "2022-01-07").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO daily_product_revenue").WithArgs("2022-01-06", "2022-01-07").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO daily_situation_revenue").WithArgs("2022-01-06", "2022-01-07").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// Act
+	result, err := summaryService.RefreshInvoices(context.Background(), []int{1000, 1001, 1000})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []string{"2022-01-06", "2022-01-07"}, result, "result should be the days of the invoices")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestServiceSummaryRefreshInvoicesEmpty(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	summaryRepository := NewSummaryRepository(db)
+	summaryService := NewSummaryService(summaryRepository)
+
+	// Act
+	result, err := summaryService.RefreshInvoices(context.Background(), nil)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Empty(t, result, "result should be empty")
+	assert.Nil(t, mock.ExpectationsWereMet(), "no query should run")
+}
+
+func TestServiceSummaryRefreshInvoicesError(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	summaryRepository := NewSummaryRepository(db)
+	summaryService := NewSummaryService(summaryRepository)
+
+	rows := mock.NewRows([]string{"invoice_day"})
+	rows.AddRow("2022-01-06")
+	mock.ExpectQuery("SELECT DISTINCT DATE_FORMAT").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM daily_product_revenue WHERE day IN").WithArgs("2022-01-06").WillReturnError(errors.New("error"))
+	mock.ExpectRollback()
+
+	// Act
+	result, err := summaryService.RefreshInvoices(context.Background(), []int{1000})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorSummaryExecRefreshStatement, "error should be the exec refresh error")
+	assert.Nil(t, result, "result should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "refresh should be rolled back")
+}
+
+func TestServiceSummaryRebuild(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	summaryRepository := NewSummaryRepository(db)
+	summaryService := NewSummaryService(summaryRepository)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM daily_product_revenue$").WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("DELETE FROM daily_situation_revenue$").WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("INSERT INTO daily_product_revenue").WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("INSERT INTO daily_situation_revenue").WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectCommit()
+
+	// Act
+	err = summaryService.Rebuild(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
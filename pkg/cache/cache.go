@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"net/url"
+	"time"
+)
+
+// Cache stores report results by key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	Get(key string) (Entry, bool)
+	Set(key string, entry Entry)
+	Purge()
+}
+
+type Entry struct {
+	Status       int
+	ContentType  string
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+// Key builds a cache key from the report name and its parameters. Parameters
+// are sorted, so the order they were received in does not matter.
+func Key(report string, params url.Values) string {
+	return report + "?" + params.Encode()
+}
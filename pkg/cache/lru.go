@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+const (
+	DefaultLRUCapacity = 128
+)
+
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // front is the most recently used
+}
+
+// NewLRU returns an in-memory Cache that evicts the least recently used entry
+// once it holds capacity entries. DefaultLRUCapacity is used when capacity is
+// not positive.
+func NewLRU(capacity int) Cache {
+	if capacity <= 0 {
+		capacity = DefaultLRUCapacity
+	}
+
+	return &lru{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (l *lru) Get(key string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+
+	l.order.MoveToFront(element)
+
+	return element.Value.(*lruItem).entry, true
+}
+
+func (l *lru) Set(key string, entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (l *lru) Purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.items = map[string]*list.Element{}
+	l.order.Init()
+}
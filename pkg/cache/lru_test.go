@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	lru := NewLRU(2)
+	lru.Set("a", Entry{Body: []byte("a")})
+	lru.Set("b", Entry{Body: []byte("b")})
+	lru.Get("a")
+
+	// Act
+	lru.Set("c", Entry{Body: []byte("c")})
+
+	// Assert
+	_, okA := lru.Get("a")
+	_, okB := lru.Get("b")
+	_, okC := lru.Get("c")
+	assert.True(t, okA, "a was used last, should be kept")
+	assert.False(t, okB, "b was the least recently used, should be evicted")
+	assert.True(t, okC, "c should be stored")
+}
+
+func TestLRUSetReplacesEntry(t *testing.T) {
+	// Arrange
+	lru := NewLRU(2)
+	lru.Set("a", Entry{Body: []byte("old")})
+	lru.Set("b", Entry{Body: []byte("b")})
+
+	// Act
+	lru.Set("a", Entry{Body: []byte("new")})
+	lru.Set("c", Entry{Body: []byte("c")})
+
+	// Assert
+	entry, ok := lru.Get("a")
+	_, okB := lru.Get("b")
+	assert.True(t, ok, "a was set last, should be kept")
+	assert.Equal(t, []byte("new"), entry.Body)
+	assert.False(t, okB, "b should be evicted")
+}
+
+func TestLRUPurge(t *testing.T) {
+	// Arrange
+	cache := NewLRU(0)
+	cache.Set("a", Entry{})
+
+	// Act
+	cache.Purge()
+
+	// Assert
+	_, ok := cache.Get("a")
+	assert.False(t, ok, "entries should be dropped")
+	assert.Equal(t, DefaultLRUCapacity, cache.(*lru).capacity)
+}
+
+func TestKeyIgnoresParamsOrder(t *testing.T) {
+	// Arrange
+	first := url.Values{"limit": {"10"}, "from": {"2021-01-01"}}
+	second := url.Values{"from": {"2021-01-01"}, "limit": {"10"}}
+
+	// Act & Assert
+	assert.Equal(t, Key("report", first), Key("report", second))
+	assert.NotEqual(t, Key("report", first), Key("other", first))
+}
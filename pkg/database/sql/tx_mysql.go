@@ -1,35 +1,103 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"errors"
 	"os"
+	"path/filepath"
+	"sync"
+	"testing"
 
 	"github.com/DATA-DOG/go-txdb"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
+	"github.com/matias-ziliotto/HackthonGo/internal/config"
+	"github.com/matias-ziliotto/HackthonGo/pkg/storage"
 )
 
-func init() {
-	err := godotenv.Load("../../cmd/server/.env")
+var (
+	// Errors
+	ErrorTxDbNotRegistered = errors.New("txdb is not registered, call RegisterTxDb first")
 
+	txDbRegistered sync.Once
+	txDbReady      bool
+)
+
+// RegisterTxDb applies the migrations pending to the DB of driver at dsn and
+// registers the txdb driver on it, every connection of InitTxSqlDb runs in a
+// transaction rolled back on close. Only the first call registers it. It
+// returns the dialect the repositories under test must use.
+func RegisterTxDb(driver string, dsn string) (storage.Dialect, error) {
+	db, err := storage.Open(driver, dsn)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbServer := os.Getenv("DB_HOST")
-	dbHost := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
+	defer db.Close()
+
+	if err = db.Migrate(context.Background()); err != nil {
+		return nil, err
+	}
 
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPassword, dbServer, dbHost, dbName)
+	txDbRegistered.Do(func() {
+		txdb.Register("txdb", driver, dsn)
+		txDbReady = true
+	})
 
-	txdb.Register("txdb", "mysql", connectionString)
+	return db.Dialect, nil
+}
+
+// RegisterConfigTxDb registers txdb on the DB of the config loaded with
+// args or, when it has no DB, on a new embedded SQLite DB. It returns the
+// dialect of the DB and the func removing the SQLite one, to call once the
+// tests are done.
+func RegisterConfigTxDb(args []string) (storage.Dialect, func(), error) {
+	cfg, err := config.Load(args)
+	if err == nil {
+		dialect, err := RegisterTxDb(cfg.DB.Driver, cfg.DB.ConnectionString())
+		return dialect, func() {}, err
+	}
+
+	if !errors.Is(err, config.ErrorConfigMissingDB) {
+		return nil, func() {}, err
+	}
+
+	dir, err := os.MkdirTemp("", "txdb")
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	remove := func() { os.RemoveAll(dir) }
+	db := config.Default().DB
+	db.Driver = storage.DriverSQLite
+	db.Name = filepath.Join(dir, "test.db")
+
+	dialect, err := RegisterTxDb(db.Driver, db.ConnectionString())
+	return dialect, remove, err
+}
+
+// InitTxSqlDbOrSkip is InitTxSqlDb for a test, skipped when txdb is not
+// registered. The DB is closed when the test ends.
+func InitTxSqlDbOrSkip(t testing.TB) *sql.DB {
+	db, err := InitTxSqlDb()
+	if errors.Is(err, ErrorTxDbNotRegistered) {
+		t.Skipf("no db: %v", err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
 }
 
 func InitTxSqlDb() (*sql.DB, error) {
+	if !txDbReady {
+		return nil, ErrorTxDbNotRegistered
+	}
+
 	db, err := sql.Open("txdb", uuid.New().String())
 	if err == nil {
 		return db, db.Ping()
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCheckTimeout = 2 * time.Second
+)
+
+// Check fails when its subsystem can not serve requests, with the reason.
+type Check func(ctx context.Context) error
+
+// Report is the result of every readiness check, in the order they were
+// registered.
+type Report struct {
+	Ready  bool          `json:"ready"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Name       string `json:"name"`
+	Ready      bool   `json:"ready"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Readiness holds the checks the subsystems register, the service is ready
+// while every one passes. Safe for concurrent use.
+type Readiness struct {
+	mu      sync.RWMutex
+	checks  []namedCheck
+	timeout time.Duration
+}
+
+// NewReadiness returns a Readiness whose checks fail when they take longer
+// than timeout, DefaultCheckTimeout when it is not positive.
+func NewReadiness(timeout time.Duration) *Readiness {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	return &Readiness{
+		timeout: timeout,
+	}
+}
+
+// Register adds the check of the subsystem name.
+func (r *Readiness) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Check runs every check at once and reports them.
+func (r *Readiness) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Ready: true, Checks: make([]CheckResult, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check namedCheck) {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		report.Ready = report.Ready && result.Ready
+	}
+
+	return report
+}
+
+func (r *Readiness) run(ctx context.Context, check namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.check(ctx)
+	result := CheckResult{
+		Name:       check.name,
+		Ready:      err == nil,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
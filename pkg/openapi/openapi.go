@@ -0,0 +1,384 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/pkg/web"
+)
+
+const (
+	Version = "3.0.3"
+
+	// Param types
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+var (
+	//go:embed redoc.html
+	redocPage []byte
+
+	// pathParam matches the gin path params, :id
+	pathParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+)
+
+// Document is the subset of an OpenAPI 3 document the API needs.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	OperationId string              `json:"operationId,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Param is a query param of a route.
+type Param struct {
+	Name        string
+	Type        string // TypeString when empty
+	Description string
+	Required    bool
+}
+
+// Route describes a route registered in gin, the document is built from
+// both.
+type Route struct {
+	Summary   string
+	Tags      []string
+	Query     []Param
+	Data      interface{} // value of the type of the data of the success response, none when nil
+	Status    int         // status of the success response, http.StatusOK when 0
+	Paginated bool        // the success response has pagination meta and links
+	CSV       bool        // the success response can also be text/csv
+	Raw       bool        // the success response is Data itself instead of a web.SuccessResponse
+	HTML      bool        // the success response is a text/html page
+	Errors    []int       // statuses answered with web.ErrorResponse or web.Problem besides 500 and 504
+}
+
+// Registry keeps the route descriptions and the document built from them.
+type Registry struct {
+	info     Info
+	mu       sync.RWMutex
+	routes   map[string]Route
+	document Document
+}
+
+func NewRegistry(info Info) *Registry {
+	return &Registry{
+		info:   info,
+		routes: map[string]Route{},
+	}
+}
+
+// Describe documents the route registered in gin for method and path.
+func (r *Registry) Describe(method string, path string, route Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes[method+" "+path] = route
+}
+
+// Build builds the document from the routes registered in gin and returns
+// the ones without description, which are still listed with just their
+// responses.
+func (r *Registry) Build(routes gin.RoutesInfo) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	builder := schemaBuilder{schemas: map[string]*Schema{}}
+	errorSchema := builder.schema(reflect.TypeOf(web.ErrorResponse{}))
+	problemSchema := builder.schema(reflect.TypeOf(web.Problem{}))
+	pageMetaSchema := builder.schema(reflect.TypeOf(web.PageMeta{}))
+	pageLinksSchema := builder.schema(reflect.TypeOf(web.PageLinks{}))
+
+	document := Document{
+		OpenAPI:    Version,
+		Info:       r.info,
+		Paths:      map[string]map[string]Operation{},
+		Components: Components{Schemas: builder.schemas},
+	}
+	var undocumented []string
+
+	for _, routeInfo := range routes {
+		route, ok := r.routes[routeInfo.Method+" "+routeInfo.Path]
+		if !ok {
+			undocumented = append(undocumented, routeInfo.Method+" "+routeInfo.Path)
+		}
+
+		operation := Operation{
+			Summary:     route.Summary,
+			Tags:        route.Tags,
+			OperationId: operationId(routeInfo.Method, routeInfo.Path),
+			Responses:   map[string]Response{},
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(routeInfo.Path, -1) {
+			operation.Parameters = append(operation.Parameters, Parameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: TypeString},
+			})
+		}
+
+		for _, param := range route.Query {
+			paramType := param.Type
+			if paramType == "" {
+				paramType = TypeString
+			}
+
+			operation.Parameters = append(operation.Parameters, Parameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Required:    param.Required,
+				Schema:      &Schema{Type: paramType},
+			})
+		}
+
+		envelope := &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"data": {}},
+			Required:   []string{"data"},
+		}
+		if route.Data != nil {
+			envelope.Properties["data"] = builder.schema(reflect.TypeOf(route.Data))
+		}
+		if route.Paginated {
+			envelope.Properties["meta"] = pageMetaSchema
+			envelope.Properties["links"] = pageLinksSchema
+		}
+
+		success := Response{
+			Description: http.StatusText(successStatus(route)),
+			Content:     map[string]MediaType{gin.MIMEJSON: {Schema: envelope}},
+		}
+		if route.Raw {
+			success.Content[gin.MIMEJSON] = MediaType{Schema: envelope.Properties["data"]}
+		}
+		if route.HTML {
+			success.Content = map[string]MediaType{gin.MIMEHTML: {Schema: &Schema{Type: TypeString}}}
+		}
+		if route.CSV {
+			success.Content["text/csv"] = MediaType{Schema: &Schema{Type: TypeString}}
+		}
+		operation.Responses[strconv.Itoa(successStatus(route))] = success
+
+		// Every route can fail, or take longer than its deadline
+		errorStatuses := append([]int{http.StatusInternalServerError, http.StatusGatewayTimeout}, route.Errors...)
+		for _, status := range errorStatuses {
+			operation.Responses[strconv.Itoa(status)] = Response{
+				Description: http.StatusText(status),
+				Content: map[string]MediaType{
+					gin.MIMEJSON:        {Schema: errorSchema},
+					web.MIMEProblemJSON: {Schema: problemSchema},
+				},
+			}
+		}
+
+		path := pathParam.ReplaceAllString(routeInfo.Path, "{$1}")
+		if document.Paths[path] == nil {
+			document.Paths[path] = map[string]Operation{}
+		}
+		document.Paths[path][strings.ToLower(routeInfo.Method)] = operation
+	}
+
+	sort.Strings(undocumented)
+	r.document = document
+
+	return undocumented
+}
+
+func (r *Registry) Document() Document {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.document
+}
+
+// JSONHandler serves the document as JSON.
+func (r *Registry) JSONHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Document())
+	}
+}
+
+// UIHandler serves a Redoc page rendering the document served at specURL.
+func (r *Registry) UIHandler(specURL string) gin.HandlerFunc {
+	page := strings.ReplaceAll(string(redocPage), "{{spec_url}}", specURL)
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+func successStatus(route Route) int {
+	if route.Status == 0 {
+		return http.StatusOK
+	}
+
+	return route.Status
+}
+
+// operationId turns GET /sales/held/:id into getSalesHeldId.
+func operationId(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == ':' || r == '_' || r == '.' }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+
+	return id
+}
+
+// schemaBuilder turns Go types into schemas, adding the structs to schemas
+// and referencing them.
+type schemaBuilder struct {
+	schemas map[string]*Schema
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		schema := b.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+
+		return schema
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: TypeString, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: TypeInteger}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: TypeNumber}
+	case reflect.String:
+		return &Schema{Type: TypeString}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.structSchema(t)
+	}
+
+	// interface{} and the like can be anything
+	return &Schema{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+	if _, ok := b.schemas[t.Name()]; ok {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// Registered before the fields so recursive types end
+	b.schemas[t.Name()] = schema
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, options := field.Name, ""
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			name = tag
+			if comma := strings.Index(tag, ","); comma >= 0 {
+				name, options = tag[:comma], tag[comma:]
+			}
+		}
+
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without name have their fields inlined
+		if field.Anonymous && name == field.Name && field.Type.Kind() == reflect.Struct {
+			embedded := b.schemas[field.Type.Name()]
+			if embedded == nil {
+				b.structSchema(field.Type)
+				embedded = b.schemas[field.Type.Name()]
+			}
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return ref
+}
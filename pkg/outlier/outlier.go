@@ -0,0 +1,179 @@
+package outlier
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	// Methods
+	MethodZScore = "zscore"
+	MethodIQR    = "iqr"
+
+	DefaultZScoreThreshold = 3
+	DefaultIQRThreshold    = 1.5
+	DefaultMinGroupSize    = 5
+)
+
+var (
+	// Errors
+	ErrorInvalidMethod    = errors.New("method must be zscore or iqr")
+	ErrorInvalidThreshold = errors.New("threshold and min group size can not be negative")
+)
+
+type Config struct {
+	Method       string  // MethodIQR when empty
+	Threshold    float64 // standard deviations for zscore, IQRs beyond the quartiles for iqr
+	PerGroup     bool    // use a baseline per group instead of the whole population
+	MinGroupSize int     // groups with less observations use the whole population as baseline
+}
+
+func (c Config) WithDefaults() (Config, error) {
+	if c.Method == "" {
+		c.Method = MethodIQR
+	}
+
+	if c.Method != MethodZScore && c.Method != MethodIQR {
+		return Config{}, ErrorInvalidMethod
+	}
+
+	if c.Threshold < 0 || c.MinGroupSize < 0 {
+		return Config{}, ErrorInvalidThreshold
+	}
+
+	if c.Threshold == 0 {
+		c.Threshold = DefaultIQRThreshold
+		if c.Method == MethodZScore {
+			c.Threshold = DefaultZScoreThreshold
+		}
+	}
+
+	if c.MinGroupSize == 0 {
+		c.MinGroupSize = DefaultMinGroupSize
+	}
+
+	return c, nil
+}
+
+type Observation struct {
+	Id    int
+	Group int
+	Value float64
+}
+
+type Outlier struct {
+	Observation
+	Score float64 // standard deviations from the mean for zscore, IQRs beyond the quartiles for iqr
+	Lower float64
+	Upper float64
+}
+
+type baseline struct {
+	lower  float64
+	upper  float64
+	center float64
+	spread float64
+}
+
+// Detect returns the observations outside the baseline bounds of their group,
+// or of the whole population when config.PerGroup is false. A baseline
+// without spread, like one where every value is the same, flags nothing.
+func Detect(observations []Observation, config Config) ([]Outlier, error) {
+	config, err := config.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(observations))
+	groups := map[int][]float64{}
+	for i, observation := range observations {
+		values[i] = observation.Value
+		groups[observation.Group] = append(groups[observation.Group], observation.Value)
+	}
+
+	population := newBaseline(values, config)
+	baselines := map[int]baseline{}
+	for group, groupValues := range groups {
+		baselines[group] = population
+		if config.PerGroup && len(groupValues) >= config.MinGroupSize {
+			baselines[group] = newBaseline(groupValues, config)
+		}
+	}
+
+	var outliers []Outlier
+	for _, observation := range observations {
+		groupBaseline := baselines[observation.Group]
+		if groupBaseline.spread == 0 || (observation.Value >= groupBaseline.lower && observation.Value <= groupBaseline.upper) {
+			continue
+		}
+
+		score := (observation.Value - groupBaseline.center) / groupBaseline.spread
+		if config.Method == MethodIQR {
+			score = (observation.Value - groupBaseline.upper) / groupBaseline.spread
+			if observation.Value < groupBaseline.lower {
+				score = (observation.Value - groupBaseline.lower) / groupBaseline.spread
+			}
+			// distance is measured from the quartiles, not from the fences
+			score += math.Copysign(config.Threshold, score)
+		}
+
+		outliers = append(outliers, Outlier{
+			Observation: observation,
+			Score:       score,
+			Lower:       groupBaseline.lower,
+			Upper:       groupBaseline.upper,
+		})
+	}
+
+	return outliers, nil
+}
+
+func newBaseline(values []float64, config Config) baseline {
+	if len(values) == 0 {
+		return baseline{}
+	}
+
+	if config.Method == MethodZScore {
+		var sum, squares float64
+		for _, value := range values {
+			sum += value
+		}
+		mean := sum / float64(len(values))
+		for _, value := range values {
+			squares += (value - mean) * (value - mean)
+		}
+		deviation := math.Sqrt(squares / float64(len(values)))
+
+		return baseline{
+			lower:  mean - config.Threshold*deviation,
+			upper:  mean + config.Threshold*deviation,
+			center: mean,
+			spread: deviation,
+		}
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	q1 := quantile(sorted, 0.25)
+	q3 := quantile(sorted, 0.75)
+	iqr := q3 - q1
+
+	return baseline{
+		lower:  q1 - config.Threshold*iqr,
+		upper:  q3 + config.Threshold*iqr,
+		center: (q1 + q3) / 2,
+		spread: iqr,
+	}
+}
+
+// quantile interpolates linearly between the closest ranks of sorted.
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
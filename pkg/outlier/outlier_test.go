@@ -0,0 +1,120 @@
+package outlier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// observationsOf are observations of group with values, their ids from
+// firstId on.
+func observationsOf(firstId int, group int, values ...float64) []Observation {
+	observations := make([]Observation, 0, len(values))
+	for i, value := range values {
+		observations = append(observations, Observation{Id: firstId + i, Group: group, Value: value})
+	}
+
+	return observations
+}
+
+func outlierIds(outliers []Outlier) []int {
+	ids := []int{}
+	for _, outlier := range outliers {
+		ids = append(ids, outlier.Id)
+	}
+
+	return ids
+}
+
+func TestConfigWithDefaults(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        Config
+		expected      Config
+		expectedError error
+	}{
+		{"iqr by default", Config{}, Config{Method: MethodIQR, Threshold: DefaultIQRThreshold, MinGroupSize: DefaultMinGroupSize}, nil},
+		{"zscore threshold", Config{Method: MethodZScore}, Config{Method: MethodZScore, Threshold: DefaultZScoreThreshold, MinGroupSize: DefaultMinGroupSize}, nil},
+		{"kept", Config{Method: MethodZScore, Threshold: 2, PerGroup: true, MinGroupSize: 3}, Config{Method: MethodZScore, Threshold: 2, PerGroup: true, MinGroupSize: 3}, nil},
+		{"invalid method", Config{Method: "mad"}, Config{}, ErrorInvalidMethod},
+		{"negative threshold", Config{Threshold: -1}, Config{}, ErrorInvalidThreshold},
+		{"negative min group size", Config{MinGroupSize: -1}, Config{}, ErrorInvalidThreshold},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			result, err := test.config.WithDefaults()
+
+			// Assert
+			assert.Equal(t, test.expectedError, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	// 10 to 18 and 100, which is 2.99 deviations above the mean
+	population := observationsOf(1, 1, 10, 11, 12, 13, 14, 15, 16, 17, 18, 100)
+	// Group 2 is small and high, a baseline of its own flags nothing
+	perProduct := append(observationsOf(1, 1, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29),
+		observationsOf(21, 2, 100, 101, 102, 103, 104)...)
+
+	tests := []struct {
+		name         string
+		observations []Observation
+		config       Config
+		expectedIds  []int
+	}{
+		{"iqr", population, Config{Method: MethodIQR}, []int{10}},
+		{"iqr high threshold", population, Config{Method: MethodIQR, Threshold: 50}, []int{}},
+		{"zscore", population, Config{Method: MethodZScore, Threshold: 2}, []int{10}},
+		{"zscore default threshold", population, Config{Method: MethodZScore}, []int{}},
+		{"population baseline", perProduct, Config{Method: MethodIQR}, []int{21, 22, 23, 24, 25}},
+		{"per product", perProduct, Config{Method: MethodIQR, PerGroup: true}, []int{}},
+		{"group smaller than min size", perProduct, Config{Method: MethodIQR, PerGroup: true, MinGroupSize: 6}, []int{21, 22, 23, 24, 25}},
+		{"no spread", observationsOf(1, 1, 5, 5, 5, 5), Config{Method: MethodZScore}, []int{}},
+		{"empty", nil, Config{}, []int{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			result, err := Detect(test.observations, test.config)
+
+			// Assert
+			assert.Nil(t, err, "error should be nil")
+			assert.Equal(t, test.expectedIds, outlierIds(result))
+		})
+	}
+}
+
+func TestDetectScores(t *testing.T) {
+	// Arrange
+	observations := observationsOf(1, 1, 1, 2, 3, 4, 5, 20, -20)
+
+	// Act
+	iqrOutliers, iqrErr := Detect(observations, Config{Method: MethodIQR, Threshold: 1})
+	zscoreOutliers, zscoreErr := Detect(observations, Config{Method: MethodZScore, Threshold: 1})
+
+	// Assert
+	assert.Nil(t, iqrErr, "error should be nil")
+	assert.Nil(t, zscoreErr, "error should be nil")
+	// q1 1.5, q3 4.5, IQR 3, fences 1.5 - 3 and 4.5 + 3
+	assert.Equal(t, []Outlier{
+		{Observation: observations[5], Score: 5.166666666666667, Lower: -1.5, Upper: 7.5},
+		{Observation: observations[6], Score: -7.166666666666667, Lower: -1.5, Upper: 7.5},
+	}, iqrOutliers, "the scores should be the IQRs beyond the quartiles")
+	assert.Equal(t, []int{6, 7}, outlierIds(zscoreOutliers))
+	assert.Greater(t, zscoreOutliers[0].Score, 0.0, "a high value should have a positive score")
+	assert.Less(t, zscoreOutliers[1].Score, 0.0, "a low value should have a negative score")
+}
+
+func TestDetectInvalidConfig(t *testing.T) {
+	// Act
+	result, err := Detect(observationsOf(1, 1, 1, 2, 3), Config{Method: "mad"})
+
+	// Assert
+	assert.Equal(t, ErrorInvalidMethod, err)
+	assert.Nil(t, result, "result should be nil")
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// Drivers
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+var (
+	// MySQL is the dialect the repositories queries are written in
+	MySQL Dialect = mysqlDialect{}
+	// SQLite is the dialect of the embedded DB, a file needing no server
+	SQLite Dialect = sqliteDialect{}
+	// Postgres is the dialect of the production DB
+	Postgres Dialect = postgresDialect{}
+
+	// dateFormatRegexp matches DATE_FORMAT(expression, 'format')
+	dateFormatRegexp = regexp.MustCompile(`DATE_FORMAT\(([^,()]+), '([^']*)'\)`)
+	// postgresDateFormat turns the DATE_FORMAT specifiers into to_char ones
+	postgresDateFormat = strings.NewReplacer("%Y", "YYYY", "%m", "MM", "%d", "DD", "%H", "HH24", "%i", "MI", "%s", "SS")
+)
+
+// Dialect is the SQL of a driver. The repositories write their queries for
+// MySQL, with ? placeholders, and run them rebound to the dialect of their
+// DB.
+type Dialect interface {
+	// Name is the name of the driver of the dialect.
+	Name() string
+	// Rebind returns query, written for MySQL, in the dialect.
+	Rebind(query string) string
+	// Upsert is the clause making an INSERT update the given columns of the
+	// rows with the same keys, instead of failing.
+	Upsert(keys []string, columns []string) string
+}
+
+// Copier is a dialect loading bulk rows faster with COPY than with a
+// multi-row INSERT.
+type Copier interface {
+	// CopyIn is the statement copying the rows of columns into table.
+	CopyIn(table string, columns []string) string
+}
+
+// Returner is a dialect whose driver can not tell the id of the row an
+// INSERT stored, the INSERT returns it instead.
+type Returner interface {
+	// Returning is the clause making an INSERT return column.
+	Returning(column string) string
+}
+
+// Sequencer is a dialect whose ids come from sequences that the inserts
+// with explicit ids do not move.
+type Sequencer interface {
+	// SetSequence is the statement moving the id sequence of table to its
+	// biggest id.
+	SetSequence(table string) string
+}
+
+// Locker is a dialect with named locks held by a connection until it
+// releases them or closes.
+type Locker interface {
+	// Lock waits for the lock named name, until ctx is done.
+	Lock(ctx context.Context, conn *sql.Conn, name string) error
+	// Unlock releases the lock named name.
+	Unlock(ctx context.Context, conn *sql.Conn, name string) error
+}
+
+// DialectFor returns the dialect of driver.
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case DriverMySQL:
+		return MySQL, nil
+	case DriverSQLite:
+		return SQLite, nil
+	case DriverPostgres:
+		return Postgres, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrorStorageUnknownDriver, driver)
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return DriverMySQL
+}
+
+func (mysqlDialect) Rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) Upsert(keys []string, columns []string) string {
+	assignments := make([]string, 0, len(columns))
+	for _, column := range columns {
+		assignments = append(assignments, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+// Lock takes the lock with GET_LOCK, which needs a timeout in seconds, the
+// one of ctx or a day.
+func (mysqlDialect) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	timeout := 24 * time.Hour
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(timeout.Seconds())).Scan(&locked); err != nil {
+		return err
+	}
+
+	if locked.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrorStorageLockTimeout, name)
+	}
+
+	return nil
+}
+
+func (mysqlDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+	return err
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return DriverSQLite
+}
+
+// Rebind turns DATE_FORMAT into strftime, which takes the same %Y, %m and %d
+// specifiers. DATE and the ? placeholders are the same in SQLite.
+func (sqliteDialect) Rebind(query string) string {
+	return dateFormatRegexp.ReplaceAllString(query, "strftime('$2', $1)")
+}
+
+func (sqliteDialect) Upsert(keys []string, columns []string) string {
+	return onConflict(keys, columns)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return DriverPostgres
+}
+
+// Rebind numbers the ? placeholders, $1 to $n, and turns DATE_FORMAT into
+// to_char. DATE is a cast to date in Postgres too.
+func (postgresDialect) Rebind(query string) string {
+	query = dateFormatRegexp.ReplaceAllStringFunc(query, func(dateFormat string) string {
+		match := dateFormatRegexp.FindStringSubmatch(dateFormat)
+		return fmt.Sprintf("to_char(CAST(%s AS timestamp), '%s')", match[1], postgresDateFormat.Replace(match[2]))
+	})
+
+	var rebound strings.Builder
+	placeholder := 0
+	quoted := false
+	for _, char := range query {
+		switch {
+		case char == '\'':
+			quoted = !quoted
+		case char == '?' && !quoted:
+			placeholder++
+			rebound.WriteString("$" + strconv.Itoa(placeholder))
+			continue
+		}
+
+		rebound.WriteRune(char)
+	}
+
+	return rebound.String()
+}
+
+func (postgresDialect) Upsert(keys []string, columns []string) string {
+	return onConflict(keys, columns)
+}
+
+func (postgresDialect) CopyIn(table string, columns []string) string {
+	return pq.CopyIn(table, columns...)
+}
+
+func (postgresDialect) Returning(column string) string {
+	return "RETURNING " + column
+}
+
+// SetSequence sets the SERIAL sequence of table, which nextval follows.
+func (postgresDialect) SetSequence(table string) string {
+	return fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), MAX(id)) FROM %s", table, table)
+}
+
+// Lock takes the advisory lock of the hash of name, pg_advisory_lock waits
+// for it until ctx is done.
+func (postgresDialect) Lock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey(name))
+	return err
+}
+
+func (postgresDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey(name))
+	return err
+}
+
+// lockKey is the advisory lock key of name.
+func lockKey(name string) int64 {
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+
+	return int64(hash.Sum32())
+}
+
+// onConflict is the standard upsert clause, of SQLite and Postgres.
+func onConflict(keys []string, columns []string) string {
+	assignments := make([]string, 0, len(columns))
+	for _, column := range columns {
+		assignments = append(assignments, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ", "), strings.Join(assignments, ", "))
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectFor(t *testing.T) {
+	tests := []struct {
+		driver   string
+		expected Dialect
+	}{
+		{DriverMySQL, MySQL},
+		{DriverSQLite, SQLite},
+		{DriverPostgres, Postgres},
+	}
+
+	for _, test := range tests {
+		t.Run(test.driver, func(t *testing.T) {
+			// Act
+			dialect, err := DialectFor(test.driver)
+
+			// Assert
+			assert.Nil(t, err, "error should be nil")
+			assert.Equal(t, test.expected, dialect)
+			assert.Equal(t, test.driver, dialect.Name())
+		})
+	}
+}
+
+func TestDialectForUnknownDriver(t *testing.T) {
+	// Act
+	dialect, err := DialectFor("oracle")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorStorageUnknownDriver)
+	assert.Nil(t, dialect, "dialect should be nil")
+}
+
+func TestPostgresRebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"no placeholders", "SELECT id FROM products", "SELECT id FROM products"},
+		{"placeholders numbered", "UPDATE products SET price = ? WHERE id = ? AND class = ?", "UPDATE products SET price = $1 WHERE id = $2 AND class = $3"},
+		{"more than nine", "VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)", "VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10)"},
+		{"quoted ? kept", "SELECT id FROM customers WHERE last_name = 'Who?' AND id = ?", "SELECT id FROM customers WHERE last_name = 'Who?' AND id = $1"},
+		{"quote with escaped quote", "SELECT ? FROM customers WHERE last_name = 'O''Brien?' AND id = ?", "SELECT $1 FROM customers WHERE last_name = 'O''Brien?' AND id = $2"},
+		{"date format", "SELECT DATE_FORMAT(invoices.datetime, '%Y-%m-%d') FROM invoices WHERE id = ?", "SELECT to_char(CAST(invoices.datetime AS timestamp), 'YYYY-MM-DD') FROM invoices WHERE id = $1"},
+		{"date format with time", "SELECT DATE_FORMAT(datetime, '%Y-%m-%d %H:%i:%s') FROM invoices", "SELECT to_char(CAST(datetime AS timestamp), 'YYYY-MM-DD HH24:MI:SS') FROM invoices"},
+		{"date format repeated", "SELECT DATE_FORMAT(a, '%Y'), DATE_FORMAT(b, '%m') FROM t", "SELECT to_char(CAST(a AS timestamp), 'YYYY'), to_char(CAST(b AS timestamp), 'MM') FROM t"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			result := Postgres.Rebind(test.query)
+
+			// Assert
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestPostgresUpsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		columns  []string
+		expected string
+	}{
+		{"one key", []string{"id"}, []string{"price"}, "ON CONFLICT (id) DO UPDATE SET price = excluded.price"},
+		{"many keys and columns", []string{"date", "situation"}, []string{"total", "invoices"}, "ON CONFLICT (date, situation) DO UPDATE SET total = excluded.total, invoices = excluded.invoices"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			result := Postgres.Upsert(test.keys, test.columns)
+
+			// Assert
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestPostgresCopyIn(t *testing.T) {
+	// Arrange
+	copier, ok := Postgres.(Copier)
+
+	// Act
+	result := copier.CopyIn("sales", []string{"id", "invoice_id", "quantity"})
+
+	// Assert
+	assert.True(t, ok, "postgres should be a copier")
+	assert.Equal(t, `COPY "sales" ("id", "invoice_id", "quantity") FROM STDIN`, result)
+}
+
+func TestInsertBulkPostgresCopies(t *testing.T) {
+	// Arrange
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	defer mockDB.Close()
+	db := New(mockDB, Postgres)
+
+	mock.ExpectBegin()
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(`COPY "products" ("id", "price") FROM STDIN`))
+	prepare.ExpectExec().WithArgs(1, 10.5).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepare.ExpectExec().WithArgs(2, 20.0).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepare.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT setval").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// Act
+	err = db.InsertBulk(context.Background(), "products", []string{"id", "price"}, [][]interface{}{{1, 10.5}, {2, 20.0}})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestInsertBulkPostgresRollsBackFailedCopy(t *testing.T) {
+	// Arrange
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	defer mockDB.Close()
+	db := New(mockDB, Postgres)
+
+	mock.ExpectBegin()
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(`COPY "products" ("id", "price") FROM STDIN`))
+	prepare.ExpectExec().WithArgs(1, 10.5).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepare.ExpectExec().WithArgs(2, 20.0).WillReturnError(errors.New("invalid input syntax"))
+	mock.ExpectRollback()
+
+	// Act
+	err = db.InsertBulk(context.Background(), "products", []string{"id", "price"}, [][]interface{}{{1, 10.5}, {2, 20.0}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorStorageExecBulk)
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestInsertBulkPostgresSetsSequence(t *testing.T) {
+	// Arrange
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	defer mockDB.Close()
+	db := New(mockDB, Postgres)
+
+	mock.ExpectBegin()
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(`COPY "products" ("id", "price") FROM STDIN`))
+	prepare.ExpectExec().WithArgs(1, 10.5).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepare.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT setval(pg_get_serial_sequence('products', 'id'), MAX(id)) FROM products")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	// Act
+	err = db.InsertBulk(context.Background(), "products", []string{"id", "price"}, [][]interface{}{{1, 10.5}})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestInsertIdPostgresReturnsId(t *testing.T) {
+	// Arrange
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	defer mockDB.Close()
+	db := New(mockDB, Postgres)
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO products(description, price) VALUES($1, $2) RETURNING id")).WithArgs("Mate", 10.5).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+
+	// Act
+	id, err := db.InsertId(context.Background(), "INSERT INTO products(description, price) VALUES(?, ?)", "Mate", 10.5)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, int64(8), id)
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
+
+func TestInsertIdMySQLReadsLastInsertId(t *testing.T) {
+	// Arrange
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err, "error should be nil")
+	defer mockDB.Close()
+	db := New(mockDB, MySQL)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO products(description, price) VALUES(?, ?)")).WithArgs("Mate", 10.5).WillReturnResult(sqlmock.NewResult(8, 1))
+
+	// Act
+	id, err := db.InsertId(context.Background(), "INSERT INTO products(description, price) VALUES(?, ?)", "Mate", 10.5)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, int64(8), id)
+	assert.Nil(t, mock.ExpectationsWereMet(), "every expectation should be met")
+}
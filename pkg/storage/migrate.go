@@ -0,0 +1,474 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+const (
+	// migrationsLock is the name of the lock only one migrator holds
+	migrationsLock = "schema_migrations"
+	// migrationsLayout is the layout of the applied_at of the migrations
+	migrationsLayout = "2006-01-02 15:04:05"
+
+	// Errors of a query of a table not created
+	mysqlNoSuchTable       = 1146
+	postgresUndefinedTable = "42P01"
+	sqliteNoSuchTable      = "no such table"
+)
+
+var (
+	//go:embed migrations
+	migrations embed.FS
+
+	// migrationFileRegexp matches <version>_<name>.<up|down>.sql
+	migrationFileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+	// Db queries & statements
+	CreateMigrationsTableStatement = "CREATE TABLE IF NOT EXISTS schema_migrations(version INTEGER NOT NULL, name VARCHAR (255) NOT NULL, applied_at VARCHAR (19) NOT NULL, PRIMARY KEY(version))"
+	GetAppliedMigrationsQuery      = "SELECT version, applied_at FROM schema_migrations ORDER BY version"
+	StoreMigrationStatement        = "INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, ?)"
+	DeleteMigrationStatement       = "DELETE FROM schema_migrations WHERE version = ?"
+
+	// Errors
+	ErrorStorageInvalidMigration = errors.New("invalid migration")
+	ErrorStorageMigrationFailed  = errors.New("migration failed")
+	ErrorStorageUnknownMigration = errors.New("applied migration is unknown")
+	ErrorStoragePendingMigration = errors.New("migrations not applied")
+)
+
+// Migration is a numbered change of the schema of a dialect, with the SQL
+// applying it and the SQL undoing it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied, empty when it was
+// not.
+type MigrationStatus struct {
+	Migration
+	AppliedAt string
+}
+
+// Migrator applies and undoes the migrations of the dialect of a DB, the
+// files of migrations/<driver>. Every run holds a lock, so two instances
+// never migrate the same DB at once.
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+	// LockTimeout is how long a run waits for the lock, none when 0
+	LockTimeout time.Duration
+}
+
+// NewMigrator returns the migrator of db, failing when its migration files
+// are not a sequence of versions with an up and a down file each.
+func NewMigrator(db *DB) (*Migrator, error) {
+	dialectMigrations, err := readMigrations(path.Join("migrations", db.Dialect.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:          db,
+		migrations:  dialectMigrations,
+		LockTimeout: time.Minute,
+	}, nil
+}
+
+// Migrate applies the migrations pending of db.
+func (db *DB) Migrate(ctx context.Context) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(ctx)
+
+	return err
+}
+
+// Up applies the migrations pending, in order, and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo applies the migrations pending up to version, every one when version
+// is 0, in order, and returns them.
+func (m *Migrator) UpTo(ctx context.Context, version int) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.lockedStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if version > 0 && status.Version > version {
+				break
+			}
+
+			if status.AppliedAt != "" {
+				continue
+			}
+
+			if err = m.apply(ctx, conn, status.Migration, true); err != nil {
+				return err
+			}
+
+			applied = append(applied, status.Migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down undoes the last steps migrations applied, the last one first, and
+// returns them. Steps below 1 undo every migration.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var undone []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.lockedStatus(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if statuses[i].AppliedAt == "" {
+				continue
+			}
+
+			if steps > 0 && len(undone) == steps {
+				break
+			}
+
+			if err = m.apply(ctx, conn, statuses[i].Migration, false); err != nil {
+				return err
+			}
+
+			undone = append(undone, statuses[i].Migration)
+		}
+
+		return nil
+	})
+
+	return undone, err
+}
+
+// Redo undoes the last migration applied and applies it again, to check its
+// down file or apply a changed up file, and returns it.
+func (m *Migrator) Redo(ctx context.Context) ([]Migration, error) {
+	undone, err := m.Down(ctx, 1)
+	if err != nil || len(undone) == 0 {
+		return undone, err
+	}
+
+	return m.UpTo(ctx, undone[0].Version)
+}
+
+// Status returns every migration, in order, with when it was applied. It
+// only reads the DB, every migration is pending when none was applied yet.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return m.status(ctx, conn)
+}
+
+// Pending returns the migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == "" {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// CheckMigrated fails with ErrorStoragePendingMigration while a migration of
+// db is not applied, the code being newer than the DB. It only reads the DB,
+// so it can run as often as the readiness probes do, as a read only user.
+func (db *DB) CheckMigrated(ctx context.Context) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending, from %d_%s", ErrorStoragePendingMigration, len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	return nil
+}
+
+// lockedStatus is the status of the migrations for a run holding the lock,
+// which creates the migrations table first if needed.
+func (m *Migrator) lockedStatus(ctx context.Context, conn *sql.Conn) ([]MigrationStatus, error) {
+	if _, err := conn.ExecContext(ctx, m.db.Dialect.Rebind(CreateMigrationsTableStatement)); err != nil {
+		return nil, err
+	}
+
+	return m.status(ctx, conn)
+}
+
+// status fails with ErrorStorageUnknownMigration when a version applied has
+// no files, the DB being newer than the code. No migration was applied when
+// the migrations table was not created yet.
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{Migration: migration, AppliedAt: applied[migration.Version]})
+		delete(applied, migration.Version)
+	}
+
+	for version := range applied {
+		return nil, fmt.Errorf("%w: %d", ErrorStorageUnknownMigration, version)
+	}
+
+	return statuses, nil
+}
+
+// applied returns when every version applied was, by version.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]string, error) {
+	applied := map[int]string{}
+
+	rows, err := conn.QueryContext(ctx, m.db.Dialect.Rebind(GetAppliedMigrationsQuery))
+	if isMissingTable(err) {
+		return applied, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// apply runs the up or the down script of migration and records it, in a
+// transaction on Postgres, so a migration failing there changes nothing.
+// SQLite runs it in the transaction of the whole run already, MySQL commits
+// every statement changing the schema, so a migration failing halfway is
+// left half applied there and must be fixed by hand.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	if _, ok := m.db.Dialect.(postgresDialect); !ok {
+		return m.run(ctx, conn, migration, up)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = m.run(ctx, tx, migration, up); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer runs statements, on a connection or in a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// run runs the up or the down script of migration on conn and records it.
+func (m *Migrator) run(ctx context.Context, conn execer, migration Migration, up bool) error {
+	script := migration.Down
+	if up {
+		script = migration.Up
+	}
+
+	for _, statement := range strings.Split(script, ";") {
+		statement = strings.TrimSpace(stripComments(statement))
+		if statement == "" {
+			continue
+		}
+
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%w: %d_%s: %v", ErrorStorageMigrationFailed, migration.Version, migration.Name, err)
+		}
+	}
+
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, m.db.Dialect.Rebind(StoreMigrationStatement), migration.Version, migration.Name, time.Now().UTC().Format(migrationsLayout))
+	} else {
+		_, err = conn.ExecContext(ctx, m.db.Dialect.Rebind(DeleteMigrationStatement), migration.Version)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %d_%s: %v", ErrorStorageMigrationFailed, migration.Version, migration.Name, err)
+	}
+
+	return nil
+}
+
+// withLock runs run on a connection holding the migrations lock. SQLite has
+// no named locks, there run holds a write transaction instead, which only a
+// connection at a time can, and a failed run changes nothing.
+func (m *Migrator) withLock(ctx context.Context, run func(conn *sql.Conn) error) error {
+	conn, err := m.db.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	lockCtx := ctx
+	if m.LockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, m.LockTimeout)
+		defer cancel()
+	}
+
+	locker, ok := m.db.Dialect.(Locker)
+	if !ok {
+		if _, err = conn.ExecContext(lockCtx, "BEGIN IMMEDIATE"); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrorStorageLockTimeout, migrationsLock, err)
+		}
+
+		if err = run(conn); err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+			return err
+		}
+
+		_, err = conn.ExecContext(ctx, "COMMIT")
+
+		return err
+	}
+
+	if err = locker.Lock(lockCtx, conn, migrationsLock); err != nil {
+		return err
+	}
+
+	defer locker.Unlock(context.Background(), conn, migrationsLock)
+
+	return run(conn)
+}
+
+// isMissingTable tells whether err is the error of a query of a table not
+// created.
+func isMissingTable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlNoSuchTable
+	}
+
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) {
+		return postgresErr.Code == postgresUndefinedTable
+	}
+
+	return strings.Contains(err.Error(), sqliteNoSuchTable)
+}
+
+// readMigrations reads the migrations of dir ordered by version.
+func readMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorStorageInvalidMigration, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrorStorageInvalidMigration, entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d has two names", ErrorStorageInvalidMigration, version)
+		}
+
+		script, err := fs.ReadFile(migrations, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorStorageInvalidMigration, err)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	dirMigrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("%w: %d_%s needs an up and a down file", ErrorStorageInvalidMigration, migration.Version, migration.Name)
+		}
+
+		dirMigrations = append(dirMigrations, *migration)
+	}
+
+	sort.Slice(dirMigrations, func(i, j int) bool { return dirMigrations[i].Version < dirMigrations[j].Version })
+
+	return dirMigrations, nil
+}
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// openTestDB opens a new SQLite DB, closed when the test ends.
+func openTestDB(t *testing.T) *DB {
+	db, err := Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// tableExists tells whether the SQLite db has table.
+func tableExists(t *testing.T, db *DB, table string) bool {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	assert.Nil(t, err, "error should be nil")
+
+	return count > 0
+}
+
+func TestCheckMigratedOnlyReads(t *testing.T) {
+	// Arrange
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	// Act
+	errBefore := db.CheckMigrated(ctx)
+	migrationsTableCreated := tableExists(t, db, "schema_migrations")
+	errMigrate := db.Migrate(ctx)
+	errAfter := db.CheckMigrated(ctx)
+
+	// Assert
+	assert.ErrorIs(t, errBefore, ErrorStoragePendingMigration, "every migration should be pending")
+	assert.False(t, migrationsTableCreated, "the check should not create the migrations table")
+	assert.Nil(t, errMigrate, "error should be nil")
+	assert.Nil(t, errAfter, "no migration should be pending")
+}
+
+// newTestMigrator is the migrator of a new SQLite DB.
+func newTestMigrator(t *testing.T) (*Migrator, *DB) {
+	db := openTestDB(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return migrator, db
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	// Arrange
+	migrator, db := newTestMigrator(t)
+	ctx := context.Background()
+
+	// Act
+	applied, errUp := migrator.Up(ctx)
+	createdTables := tableExists(t, db, "products") && tableExists(t, db, "sales")
+	appliedAgain, errUpAgain := migrator.Up(ctx)
+	undone, errDown := migrator.Down(ctx, 0)
+
+	// Assert
+	assert.Nil(t, errUp, "error should be nil")
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, migrationVersions(applied))
+	assert.True(t, createdTables, "the tables should be created")
+	assert.Nil(t, errUpAgain, "error should be nil")
+	assert.Empty(t, appliedAgain, "no migration should be pending")
+	assert.Nil(t, errDown, "error should be nil")
+	assert.Equal(t, []int{5, 4, 3, 2, 1}, migrationVersions(undone), "the last migration should be undone first")
+	assert.False(t, tableExists(t, db, "products"), "the tables should be dropped")
+	assert.True(t, tableExists(t, db, "schema_migrations"), "the migrations table should be kept")
+}
+
+func TestMigratorDownSteps(t *testing.T) {
+	// Arrange
+	migrator, _ := newTestMigrator(t)
+	ctx := context.Background()
+	_, err := migrator.Up(ctx)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	undone, err := migrator.Down(ctx, 1)
+	statuses, errStatus := migrator.Status(ctx)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []int{5}, migrationVersions(undone))
+	assert.Nil(t, errStatus, "error should be nil")
+	assert.Len(t, statuses, 5)
+	assert.NotEmpty(t, statuses[3].AppliedAt, "the migrations before the last should still be applied")
+	assert.Empty(t, statuses[4].AppliedAt, "the last migration should be pending")
+}
+
+func TestMigratorUpTo(t *testing.T) {
+	// Arrange
+	migrator, _ := newTestMigrator(t)
+	ctx := context.Background()
+
+	// Act
+	applied, err := migrator.UpTo(ctx, 1)
+	pending, errPending := migrator.Pending(ctx)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []int{1}, migrationVersions(applied))
+	assert.Nil(t, errPending, "error should be nil")
+	assert.Equal(t, []int{2, 3, 4, 5}, migrationVersions(pending))
+}
+
+func TestMigratorUpgradesBaselineSchema(t *testing.T) {
+	// Arrange
+	migrator, db := newTestMigrator(t)
+	ctx := context.Background()
+	_, err := migrator.UpTo(ctx, 1)
+	assert.Nil(t, err, "error should be nil")
+	_, err = db.Exec("INSERT INTO products(id, price, description) VALUES(1, 10, 'Mate')")
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	applied, err := migrator.Up(ctx)
+	var class string
+	errClass := db.QueryRow("SELECT class FROM products WHERE id = 1").Scan(&class)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, []int{2, 3, 4, 5}, migrationVersions(applied))
+	assert.Nil(t, errClass, "the products should have a class")
+	assert.Empty(t, class, "the products stored before should have no class")
+	assert.True(t, tableExists(t, db, "sales_held"), "the held sales table should be created")
+	assert.True(t, tableExists(t, db, "daily_product_revenue") && tableExists(t, db, "daily_situation_revenue"), "the summary tables should be created")
+}
+
+func TestMigratorFillsSummariesOfExistingData(t *testing.T) {
+	// Arrange
+	migrator, db := newTestMigrator(t)
+	ctx := context.Background()
+	_, err := migrator.UpTo(ctx, 3)
+	assert.Nil(t, err, "error should be nil")
+	for _, statement := range []string{
+		"INSERT INTO products(id, price, description) VALUES(1, 10, 'Mate')",
+		"INSERT INTO customers(id, first_name, last_name, situation) VALUES(1, 'Pepe', 'Argento', 'Activo')",
+		"INSERT INTO invoices(id, customer_id, datetime, total) VALUES(1, 1, '2021-12-31 10:00:00', 20), (2, 1, '2022-01-01 10:00:00', 10)",
+		"INSERT INTO sales(id, invoice_id, product_id, quantity) VALUES(1, 1, 1, 2), (2, 2, 1, 1)",
+	} {
+		_, err = db.Exec(statement)
+		assert.Nil(t, err, "error should be nil")
+	}
+
+	// Act
+	_, err = migrator.Up(ctx)
+	var productDays, situationDays int
+	var revenue float64
+	errProducts := db.QueryRow("SELECT COUNT(*), SUM(revenue) FROM daily_product_revenue").Scan(&productDays, &revenue)
+	errSituations := db.QueryRow("SELECT COUNT(*) FROM daily_situation_revenue WHERE situation = 'Activo'").Scan(&situationDays)
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Nil(t, errProducts, "error should be nil")
+	assert.Nil(t, errSituations, "error should be nil")
+	assert.Equal(t, 2, productDays, "every day with sales should be summarized")
+	assert.Equal(t, 30.0, revenue, "the revenue should be the one of the sales")
+	assert.Equal(t, 2, situationDays, "every day with invoices should be summarized")
+}
+
+func TestMigratorRedo(t *testing.T) {
+	// Arrange
+	migrator, _ := newTestMigrator(t)
+	ctx := context.Background()
+	_, err := migrator.Up(ctx)
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	redone, errRedo := migrator.Redo(ctx)
+	redoneAgain, errRedoAgain := migrator.Redo(ctx)
+	pending, errPending := migrator.Pending(ctx)
+
+	// Assert
+	assert.Nil(t, errRedo, "error should be nil")
+	assert.Equal(t, []int{5}, migrationVersions(redone), "the last migration should be applied again")
+	assert.Nil(t, errRedoAgain, "error should be nil")
+	assert.Equal(t, []int{5}, migrationVersions(redoneAgain))
+	assert.Nil(t, errPending, "error should be nil")
+	assert.Empty(t, pending, "every migration should be applied")
+}
+
+func TestMigratorStatusUnknownVersion(t *testing.T) {
+	// Arrange
+	migrator, db := newTestMigrator(t)
+	ctx := context.Background()
+	_, err := migrator.Up(ctx)
+	assert.Nil(t, err, "error should be nil")
+	_, err = db.Exec(StoreMigrationStatement, 99, "from_newer_code", "2022-01-01 00:00:00")
+	assert.Nil(t, err, "error should be nil")
+
+	// Act
+	_, errStatus := migrator.Status(ctx)
+	_, errUp := migrator.Up(ctx)
+
+	// Assert
+	assert.ErrorIs(t, errStatus, ErrorStorageUnknownMigration)
+	assert.ErrorIs(t, errUp, ErrorStorageUnknownMigration, "a DB newer than the code should not be migrated")
+}
+
+func TestMigratorLockTimeout(t *testing.T) {
+	// Arrange
+	first, db := newTestMigrator(t)
+	second, err := NewMigrator(db)
+	assert.Nil(t, err, "error should be nil")
+	second.LockTimeout = 100 * time.Millisecond
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	firstErr := make(chan error, 1)
+	go func() {
+		firstErr <- first.withLock(context.Background(), func(conn *sql.Conn) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	// Act
+	applied, err := second.Up(context.Background())
+	close(release)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorStorageLockTimeout, "the second runner should not get the lock")
+	assert.Empty(t, applied)
+	assert.Nil(t, <-firstErr, "error should be nil")
+}
+
+// migrationVersions are the versions of migrations, in order.
+func migrationVersions(migrations []Migration) []int {
+	versions := make([]int, 0, len(migrations))
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+
+	return versions
+}
+
+// newPostgresMigrator is a migrator of a mocked Postgres DB with a single
+// migration, its lock taken and its table read as empty.
+func newPostgresMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { mockDB.Close() })
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}))
+
+	migrator := &Migrator{
+		db:         New(mockDB, postgresDialect{}),
+		migrations: []Migration{{Version: 1, Name: "create_tables", Up: "CREATE TABLE a(id INTEGER); CREATE TABLE b(id INTEGER);", Down: "DROP TABLE b; DROP TABLE a;"}},
+	}
+
+	return migrator, mock
+}
+
+func TestMigratorPostgresAppliesInTransaction(t *testing.T) {
+	// Arrange
+	migrator, mock := newPostgresMigrator(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(1, "create_tables", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	applied, err := migrator.Up(context.Background())
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Len(t, applied, 1)
+	assert.Nil(t, mock.ExpectationsWereMet(), "the migration should be applied in a transaction")
+}
+
+func TestMigratorPostgresRollsBackFailedMigration(t *testing.T) {
+	// Arrange
+	migrator, mock := newPostgresMigrator(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE b").WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	applied, err := migrator.Up(context.Background())
+
+	// Assert
+	assert.ErrorIs(t, err, ErrorStorageMigrationFailed)
+	assert.Empty(t, applied)
+	assert.Nil(t, mock.ExpectationsWereMet(), "the migration should be rolled back")
+}
@@ -0,0 +1,6 @@
+-- The column may already be there, added by hand before the migrations.
+-- MySQL has no ADD COLUMN IF NOT EXISTS, so it is added only when missing.
+SET @add_class = (SELECT IF(COUNT(*) = 0, 'ALTER TABLE products ADD COLUMN class CHAR (1) NOT NULL DEFAULT ''''', 'DO 0') FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'products' AND column_name = 'class');
+PREPARE add_class FROM @add_class;
+EXECUTE add_class;
+DEALLOCATE PREPARE add_class;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sales_held(
+	id INT NOT NULL,
+	invoice_id INT NOT NULL,
+	product_id INT NOT NULL,
+	quantity FLOAT NOT NULL,
+	reason VARCHAR (255) NOT NULL,
+
+	PRIMARY KEY(id),
+	FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
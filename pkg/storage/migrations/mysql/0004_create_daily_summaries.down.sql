@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS daily_situation_revenue;
+DROP TABLE IF EXISTS daily_product_revenue;
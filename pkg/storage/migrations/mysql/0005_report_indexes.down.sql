@@ -0,0 +1,12 @@
+-- MySQL drops the indexes it made for the foreign keys once another one
+-- leads with their columns, and refuses to drop that one while a foreign
+-- key needs it, so plain indexes go back first
+CREATE INDEX sales_product_id ON sales(product_id);
+CREATE INDEX sales_invoice_id ON sales(invoice_id);
+CREATE INDEX invoices_customer_id ON invoices(customer_id);
+
+DROP INDEX sales_product_id_quantity ON sales;
+DROP INDEX sales_invoice_id_product_id ON sales;
+DROP INDEX invoices_customer_id_datetime ON invoices;
+DROP INDEX invoices_datetime ON invoices;
+DROP INDEX customers_situation ON customers;
@@ -0,0 +1,23 @@
+-- The reports join sales by product and by invoice, invoices by customer
+-- and group by situation and day. The extra columns cover the aggregates,
+-- so the reports never read the rows.
+CREATE INDEX sales_product_id_quantity ON sales(product_id, quantity);
+CREATE INDEX sales_invoice_id_product_id ON sales(invoice_id, product_id, quantity);
+CREATE INDEX invoices_customer_id_datetime ON invoices(customer_id, datetime, total);
+CREATE INDEX invoices_datetime ON invoices(datetime, total);
+CREATE INDEX customers_situation ON customers(situation);
+-- A down leaves plain indexes for the foreign keys, which the ones above
+-- cover. MySQL has no DROP INDEX IF EXISTS, so they are dropped only when
+-- present, for the migration to be applied again.
+SET @drop_index = (SELECT IF(COUNT(*) > 0, 'DROP INDEX sales_product_id ON sales', 'DO 0') FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'sales' AND index_name = 'sales_product_id');
+PREPARE drop_index FROM @drop_index;
+EXECUTE drop_index;
+DEALLOCATE PREPARE drop_index;
+SET @drop_index = (SELECT IF(COUNT(*) > 0, 'DROP INDEX sales_invoice_id ON sales', 'DO 0') FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'sales' AND index_name = 'sales_invoice_id');
+PREPARE drop_index FROM @drop_index;
+EXECUTE drop_index;
+DEALLOCATE PREPARE drop_index;
+SET @drop_index = (SELECT IF(COUNT(*) > 0, 'DROP INDEX invoices_customer_id ON invoices', 'DO 0') FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'invoices' AND index_name = 'invoices_customer_id');
+PREPARE drop_index FROM @drop_index;
+EXECUTE drop_index;
+DEALLOCATE PREPARE drop_index;
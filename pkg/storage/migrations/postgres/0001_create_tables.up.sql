@@ -0,0 +1,34 @@
+-- Amounts are NUMERIC, ROUND(x, n) only takes NUMERIC in Postgres
+CREATE TABLE IF NOT EXISTS products(
+	id SERIAL PRIMARY KEY,
+	price NUMERIC NOT NULL,
+	description VARCHAR (45) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customers(
+	id SERIAL PRIMARY KEY,
+	first_name VARCHAR (45) NOT NULL,
+	last_name VARCHAR (45) NOT NULL,
+	situation VARCHAR (45) NOT NULL
+);
+
+-- Datetimes are TEXT, the driver would return TIMESTAMP columns as
+-- time.Time instead of the strings MySQL returns
+CREATE TABLE IF NOT EXISTS invoices(
+	id SERIAL PRIMARY KEY,
+	customer_id INTEGER NOT NULL,
+	datetime TEXT NOT NULL,
+	total NUMERIC NOT NULL,
+
+	FOREIGN KEY (customer_id) REFERENCES customers(id)
+);
+
+CREATE TABLE IF NOT EXISTS sales(
+	id SERIAL PRIMARY KEY,
+	invoice_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantity NUMERIC NOT NULL,
+
+	FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
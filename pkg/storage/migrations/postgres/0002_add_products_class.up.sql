@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS class CHAR (1) NOT NULL DEFAULT '';
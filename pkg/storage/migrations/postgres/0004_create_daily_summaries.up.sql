@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS daily_product_revenue(
+	day DATE NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantity NUMERIC NOT NULL,
+	revenue NUMERIC NOT NULL,
+	sales INTEGER NOT NULL,
+
+	PRIMARY KEY(day, product_id),
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE TABLE IF NOT EXISTS daily_situation_revenue(
+	day DATE NOT NULL,
+	situation VARCHAR (45) NOT NULL,
+	revenue NUMERIC NOT NULL,
+	invoices INTEGER NOT NULL,
+
+	PRIMARY KEY(day, situation)
+);
+
+-- The summaries of the data loaded before the tables existed, the loads
+-- keep them up to date from here on
+INSERT INTO daily_product_revenue (day, product_id, quantity, revenue, sales) SELECT DATE(invoices.datetime), sales.product_id, SUM(sales.quantity), SUM(products.price * sales.quantity), COUNT(sales.id) FROM sales INNER JOIN invoices ON invoices.id = sales.invoice_id INNER JOIN products ON products.id = sales.product_id GROUP BY DATE(invoices.datetime), sales.product_id;
+INSERT INTO daily_situation_revenue (day, situation, revenue, invoices) SELECT DATE(invoices.datetime), customers.situation, SUM(invoices.total), COUNT(invoices.id) FROM invoices INNER JOIN customers ON customers.id = invoices.customer_id GROUP BY DATE(invoices.datetime), customers.situation;
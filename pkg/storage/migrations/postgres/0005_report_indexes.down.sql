@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS sales_product_id_quantity;
+DROP INDEX IF EXISTS sales_invoice_id_product_id;
+DROP INDEX IF EXISTS invoices_customer_id_datetime;
+DROP INDEX IF EXISTS invoices_datetime;
+DROP INDEX IF EXISTS customers_situation;
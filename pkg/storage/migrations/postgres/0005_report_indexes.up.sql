@@ -0,0 +1,8 @@
+-- The reports join sales by product and by invoice, invoices by customer
+-- and group by situation and day. The extra columns cover the aggregates,
+-- so the reports never read the rows.
+CREATE INDEX sales_product_id_quantity ON sales(product_id, quantity);
+CREATE INDEX sales_invoice_id_product_id ON sales(invoice_id, product_id, quantity);
+CREATE INDEX invoices_customer_id_datetime ON invoices(customer_id, datetime, total);
+CREATE INDEX invoices_datetime ON invoices(datetime, total);
+CREATE INDEX customers_situation ON customers(situation);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS products(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	price REAL NOT NULL,
+	description VARCHAR (45) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customers(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name VARCHAR (45) NOT NULL,
+	last_name VARCHAR (45) NOT NULL,
+	situation VARCHAR (45) NOT NULL
+);
+
+-- Datetimes are TEXT, the driver would return DATETIME columns as time.Time
+-- instead of the strings MySQL returns
+CREATE TABLE IF NOT EXISTS invoices(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer_id INTEGER NOT NULL,
+	datetime TEXT NOT NULL,
+	total REAL NOT NULL,
+
+	FOREIGN KEY (customer_id) REFERENCES customers(id)
+);
+
+CREATE TABLE IF NOT EXISTS sales(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	invoice_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantity REAL NOT NULL,
+
+	FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN class CHAR (1) NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sales_held(
+	id INTEGER NOT NULL,
+	invoice_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantity REAL NOT NULL,
+	reason VARCHAR (255) NOT NULL,
+
+	PRIMARY KEY(id),
+	FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+const (
+	// maxBackoff is the longest wait between two attempts
+	maxBackoff = 30 * time.Second
+
+	// MySQL errors of statements a retry can succeed
+	mysqlDeadlock        = 1213
+	mysqlLockWaitTimeout = 1205
+)
+
+var (
+	// Postgres errors of statements a retry can succeed
+	postgresTransient = map[pq.ErrorCode]bool{
+		"40P01": true, // deadlock_detected
+		"40001": true, // serialization_failure
+		"55P03": true, // lock_not_available
+	}
+
+	// pools are the pools opened with OpenWithOptions, so every DB wrapping one
+	// shares its options and stats
+	pools sync.Map
+)
+
+// Options are the pool settings of a DB and how it retries. The zero
+// Options are the database/sql defaults, without retries.
+type Options struct {
+	MaxOpenConns    int           // no limit when 0
+	MaxIdleConns    int           // 2 when 0
+	ConnMaxLifetime time.Duration // forever when 0
+	ConnMaxIdleTime time.Duration // forever when 0
+	// ConnectRetries are the pings retried while the DB is not reachable on
+	// open, ConnectBackoff the wait before the first, doubled on every one
+	ConnectRetries int
+	ConnectBackoff time.Duration
+	// Retries are the statements retried after a deadlock or a lock wait
+	// timeout, RetryBackoff the wait before the first, doubled on every one
+	Retries      int
+	RetryBackoff time.Duration
+	// Replicas are the DSNs of the read replicas, the reads go to the
+	// healthy ones. A replica further behind than ReplicaMaxLag, no limit
+	// when 0, is unhealthy. They are checked every ReplicaCheckInterval,
+	// only on open when 0
+	Replicas             []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+}
+
+// PoolStats are the stats of the connections of a DB.
+type PoolStats struct {
+	MaxOpenConnections int            `json:"max_open_connections"`
+	OpenConnections    int            `json:"open_connections"`
+	InUse              int            `json:"in_use"`
+	Idle               int            `json:"idle"`
+	WaitCount          int64          `json:"wait_count"`
+	WaitDurationMs     int64          `json:"wait_duration_ms"`
+	MaxIdleClosed      int64          `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64          `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64          `json:"max_lifetime_closed"`
+	Retries            int64          `json:"retries"` // statements retried after a transient error
+	Replicas           []ReplicaStats `json:"replicas,omitempty"`
+}
+
+// pool is what the DBs wrapping the same *sql.DB share.
+type pool struct {
+	options  Options
+	retries  int64
+	replicas []*replica
+	next     uint32        // the replica the next read goes to
+	stop     chan struct{} // closed to stop checking the replicas
+}
+
+// OpenWithOptions opens the DB of driver at dsn, and its replicas, with the
+// pool settings of options. It waits for the DB to be reachable, retrying the
+// ping with backoff, until the retries run out or ctx is done.
+func OpenWithOptions(ctx context.Context, driver string, dsn string, options Options) (*DB, error) {
+	dialect, err := DialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	setPool(db, options)
+
+	backoff := options.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		if err = db.PingContext(ctx); err == nil {
+			break
+		}
+
+		if attempt >= options.ConnectRetries {
+			db.Close()
+			return nil, err
+		}
+
+		log.Printf("db not reachable, retrying in %s: %v", backoff, err)
+		if err = sleep(ctx, backoff); err != nil {
+			db.Close()
+			return nil, err
+		}
+		backoff = nextBackoff(backoff)
+	}
+
+	replicas, err := openReplicas(ctx, driver, dialect, options)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	p := &pool{options: options, replicas: replicas, stop: make(chan struct{})}
+	pools.Store(db, p)
+	if len(replicas) > 0 && options.ReplicaCheckInterval > 0 {
+		go watchReplicas(p, dialect)
+	}
+
+	return New(db, dialect), nil
+}
+
+// NewWithOptions returns db running the queries in dialect and retrying as
+// options say, for every DB wrapping it. Its pool settings are left as they
+// are.
+func NewWithOptions(db *sql.DB, dialect Dialect, options Options) *DB {
+	pools.Store(db, &pool{options: options, stop: make(chan struct{})})
+
+	return New(db, dialect)
+}
+
+// Close closes the DB and its replicas, forgetting their pools.
+func (db *DB) Close() error {
+	if p, ok := pools.LoadAndDelete(db.DB); ok {
+		close(p.(*pool).stop)
+		closeReplicas(p.(*pool).replicas)
+	}
+
+	return db.DB.Close()
+}
+
+// PoolStats returns the stats of the connections of db.
+func (db *DB) PoolStats() PoolStats {
+	stats := db.DB.Stats()
+	poolStats := PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+
+	if p, ok := db.pool(); ok {
+		poolStats.Retries = atomic.LoadInt64(&p.retries)
+		for _, r := range p.replicas {
+			poolStats.Replicas = append(poolStats.Replicas, r.stats())
+		}
+	}
+
+	return poolStats
+}
+
+// Retry runs run, and runs it again while it fails with a transient error
+// and the retries of the options of db last. Run must be safe to repeat: a
+// single statement, or a whole transaction.
+func (db *DB) Retry(ctx context.Context, run func() error) error {
+	p, ok := db.pool()
+	if !ok {
+		return run()
+	}
+
+	backoff := p.options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := run()
+		if err == nil || attempt >= p.options.Retries || !IsTransient(err) {
+			return err
+		}
+
+		atomic.AddInt64(&p.retries, 1)
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			return err
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// IsTransient reports whether err is a deadlock or a lock wait timeout, the
+// errors of statements that can succeed when retried.
+func IsTransient(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout
+	}
+
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) {
+		return postgresTransient[postgresErr.Code]
+	}
+
+	return false
+}
+
+func (db *DB) pool() (*pool, bool) {
+	p, ok := pools.Load(db.DB)
+	if !ok {
+		return nil, false
+	}
+
+	return p.(*pool), true
+}
+
+// setPool applies the pool settings of options to db.
+func setPool(db *sql.DB, options Options) {
+	db.SetMaxOpenConns(options.MaxOpenConns)
+	if options.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(options.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(options.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(options.ConnMaxIdleTime)
+}
+
+// sleep waits for duration, or fails when ctx is done first.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff *= 2; backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// primaryKey is the context key of the contexts reading from the primary
+type primaryKey struct{}
+
+var (
+	// Errors
+	ErrorStorageReplicationStopped = errors.New("replication is stopped")
+)
+
+// Replicator is a dialect telling how far behind its primary a replica is.
+// The replicas of the other dialects are never behind.
+type Replicator interface {
+	// ReplicaLag is how long ago the last change db replayed happened on the
+	// primary, 0 when db is not a replica.
+	ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error)
+}
+
+// ReplicaStats are the health and the connections of a replica of a DB.
+type ReplicaStats struct {
+	Healthy         bool  `json:"healthy"`
+	LagMs           int64 `json:"lag_ms"`
+	OpenConnections int   `json:"open_connections"`
+	InUse           int   `json:"in_use"`
+}
+
+// replica is a read replica of a DB, healthy while it is reachable and not
+// further behind than the max lag.
+type replica struct {
+	db      *sql.DB
+	mu      sync.RWMutex
+	healthy bool
+	lag     time.Duration
+}
+
+// WithPrimary returns ctx making the reads go to the primary, for the reads
+// of what was just written, which the replicas may not have yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsFromPrimary tells whether ctx was made WithPrimary.
+func ReadsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+
+	return primary
+}
+
+// Reader returns the DB the reads of ctx go to: a healthy replica of db, in
+// turns, or db itself when it has none, all of them are unhealthy or ctx was
+// made WithPrimary.
+func (db *DB) Reader(ctx context.Context) *DB {
+	p, ok := db.pool()
+	if !ok || len(p.replicas) == 0 {
+		return db
+	}
+
+	if ReadsFromPrimary(ctx) {
+		return db
+	}
+
+	next := int(atomic.AddUint32(&p.next, 1))
+	for i := range p.replicas {
+		r := p.replicas[(next+i)%len(p.replicas)]
+		if r.isHealthy() {
+			return New(r.db, db.Dialect)
+		}
+	}
+
+	return db
+}
+
+// openReplicas opens the replicas of options with its pool settings. A
+// replica not reachable is unhealthy until a check finds it is.
+func openReplicas(ctx context.Context, driver string, dialect Dialect, options Options) ([]*replica, error) {
+	// The replicas retry as the primary, and have no replicas themselves
+	replicaOptions := options
+	replicaOptions.Replicas = nil
+
+	replicas := make([]*replica, 0, len(options.Replicas))
+	for _, dsn := range options.Replicas {
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, err
+		}
+
+		setPool(db, options)
+		pools.Store(db, &pool{options: replicaOptions, stop: make(chan struct{})})
+
+		r := &replica{db: db}
+		if r.check(ctx, dialect, options.ReplicaMaxLag); !r.isHealthy() {
+			log.Printf("replica %d unhealthy, reading from the primary until it is not", len(replicas)+1)
+		}
+		replicas = append(replicas, r)
+	}
+
+	return replicas, nil
+}
+
+// watchReplicas checks the replicas of p every check interval, until p is
+// closed.
+func watchReplicas(p *pool, dialect Dialect) {
+	interval := p.options.ReplicaCheckInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, r := range p.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				r.check(ctx, dialect, p.options.ReplicaMaxLag)
+				cancel()
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func closeReplicas(replicas []*replica) {
+	for _, r := range replicas {
+		pools.Delete(r.db)
+		r.db.Close()
+	}
+}
+
+// check updates the health of the replica, unhealthy when it is not
+// reachable or further behind than maxLag, no limit when 0.
+func (r *replica) check(ctx context.Context, dialect Dialect, maxLag time.Duration) {
+	var lag time.Duration
+	err := r.db.PingContext(ctx)
+	if replicator, ok := dialect.(Replicator); ok && err == nil {
+		lag, err = replicator.ReplicaLag(ctx, r.db)
+	}
+
+	healthy := err == nil && (maxLag == 0 || lag <= maxLag)
+
+	r.mu.Lock()
+	wasHealthy := r.healthy
+	r.healthy = healthy
+	r.lag = lag
+	r.mu.Unlock()
+
+	switch {
+	case wasHealthy && err != nil:
+		log.Printf("replica unhealthy, reading from the primary: %v", err)
+	case wasHealthy && !healthy:
+		log.Printf("replica unhealthy, reading from the primary: %s behind", lag)
+	case !wasHealthy && healthy:
+		log.Printf("replica healthy, %s behind", lag)
+	}
+}
+
+func (r *replica) isHealthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.healthy
+}
+
+func (r *replica) stats() ReplicaStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := r.db.Stats()
+
+	return ReplicaStats{
+		Healthy:         r.healthy,
+		LagMs:           r.lag.Milliseconds(),
+		OpenConnections: stats.OpenConnections,
+		InUse:           stats.InUse,
+	}
+}
+
+// ReplicaLag reads Seconds_Behind_Source, Seconds_Behind_Master before
+// MySQL 8.0.22, which is NULL while the replication is stopped.
+func (mysqlDialect) ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err = rows.Scan(pointers...); err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+
+		if !values[i].Valid {
+			return 0, ErrorStorageReplicationStopped
+		}
+
+		seconds, err := strconv.Atoi(values[i].String)
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, nil
+}
+
+// ReplicaLag is the time since the last transaction replayed, 0 when every
+// change received is replayed, an idle primary does not make it behind.
+func (postgresDialect) ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var seconds float64
+	err := db.QueryRowContext(ctx, `SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
+)
+
+var (
+	// Errors
+	ErrorStorageUnknownDriver = errors.New("unknown db driver")
+	ErrorStoragePrepareBulk   = errors.New("can not prepare bulk insert")
+	ErrorStorageExecBulk      = errors.New("error executing bulk insert")
+	ErrorStorageLockTimeout   = errors.New("timeout waiting for lock")
+)
+
+// DB is a *sql.DB running the queries, written for MySQL, in the dialect of
+// its driver. The methods it does not override run the queries as they are.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Tx is a *sql.Tx of a DB, running the queries in its dialect.
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+// New returns db running the queries in dialect. It shares the options and
+// the stats of db with every DB wrapping it, when it was opened with
+// OpenWithOptions.
+func New(db *sql.DB, dialect Dialect) *DB {
+	return &DB{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+// Open opens the DB of driver at dsn, with the default pool settings and no
+// retries, and checks it is reachable.
+func Open(driver string, dsn string) (*DB, error) {
+	return OpenWithOptions(context.Background(), driver, dsn, Options{})
+}
+
+// InsertBulk inserts rows, the values of columns, into table in a single
+// multi-row INSERT, or with COPY in a transaction for the Copier dialects,
+// retried after transient errors. The id sequence of the Sequencer dialects
+// is moved past the ids inserted. Its errors are ErrorStoragePrepareBulk or
+// ErrorStorageExecBulk.
+func (db *DB) InsertBulk(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("%w: no rows to insert", ErrorStoragePrepareBulk)
+	}
+
+	return db.Retry(ctx, func() error {
+		if copier, ok := db.Dialect.(Copier); ok {
+			return db.copyBulk(ctx, copier, table, columns, rows)
+		}
+
+		return db.insertBulk(ctx, table, columns, rows)
+	})
+}
+
+func (db *DB) insertBulk(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	valueStrings := make([]string, 0, len(rows))
+	valueArgs := make([]interface{}, 0, len(rows)*len(columns))
+	for _, row := range rows {
+		valueStrings = append(valueStrings, placeholders)
+		valueArgs = append(valueArgs, row...)
+	}
+
+	stmt, err := db.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), strings.Join(valueStrings, ",")))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrorStoragePrepareBulk, err)
+	}
+
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, valueArgs...); err != nil {
+		return fmt.Errorf("%w: %v", ErrorStorageExecBulk, err)
+	}
+
+	return nil
+}
+
+// copyBulk copies the rows in a transaction, so a failed copy stores none.
+func (db *DB) copyBulk(ctx context.Context, copier Copier, table string, columns []string, rows [][]interface{}) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrorStoragePrepareBulk, err)
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, copier.CopyIn(table, columns))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrorStoragePrepareBulk, err)
+	}
+
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("%w: %v", ErrorStorageExecBulk, err)
+		}
+	}
+
+	// The copy is flushed by an Exec without values
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrorStorageExecBulk, err)
+	}
+
+	if hasColumn(columns, "id") {
+		if err = setSequence(ctx, tx, db.Dialect, table); err != nil {
+			return fmt.Errorf("%w: %v", ErrorStorageExecBulk, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrorStorageExecBulk, err)
+	}
+
+	return nil
+}
+
+// InsertId runs the INSERT query and returns the id of the row it stored,
+// with RETURNING for the Returner dialects. It is not retried, the row may
+// have been stored.
+func (db *DB) InsertId(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if returner, ok := db.Dialect.(Returner); ok {
+		var id int64
+		err := db.QueryRowContext(ctx, query+" "+returner.Returning("id"), args...).Scan(&id)
+
+		return id, err
+	}
+
+	result, err := db.DB.ExecContext(ctx, db.Dialect.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext runs the query, retried after transient errors.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := db.Retry(ctx, func() error {
+		var err error
+		rows, err = db.DB.QueryContext(ctx, db.Dialect.Rebind(query), args...)
+		return err
+	})
+
+	return rows, err
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.Dialect.Rebind(query), args...)
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext runs the statement, retried after transient errors.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.Retry(ctx, func() error {
+		var err error
+		result, err = db.DB.ExecContext(ctx, db.Dialect.Rebind(query), args...)
+		return err
+	})
+
+	return result, err
+}
+
+func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	return db.DB.Prepare(db.Dialect.Rebind(query))
+}
+
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.DB.PrepareContext(ctx, db.Dialect.Rebind(query))
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		Tx:      tx,
+		Dialect: db.Dialect,
+	}, nil
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.Tx.PrepareContext(ctx, tx.Dialect.Rebind(query))
+}
+
+// SetSequence moves the id sequence of table past the ids inserted in the
+// transaction, for the Sequencer dialects. The others move it themselves.
+func (tx *Tx) SetSequence(ctx context.Context, table string) error {
+	return setSequence(ctx, tx.Tx, tx.Dialect, table)
+}
+
+func setSequence(ctx context.Context, conn execer, dialect Dialect, table string) error {
+	sequencer, ok := dialect.(Sequencer)
+	if !ok {
+		return nil
+	}
+
+	_, err := conn.ExecContext(ctx, sequencer.SetSequence(table))
+	return err
+}
+
+func hasColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stripComments(statement string) string {
+	var lines []string
+	for _, line := range strings.Split(statement, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
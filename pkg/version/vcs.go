@@ -0,0 +1,28 @@
+//go:build go1.18
+// +build go1.18
+
+package version
+
+import (
+	"runtime/debug"
+)
+
+// readVCS fills the commit, the build time and whether the checkout was
+// modified from the VCS settings go 1.18 builds stamp, the ones the ldflags
+// did not set.
+func readVCS(buildInfo *debug.BuildInfo, info *Info) {
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !go1.18
+// +build !go1.18
+
+package version
+
+import (
+	"runtime/debug"
+)
+
+// readVCS does nothing, builds before go 1.18 stamp no VCS settings.
+func readVCS(buildInfo *debug.BuildInfo, info *Info) {}
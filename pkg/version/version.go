@@ -0,0 +1,52 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with -ldflags, like
+//
+//	go build -ldflags "-X github.com/matias-ziliotto/HackthonGo/pkg/version.Version=v1.2.0 -X github.com/matias-ziliotto/HackthonGo/pkg/version.Commit=$(git rev-parse HEAD)"
+//
+// The ones left empty come from the build info of the binary.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Info is the build of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of the running binary, from the ldflags and, for
+// the ones not set, its build info. The version is "dev" when neither has
+// it, a binary built from a checkout instead of a module version.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+
+		readVCS(buildInfo, &info)
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	return info
+}
@@ -0,0 +1,208 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/pkg/cache"
+)
+
+// ResponseCache caches successful report responses and serves them with
+// ETag and Last-Modified headers. Last-Modified is the last time the data was
+// invalidated, so every report shares it.
+type ResponseCache struct {
+	cache cache.Cache
+	mu    sync.RWMutex
+	// generation counts the purges, a report started before the last one
+	// may be stale
+	generation   uint64
+	lastModified time.Time
+	purgedAt     time.Time
+}
+
+func NewResponseCache(c cache.Cache) *ResponseCache {
+	return &ResponseCache{
+		cache:        c,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+		purgedAt:     time.Now(),
+	}
+}
+
+// Purge drops every cached response and marks the data as modified now.
+// Last-Modified has a precision of seconds, so it moves a second ahead when
+// purged again in the same second, for the clients to see the change.
+func (rc *ResponseCache) Purge() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.cache.Purge()
+	rc.generation++
+	rc.purgedAt = time.Now()
+
+	lastModified := time.Now().UTC().Truncate(time.Second)
+	if !lastModified.After(rc.lastModified) {
+		lastModified = rc.lastModified.Add(time.Second)
+	}
+	rc.lastModified = lastModified
+}
+
+func (rc *ResponseCache) LastModified() time.Time {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	return rc.lastModified
+}
+
+// PurgedWithin tells whether the cache was purged, or made, less than d ago.
+func (rc *ResponseCache) PurgedWithin(d time.Duration) bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	return time.Since(rc.purgedAt) < d
+}
+
+func (rc *ResponseCache) current() (uint64, time.Time) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	return rc.generation, rc.lastModified
+}
+
+// set stores entry unless the cache was purged since generation.
+func (rc *ResponseCache) set(key string, entry cache.Entry, generation uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	// Data changed while the report was running, the result may be stale
+	if rc.generation == generation {
+		rc.cache.Set(key, entry)
+	}
+}
+
+// Cached answers report from the cache when possible, keyed by the query
+// params and the Accept header, and stores 200 responses otherwise.
+func (rc *ResponseCache) Cached(report string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		params := c.Request.URL.Query()
+		if accept := c.GetHeader("Accept"); accept != "" {
+			params.Set("accept", accept)
+		}
+		key := cache.Key(report, params)
+
+		if entry, ok := rc.cache.Get(key); ok {
+			c.Header("X-Cache", "HIT")
+			writeCachedEntry(c, entry)
+			c.Abort()
+			return
+		}
+
+		generation, lastModified := rc.current()
+		original := c.Writer
+		recorder := &responseRecorder{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = recorder
+
+		c.Next()
+
+		c.Writer = original
+		// Failed handlers leave the response to ErrorHandler
+		if len(c.Errors) > 0 {
+			return
+		}
+
+		if recorder.status != http.StatusOK {
+			original.WriteHeader(recorder.status)
+			_, _ = original.Write(recorder.body.Bytes())
+			return
+		}
+
+		hash := sha1.Sum(recorder.body.Bytes())
+		entry := cache.Entry{
+			Status:       recorder.status,
+			ContentType:  original.Header().Get("Content-Type"),
+			Body:         recorder.body.Bytes(),
+			ETag:         `"` + hex.EncodeToString(hash[:]) + `"`,
+			LastModified: lastModified,
+		}
+
+		rc.set(key, entry, generation)
+
+		c.Header("X-Cache", "MISS")
+		writeCachedEntry(c, entry)
+	}
+}
+
+// Invalidate purges the cache once the write handlers after it finish, even
+// if they failed, since they may have changed part of the data.
+func (rc *ResponseCache) Invalidate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		rc.Purge()
+	}
+}
+
+func writeCachedEntry(c *gin.Context, entry cache.Entry) {
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", entry.LastModified.Format(http.TimeFormat))
+
+	if notModified(c.Request, entry) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Header("Content-Type", entry.ContentType)
+	c.Status(entry.Status)
+	_, _ = c.Writer.Write(entry.Body)
+}
+
+func notModified(r *http.Request, entry cache.Entry) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return ifNoneMatch == entry.ETag || ifNoneMatch == "*"
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !entry.LastModified.After(since)
+	}
+
+	return false
+}
+
+// responseRecorder buffers the response so headers can be added once the
+// whole body is known.
+type responseRecorder struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) WriteHeaderNow() {}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	return r.body.WriteString(s)
+}
+
+func (r *responseRecorder) Status() int {
+	return r.status
+}
+
+func (r *responseRecorder) Size() int {
+	return r.body.Len()
+}
+
+func (r *responseRecorder) Written() bool {
+	return r.body.Len() > 0
+}
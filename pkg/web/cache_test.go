@@ -0,0 +1,176 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matias-ziliotto/HackthonGo/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// reportRouter serves the cached reports of rc, counting how many times
+// they run. during runs in the middle of /report when set.
+type reportRouter struct {
+	*gin.Engine
+	runs   int
+	during func()
+}
+
+func newReportRouter(rc *ResponseCache) *reportRouter {
+	gin.SetMode(gin.TestMode)
+	router := &reportRouter{Engine: gin.New()}
+	router.Use(ErrorHandler())
+
+	router.GET("/report", rc.Cached("report"), func(c *gin.Context) {
+		router.runs++
+		if router.during != nil {
+			router.during()
+		}
+		Success(c, http.StatusOK, router.runs)
+	})
+	router.GET("/missing", rc.Cached("missing"), func(c *gin.Context) {
+		router.runs++
+		Response(c, http.StatusNotFound, ErrorResponse{Code: "not_found"})
+	})
+	router.GET("/failing", rc.Cached("failing"), func(c *gin.Context) {
+		router.runs++
+		c.Error(errors.New("query failed"))
+	})
+	router.POST("/write", rc.Invalidate(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func (r *reportRouter) request(method string, path string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+
+	return response
+}
+
+func TestResponseCacheMissThenHit(t *testing.T) {
+	// Arrange
+	router := newReportRouter(NewResponseCache(cache.NewLRU(0)))
+
+	// Act
+	first := router.request(http.MethodGet, "/report", nil)
+	second := router.request(http.MethodGet, "/report", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	assert.Equal(t, 1, router.runs, "the report should run once")
+}
+
+func TestResponseCacheNotModified(t *testing.T) {
+	rc := NewResponseCache(cache.NewLRU(0))
+	router := newReportRouter(rc)
+	first := router.request(http.MethodGet, "/report", nil)
+	lastModified := rc.LastModified()
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"same etag", map[string]string{"If-None-Match": first.Header().Get("ETag")}, http.StatusNotModified},
+		{"any etag", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"other etag", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"etag before date", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			response := router.request(http.MethodGet, "/report", test.headers)
+
+			// Assert
+			assert.Equal(t, test.expectedStatus, response.Code)
+			assert.Equal(t, first.Header().Get("ETag"), response.Header().Get("ETag"))
+			if test.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, response.Body.String(), "body should be empty")
+			}
+		})
+	}
+}
+
+func TestResponseCacheSkipsFailedResponses(t *testing.T) {
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/missing", http.StatusNotFound},
+		{"/failing", http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			// Arrange
+			router := newReportRouter(NewResponseCache(cache.NewLRU(0)))
+
+			// Act
+			first := router.request(http.MethodGet, test.path, nil)
+			second := router.request(http.MethodGet, test.path, nil)
+
+			// Assert
+			assert.Equal(t, test.expectedStatus, first.Code)
+			assert.Equal(t, test.expectedStatus, second.Code)
+			assert.Empty(t, second.Header().Get("X-Cache"), "response should not be cached")
+			assert.Equal(t, 2, router.runs, "the report should run every time")
+		})
+	}
+}
+
+func TestResponseCachePurgedAfterWrite(t *testing.T) {
+	// Arrange
+	rc := NewResponseCache(cache.NewLRU(0))
+	router := newReportRouter(rc)
+	before := router.request(http.MethodGet, "/report", nil)
+
+	// Act
+	router.request(http.MethodPost, "/write", nil)
+	after := router.request(http.MethodGet, "/report", nil)
+
+	// Assert
+	assert.Equal(t, "MISS", after.Header().Get("X-Cache"), "the write should purge the cache")
+	assert.Equal(t, 2, router.runs)
+	assert.NotEqual(t, before.Header().Get("Last-Modified"), after.Header().Get("Last-Modified"), "Last-Modified should move even in the same second")
+}
+
+func TestResponseCacheSkipsResultsStaleByPurge(t *testing.T) {
+	// Arrange
+	rc := NewResponseCache(cache.NewLRU(0))
+	router := newReportRouter(rc)
+	router.during = func() {
+		router.during = nil
+		rc.Purge()
+	}
+
+	// Act
+	first := router.request(http.MethodGet, "/report", nil)
+	second := router.request(http.MethodGet, "/report", nil)
+	third := router.request(http.MethodGet, "/report", nil)
+
+	// Assert
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	assert.Equal(t, "MISS", second.Header().Get("X-Cache"), "the report run during the purge should not be cached")
+	assert.Equal(t, "HIT", third.Header().Get("X-Cache"))
+	assert.Equal(t, 2, router.runs)
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Cursor is the position of a row in a keyset listing: the values of the
+// sort fields of the row, tie-breaker included.
+type Cursor struct {
+	Sort     string   `json:"s"` // sort of the listing, cursors are only valid for it
+	Values   []string `json:"v"`
+	Backward bool     `json:"b,omitempty"` // the rows before the position instead of after
+}
+
+// Cursors are the cursors of the pages around a keyset page, empty when
+// there is no such page.
+type Cursors struct {
+	Next string
+	Prev string
+}
+
+// CursorCodec turns cursors into opaque tokens signed with HMAC-SHA256, so
+// clients can not craft or alter them.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec signs cursors with secret. Without secret a random one is
+// used, so cursors stop being valid when the server restarts.
+func NewCursorCodec(secret []byte) *CursorCodec {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+
+	return &CursorCodec{key: secret}
+}
+
+func (cc *CursorCodec) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(cc.sign(payload))
+}
+
+func (cc *CursorCodec) Decode(token string) (Cursor, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return Cursor{}, ErrorQueryInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Cursor{}, ErrorQueryInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, cc.sign(payload)) {
+		return Cursor{}, ErrorQueryInvalidCursor
+	}
+
+	var cursor Cursor
+	if err = json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, ErrorQueryInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func (cc *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cc.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+// Keyset is a keyset listing query built from a QuerySpec. It fetches one
+// row more than the page size to know if there are more rows.
+type Keyset struct {
+	Clauses SQLClauses
+	Fields  []string // sort fields, Page needs their values for every fetched row
+
+	spec     QuerySpec
+	sort     string
+	backward bool
+	codec    *CursorCodec
+}
+
+// Keyset translates the spec to the SQL clauses of a keyset listing,
+// filtered by the columns of the allow-list and sorted by the ones of
+// sortColumns. Instead of skipping rows with OFFSET, the rows after (or
+// before) the cursor position are fetched, so pages stay fast and stable
+// while new rows are inserted. defaultSort must end with a unique field, the
+// tie-breaker of every sort. The cursor carries the values of the sort
+// fields as text, so sortColumns must leave out the float columns, whose
+// stored values never equal the text read back.
+func (q QuerySpec) Keyset(columns QueryColumns, sortColumns QueryColumns, defaultSort []Sort, codec *CursorCodec) (Keyset, error) {
+	if q.Page > 1 {
+		return Keyset{}, ErrorQueryCursorOnly
+	}
+
+	sorts, err := q.Sorts(sortColumns, defaultSort)
+	if err != nil {
+		return Keyset{}, err
+	}
+
+	where, args, err := q.WhereSQL(columns)
+	if err != nil {
+		return Keyset{}, err
+	}
+
+	keyset := Keyset{
+		spec:  q,
+		codec: codec,
+	}
+
+	sortFields := make([]string, 0, len(sorts))
+	for _, sortField := range sorts {
+		keyset.Fields = append(keyset.Fields, sortField.Field)
+		if sortField.Desc {
+			sortFields = append(sortFields, "-"+sortField.Field)
+		} else {
+			sortFields = append(sortFields, sortField.Field)
+		}
+	}
+	keyset.sort = strings.Join(sortFields, ",")
+
+	if q.Cursor != "" {
+		cursor, err := codec.Decode(q.Cursor)
+		if err != nil || cursor.Sort != keyset.sort || len(cursor.Values) != len(sorts) {
+			return Keyset{}, ErrorQueryInvalidCursor
+		}
+		keyset.backward = cursor.Backward
+
+		// (a > ?) OR (a = ? AND b > ?) OR ..., with < for descending fields
+		var positions []string
+		for i, sortField := range sorts {
+			var conditions []string
+			for _, previous := range sorts[:i] {
+				conditions = append(conditions, sortColumns[previous.Field]+" = ?")
+			}
+
+			operator := ">"
+			if sortField.Desc != keyset.backward {
+				operator = "<"
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", sortColumns[sortField.Field], operator))
+			positions = append(positions, "("+strings.Join(conditions, " AND ")+")")
+
+			for _, value := range cursor.Values[:i+1] {
+				args = append(args, value)
+			}
+		}
+
+		position := "(" + strings.Join(positions, " OR ") + ")"
+		if where == "" {
+			where = "WHERE " + position
+		} else {
+			where += " AND " + position
+		}
+	}
+
+	// Backward pages are fetched in reverse order, starting next to the cursor
+	orders := make([]string, 0, len(sorts))
+	for _, sortField := range sorts {
+		direction := "ASC"
+		if sortField.Desc != keyset.backward {
+			direction = "DESC"
+		}
+
+		orders = append(orders, sortColumns[sortField.Field]+" "+direction)
+	}
+
+	keyset.Clauses = SQLClauses{
+		Where:   where,
+		OrderBy: "ORDER BY " + strings.Join(orders, ", "),
+		Limit:   fmt.Sprintf("LIMIT %d", q.Size+1),
+		Args:    args,
+	}
+
+	return keyset, nil
+}
+
+// Page picks the page out of the fetched rows, given the values of the
+// Fields of every row in fetch order. It returns the indexes of the rows of
+// the page in listing order and the cursors of the pages around it.
+func (k Keyset) Page(keys [][]string) ([]int, Cursors) {
+	more := len(keys) > k.spec.Size
+	if more {
+		keys = keys[:k.spec.Size]
+	}
+
+	indexes := make([]int, len(keys))
+	for i := range indexes {
+		indexes[i] = i
+		if k.backward {
+			indexes[i] = len(keys) - 1 - i
+		}
+	}
+
+	var cursors Cursors
+	if len(indexes) == 0 {
+		return indexes, cursors
+	}
+
+	// Going forward there are rows before if we came from a cursor, going
+	// backward there are rows after since we came from them
+	hasNext := more || k.backward
+	hasPrev := (more && k.backward) || (!k.backward && k.spec.Cursor != "")
+
+	if hasNext {
+		cursors.Next = k.codec.Encode(Cursor{Sort: k.sort, Values: keys[indexes[len(indexes)-1]]})
+	}
+
+	if hasPrev {
+		cursors.Prev = k.codec.Encode(Cursor{Sort: k.sort, Values: keys[indexes[0]], Backward: true})
+	}
+
+	return indexes, cursors
+}
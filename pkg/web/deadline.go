@@ -0,0 +1,86 @@
+package web
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadlines are how long the requests of every route can take, Routes by
+// "<METHOD> <path>" as registered, like "GET /products/:id", and Default
+// for the others. No deadline when 0.
+type Deadlines struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// For is the deadline of the requests of the route of method and path.
+func (d Deadlines) For(method string, path string) time.Duration {
+	if deadline, ok := d.Routes[method+" "+path]; ok {
+		return deadline
+	}
+
+	return d.Default
+}
+
+// Handler gives the context of every request its deadline, so the queries
+// of a request are aborted once it is over or the client goes away. A failed
+// handler whose request was over is answered with ErrorRequestTimeout or
+// ErrorRequestCanceled, whatever the error the abort caused.
+func (d Deadlines) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if deadline := d.For(c.Request.Method, c.FullPath()); deadline > 0 {
+			ctx, cancel = context.WithTimeout(c.Request.Context(), deadline)
+		} else {
+			ctx, cancel = context.WithCancel(c.Request.Context())
+		}
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if err := ctx.Err(); err != nil && len(c.Errors) > 0 {
+			c.Error(err)
+		}
+	}
+}
+
+// Uninterruptible lets the requests of a route run up to their deadline
+// when the client goes away or the server shuts down, for the routes whose
+// writes would be left half done if their queries were aborted.
+func Uninterruptible() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent := c.Request.Context()
+
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if deadline, ok := parent.Deadline(); ok {
+			ctx, cancel = context.WithDeadline(detachedContext{parent}, deadline)
+		} else {
+			ctx, cancel = context.WithCancel(detachedContext{parent})
+		}
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// detachedContext has the values of its parent but is never done, whether
+// its parent is or not.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kind is the class of an application error, it decides the HTTP status of
+// the response.
+type Kind string
+
+const (
+	KindNotFound      Kind = "not_found"
+	KindConflict      Kind = "conflict"
+	KindValidation    Kind = "validation"
+	KindUnprocessable Kind = "unprocessable" // valid request the data can not answer
+	KindInternal      Kind = "internal"
+	KindCanceled      Kind = "canceled" // the client went away before the response
+	KindTimeout       Kind = "timeout"  // the request took longer than its deadline
+	KindUnavailable   Kind = "unavailable"
+
+	// StatusClientClosedRequest is the nginx status of the requests the
+	// client closed before the response
+	StatusClientClosedRequest = 499
+)
+
+var (
+	// Errors
+	ErrorInternal        = NewError(KindInternal, "internal_error", "internal server error")
+	ErrorInvalidParam    = NewError(KindValidation, "invalid_param", "invalid param")
+	ErrorRequestCanceled = NewError(KindCanceled, "request_canceled", "request canceled by the client")
+	ErrorRequestTimeout  = NewError(KindTimeout, "request_timeout", "request deadline exceeded")
+
+	kindStatuses = map[Kind]int{
+		KindNotFound:      http.StatusNotFound,
+		KindConflict:      http.StatusConflict,
+		KindValidation:    http.StatusBadRequest,
+		KindUnprocessable: http.StatusUnprocessableEntity,
+		KindInternal:      http.StatusInternalServerError,
+		KindCanceled:      StatusClientClosedRequest,
+		KindTimeout:       http.StatusGatewayTimeout,
+		KindUnavailable:   http.StatusServiceUnavailable,
+	}
+)
+
+// AppError is an error with the kind and the stable code clients see. The
+// package level errors of the app are AppErrors, and Wrap adds the cause
+// without breaking errors.Is on them.
+type AppError struct {
+	Kind    Kind
+	Code    string // machine readable, never changes once published
+	Message string
+	Detail  string       // what failed this time, safe to answer, if any
+	Err     error        // cause, logged and never answered
+	Fields  []FieldError // the fields that failed validation, if any
+}
+
+func NewError(kind Kind, code string, message string) *AppError {
+	return &AppError{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the AppErrors with the same code, so a wrapped copy is still
+// the error it was made from.
+func (e *AppError) Is(target error) bool {
+	targetError, ok := target.(*AppError)
+
+	return ok && targetError.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by cause.
+func (e *AppError) Wrap(cause error) *AppError {
+	wrapped := *e
+	wrapped.Err = cause
+
+	return &wrapped
+}
+
+// PublicMessage is the message answered to clients, the message of the
+// error and its detail but not its cause, which may have driver or SQL text.
+func (e *AppError) PublicMessage() string {
+	if e.Detail != "" {
+		return e.Message + ": " + e.Detail
+	}
+
+	return e.Message
+}
+
+// Status is the HTTP status of the kind of the error.
+func (e *AppError) Status() int {
+	if status, ok := kindStatuses[e.Kind]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// AsAppError returns the first AppError of the chain of err, or err wrapped
+// by ErrorInvalidBody when it comes from binding the request, or by
+// ErrorInternal otherwise. Errors of a context done are ErrorRequestTimeout
+// or ErrorRequestCanceled, even wrapped by an internal AppError.
+func AsAppError(err error) *AppError {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorRequestTimeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return ErrorRequestCanceled.Wrap(err)
+	}
+
+	var appError *AppError
+	if errors.As(err, &appError) {
+		return appError
+	}
+
+	if appError, ok := bindingError(err); ok {
+		return appError
+	}
+
+	return ErrorInternal.Wrap(err)
+}
+
+// ErrorHandler answers the last error handlers add with c.Error, with the
+// status and code of its kind, as a Problem to the clients accepting
+// application/problem+json and as an ErrorResponse otherwise. Errors are
+// answered with their public message only and their causes are logged, they
+// are not for clients.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		appError := AsAppError(err)
+		message := appError.PublicMessage()
+
+		if appError.Kind == KindInternal || appError.Err != nil {
+			log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+
+		if c.NegotiateFormat(gin.MIMEJSON, MIMEProblemJSON) == MIMEProblemJSON {
+			// gin keeps the content type when it is already set
+			c.Header("Content-Type", MIMEProblemJSON)
+			c.AbortWithStatusJSON(appError.Status(), Problem{
+				Type:     ProblemTypeBase + appError.Code,
+				Title:    appError.Message,
+				Status:   appError.Status(),
+				Detail:   message,
+				Instance: c.Request.URL.RequestURI(),
+				Code:     appError.Code,
+				Errors:   appError.Fields,
+			})
+			return
+		}
+
+		c.AbortWithStatusJSON(appError.Status(), ErrorResponse{
+			Status:  appError.Status(),
+			Code:    appError.Code,
+			Message: message,
+			Errors:  appError.Fields,
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package web
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Row is a row of an in-memory listing. It returns the value of a field of
+// the allow-list, an int, a float64 or a string.
+type Row func(field string) interface{}
+
+// Select runs the spec on rows in memory the way its SQL clauses run on a
+// table. It returns the indexes of the rows of the page, in listing order,
+// and how many rows match the filters.
+func (q QuerySpec) Select(columns QueryColumns, defaultSort []Sort, rows []Row) ([]int, int, error) {
+	if _, _, err := q.WhereSQL(columns); err != nil {
+		return nil, 0, err
+	}
+
+	sorts, err := q.Sorts(columns, defaultSort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matched := matchFilters(q.Filters, rows)
+	sortRows(matched, rows, sorts, false)
+
+	total := len(matched)
+	start := q.Offset()
+	if start > total {
+		start = total
+	}
+	end := start + q.Size
+	if end > total {
+		end = total
+	}
+
+	return matched[start:end], total, nil
+}
+
+// Select returns the indexes of the rows the keyset query fetches, in fetch
+// order, like its SQL clauses on a table. The keys of the fetched rows go to
+// Page.
+func (k Keyset) Select(rows []Row) []int {
+	var sorts []Sort
+	for _, field := range strings.Split(k.sort, ",") {
+		sorts = append(sorts, Sort{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")})
+	}
+
+	matched := matchFilters(k.spec.Filters, rows)
+
+	// The cursor was checked when the keyset was built
+	if k.spec.Cursor != "" {
+		cursor, _ := k.codec.Decode(k.spec.Cursor)
+
+		var after []int
+		for _, index := range matched {
+			if afterPosition(rows[index], sorts, cursor.Values, k.backward) {
+				after = append(after, index)
+			}
+		}
+		matched = after
+	}
+
+	sortRows(matched, rows, sorts, k.backward)
+	if len(matched) > k.spec.Size+1 {
+		matched = matched[:k.spec.Size+1]
+	}
+
+	return matched
+}
+
+func matchFilters(filters []Filter, rows []Row) []int {
+	matched := []int{}
+	for index, row := range rows {
+		match := true
+		for _, filter := range filters {
+			if !matchFilter(row(filter.Field), filter) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			matched = append(matched, index)
+		}
+	}
+
+	return matched
+}
+
+func matchFilter(value interface{}, filter Filter) bool {
+	switch filter.Operator {
+	case OperatorLike:
+		return strings.Contains(strings.ToLower(valueString(value)), strings.ToLower(filter.Values[0]))
+	case OperatorIn:
+		for _, filterValue := range filter.Values {
+			if comparison, ok := compareValue(value, filterValue); ok && comparison == 0 {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	comparison, ok := compareValue(value, filter.Values[0])
+	if !ok {
+		return false
+	}
+
+	switch filter.Operator {
+	case OperatorEq:
+		return comparison == 0
+	case OperatorNe:
+		return comparison != 0
+	case OperatorGt:
+		return comparison > 0
+	case OperatorGte:
+		return comparison >= 0
+	case OperatorLt:
+		return comparison < 0
+	case OperatorLte:
+		return comparison <= 0
+	}
+
+	return false
+}
+
+// afterPosition reports whether row comes after the cursor values in the
+// sorts, or before when going backward.
+func afterPosition(row Row, sorts []Sort, values []string, backward bool) bool {
+	for i, sortField := range sorts {
+		comparison, ok := compareValue(row(sortField.Field), values[i])
+		if !ok {
+			return false
+		}
+
+		if comparison == 0 {
+			continue
+		}
+
+		return (comparison > 0) != (sortField.Desc != backward)
+	}
+
+	return false
+}
+
+// sortRows sorts the indexes by the values of their rows in the sorts,
+// reversed when backward.
+func sortRows(indexes []int, rows []Row, sorts []Sort, backward bool) {
+	sort.SliceStable(indexes, func(i, j int) bool {
+		for _, sortField := range sorts {
+			comparison := compareValues(rows[indexes[i]](sortField.Field), rows[indexes[j]](sortField.Field))
+			if comparison == 0 {
+				continue
+			}
+
+			return (comparison < 0) != (sortField.Desc != backward)
+		}
+
+		return false
+	})
+}
+
+// compareValue compares a row value with a query param value, as a number
+// for number fields. It is not ok when the param is not a number for them.
+func compareValue(value interface{}, param string) (int, bool) {
+	switch value.(type) {
+	case int, float64:
+		number, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return compareValues(value, number), true
+	}
+
+	return strings.Compare(valueString(value), param), true
+}
+
+func compareValues(a interface{}, b interface{}) int {
+	aNumber, aIsNumber := valueNumber(a)
+	bNumber, bIsNumber := valueNumber(b)
+	if aIsNumber && bIsNumber {
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		}
+
+		return 0
+	}
+
+	return strings.Compare(valueString(a), valueString(b))
+}
+
+func valueNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+
+	return 0, false
+}
+
+func valueString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case int:
+		return strconv.Itoa(typed)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	}
+
+	return ""
+}
@@ -0,0 +1,226 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	MIMEProblemJSON = "application/problem+json"
+
+	// ProblemTypeBase prefixes the code of an error to make its problem type
+	ProblemTypeBase = "/problems/"
+
+	// DateLayout is the layout of the date query params
+	DateLayout = "2006-01-02"
+)
+
+var (
+	// Errors
+	ErrorInvalidBody = NewError(KindValidation, "invalid_body", "invalid request body")
+
+	// ruleMessages describe the validator rules, %s is the rule param
+	ruleMessages = map[string]string{
+		"required": "is required",
+		"min":      "must be at least %s",
+		"max":      "must be at most %s",
+		"len":      "must have length %s",
+		"gt":       "must be greater than %s",
+		"gte":      "must be greater than or equal to %s",
+		"lt":       "must be less than %s",
+		"lte":      "must be less than or equal to %s",
+		"oneof":    "must be one of %s",
+		"email":    "must be an email",
+		"datetime": "must be a date with layout %s",
+		"integer":  "must be an integer",
+		"number":   "must be a number",
+		"boolean":  "must be a boolean",
+		"date":     "must be a date, YYYY-MM-DD",
+	}
+
+	// queryRules check the string fields query params are bound into, gin
+	// fails on the numbers it can not parse without naming their param.
+	// number replaces the one of the validator, which only takes digits.
+	queryRules = map[string]func(value string) bool{
+		"integer": func(value string) bool {
+			_, err := strconv.Atoi(value)
+			return err == nil
+		},
+		"number": func(value string) bool {
+			_, err := strconv.ParseFloat(value, 64)
+			return err == nil
+		},
+		"boolean": func(value string) bool {
+			_, err := strconv.ParseBool(value)
+			return err == nil
+		},
+		"date": func(value string) bool {
+			_, err := time.Parse(DateLayout, value)
+			return err == nil
+		},
+	}
+	queryRulesOnce sync.Once
+)
+
+// Problem is an RFC 7807 problem details response, answered as
+// application/problem+json to the clients accepting it.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is the failed rule of a field of the request, a body or query
+// field by its JSON or query param name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// InvalidParam is ErrorInvalidParam for field, which failed rule.
+func InvalidParam(field string, rule string, message string) *AppError {
+	appError := ErrorInvalidParam.Wrap(fmt.Errorf("%s %s", field, message))
+	appError.Detail = field + " " + message
+	appError.Fields = []FieldError{{Field: field, Rule: rule, Message: message}}
+
+	return appError
+}
+
+// RegisterJSONFieldNames makes the gin validator name fields by their json
+// tag, or form tag for query structs, the names clients know them by.
+func RegisterJSONFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+
+		return field.Name
+	})
+}
+
+// BindQuery binds the query params of the request into query, a struct with
+// form tags and binding rules, failing with ErrorInvalidParam with the fields
+// that failed their rules. Numbers, booleans and dates are bound as strings
+// with the integer, number, boolean or date rule, and parsed once they pass.
+func BindQuery(c *gin.Context, query interface{}) error {
+	queryRulesOnce.Do(func() {
+		RegisterJSONFieldNames()
+		registerQueryRules()
+	})
+
+	err := c.ShouldBindQuery(query)
+	if err == nil {
+		return nil
+	}
+
+	appError := ErrorInvalidParam.Wrap(err)
+	if bindError, ok := bindingError(err); ok {
+		details := make([]string, 0, len(bindError.Fields))
+		for _, fieldError := range bindError.Fields {
+			details = append(details, fieldError.Field+" "+fieldError.Message)
+		}
+
+		appError.Fields = bindError.Fields
+		appError.Detail = strings.Join(details, ", ")
+	}
+
+	return appError
+}
+
+func registerQueryRules() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	for name, rule := range queryRules {
+		rule := rule
+		validate.RegisterValidation(name, func(field validator.FieldLevel) bool {
+			return rule(field.Field().String())
+		})
+	}
+}
+
+// bindingError turns the errors of binding a request with gin, invalid JSON
+// or failed validator rules, into ErrorInvalidBody with their fields.
+func bindingError(err error) (*AppError, bool) {
+	var validationErrors validator.ValidationErrors
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrors):
+		appError := ErrorInvalidBody.Wrap(err)
+		for _, fieldError := range validationErrors {
+			appError.Fields = append(appError.Fields, FieldError{
+				Field:   fieldPath(fieldError.Namespace()),
+				Rule:    fieldError.Tag(),
+				Message: ruleMessage(fieldError.Tag(), fieldError.Param()),
+			})
+		}
+
+		return appError, true
+	case errors.As(err, &typeError):
+		appError := ErrorInvalidBody.Wrap(err)
+		appError.Fields = []FieldError{{
+			Field:   typeError.Field,
+			Rule:    "type",
+			Message: "must be " + typeError.Type.Kind().String(),
+		}}
+
+		return appError, true
+	case errors.As(err, &syntaxError):
+		return ErrorInvalidBody.Wrap(err), true
+	}
+
+	return nil, false
+}
+
+// fieldPath drops the struct name the validator namespaces start with,
+// Sale.items[0].quantity is items[0].quantity.
+func fieldPath(namespace string) string {
+	if dot := strings.Index(namespace, "."); dot >= 0 {
+		return namespace[dot+1:]
+	}
+
+	return namespace
+}
+
+func ruleMessage(rule string, param string) string {
+	message, ok := ruleMessages[rule]
+	if !ok {
+		return "must satisfy " + rule
+	}
+
+	if strings.Contains(message, "%s") {
+		return fmt.Sprintf(message, param)
+	}
+
+	return message
+}
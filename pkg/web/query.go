@@ -0,0 +1,331 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+
+	// Filter operators
+	OperatorEq   = "eq"
+	OperatorNe   = "ne"
+	OperatorGt   = "gt"
+	OperatorGte  = "gte"
+	OperatorLt   = "lt"
+	OperatorLte  = "lte"
+	OperatorLike = "like"
+	OperatorIn   = "in"
+)
+
+var (
+	// Errors
+	ErrorQueryInvalidPage     = NewError(KindValidation, "invalid_page", "page must be a positive number")
+	ErrorQueryInvalidSize     = NewError(KindValidation, "invalid_size", "size must be between 1 and 500")
+	ErrorQueryPageAndCursor   = NewError(KindValidation, "page_and_cursor", "page and cursor can not be used together")
+	ErrorQueryInvalidSort     = NewError(KindValidation, "invalid_sort", "invalid sort field")
+	ErrorQueryInvalidFilter   = NewError(KindValidation, "invalid_filter", "invalid filter field")
+	ErrorQueryInvalidOperator = NewError(KindValidation, "invalid_operator", "filter operator must be eq, ne, gt, gte, lt, lte, like or in")
+	ErrorQueryInvalidCursor   = NewError(KindValidation, "invalid_cursor", "invalid cursor")
+	ErrorQueryCursorOnly      = NewError(KindValidation, "cursor_only", "this listing only paginates with cursors")
+
+	// filterParam matches the field[operator] query params
+	filterParam = regexp.MustCompile(`^([a-z_]+)\[([a-z]+)\]$`)
+
+	// likeEscaper escapes the wildcards of a like value, for it to match
+	// as is. The escape character is ! since \ needs escaping itself in
+	// the MySQL string literals but not in the others.
+	likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+	operatorsSQL = map[string]string{
+		OperatorEq:   "=",
+		OperatorNe:   "<>",
+		OperatorGt:   ">",
+		OperatorGte:  ">=",
+		OperatorLt:   "<",
+		OperatorLte:  "<=",
+		OperatorLike: "LIKE",
+		OperatorIn:   "IN",
+	}
+)
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+type Filter struct {
+	Field    string
+	Operator string
+	Values   []string // one value for every operator but in
+}
+
+// QuerySpec is how a list endpoint is paginated, sorted and filtered:
+// page=2&size=20 or cursor=..., sort=field,-field and field[operator]=value.
+type QuerySpec struct {
+	Page    int // 0 when paginating with cursors
+	Size    int
+	Cursor  string
+	Sort    []Sort
+	Filters []Filter
+}
+
+// QueryColumns is the allow-list of a listing, from the fields clients can
+// sort and filter by to their SQL columns. Nothing else reaches the query.
+type QueryColumns map[string]string
+
+// SQLClauses are the parts of a listing query built from a QuerySpec. Args
+// are the values of the Where placeholders.
+type SQLClauses struct {
+	Where   string
+	OrderBy string
+	Limit   string
+	Args    []interface{}
+}
+
+// IsQueryError reports whether err comes from an invalid QuerySpec, so it
+// is the client's fault.
+func IsQueryError(err error) bool {
+	for _, queryError := range []error{ErrorQueryInvalidPage, ErrorQueryInvalidSize, ErrorQueryPageAndCursor,
+		ErrorQueryInvalidSort, ErrorQueryInvalidFilter, ErrorQueryInvalidOperator, ErrorQueryInvalidCursor, ErrorQueryCursorOnly} {
+		if errors.Is(err, queryError) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pageQuery has the pagination and sort query params of the listings.
+type pageQuery struct {
+	Page   string `form:"page" binding:"omitempty,integer"`
+	Size   string `form:"size" binding:"omitempty,integer"`
+	Cursor string `form:"cursor"`
+	Sort   string `form:"sort"`
+}
+
+// ParseQuerySpec reads the page, size, cursor, sort and field[operator]
+// query params. Fields are only checked against the allow-list once the
+// spec is translated to SQL.
+func ParseQuerySpec(c *gin.Context) (QuerySpec, error) {
+	var query pageQuery
+	if err := BindQuery(c, &query); err != nil {
+		return QuerySpec{}, err
+	}
+
+	spec := QuerySpec{
+		Size:   DefaultPageSize,
+		Cursor: query.Cursor,
+	}
+
+	// The binding rules checked the numbers already
+	if query.Page != "" {
+		if spec.Cursor != "" {
+			return QuerySpec{}, ErrorQueryPageAndCursor
+		}
+
+		pageNumber, _ := strconv.Atoi(query.Page)
+		if pageNumber < 1 {
+			return QuerySpec{}, ErrorQueryInvalidPage
+		}
+
+		spec.Page = pageNumber
+	}
+
+	if spec.Page == 0 && spec.Cursor == "" {
+		spec.Page = 1
+	}
+
+	if query.Size != "" {
+		sizeNumber, _ := strconv.Atoi(query.Size)
+		if sizeNumber < 1 || sizeNumber > MaxPageSize {
+			return QuerySpec{}, ErrorQueryInvalidSize
+		}
+
+		spec.Size = sizeNumber
+	}
+
+	if query.Sort != "" {
+		for _, field := range strings.Split(query.Sort, ",") {
+			field = strings.TrimSpace(field)
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+			if field == "" {
+				return QuerySpec{}, ErrorQueryInvalidSort
+			}
+
+			spec.Sort = append(spec.Sort, Sort{Field: field, Desc: desc})
+		}
+	}
+
+	for param, values := range c.Request.URL.Query() {
+		match := filterParam.FindStringSubmatch(param)
+		if match == nil {
+			continue
+		}
+
+		if _, ok := operatorsSQL[match[2]]; !ok {
+			return QuerySpec{}, fmt.Errorf("%w: %s", ErrorQueryInvalidOperator, param)
+		}
+
+		for _, value := range values {
+			filter := Filter{Field: match[1], Operator: match[2], Values: []string{value}}
+			if filter.Operator == OperatorIn {
+				filter.Values = strings.Split(value, ",")
+			}
+
+			spec.Filters = append(spec.Filters, filter)
+		}
+	}
+
+	// Query params come from a map, keep the filters and their args in a stable order
+	sort.SliceStable(spec.Filters, func(i, j int) bool {
+		if spec.Filters[i].Field != spec.Filters[j].Field {
+			return spec.Filters[i].Field < spec.Filters[j].Field
+		}
+
+		return spec.Filters[i].Operator < spec.Filters[j].Operator
+	})
+
+	return spec, nil
+}
+
+// WithFilter returns a copy of the spec with one more filter, for the
+// filters an endpoint adds on its own.
+func (q QuerySpec) WithFilter(field string, operator string, values ...string) QuerySpec {
+	filters := make([]Filter, len(q.Filters), len(q.Filters)+1)
+	copy(filters, q.Filters)
+	q.Filters = append(filters, Filter{Field: field, Operator: operator, Values: values})
+
+	return q
+}
+
+// Offset is the number of rows before the requested page.
+func (q QuerySpec) Offset() int {
+	if q.Page < 1 {
+		return 0
+	}
+
+	return (q.Page - 1) * q.Size
+}
+
+// SQL translates the spec to SQL clauses with the columns of the allow-list.
+// defaultSort is used when the client does not sort, and its last field is
+// always added as a tie-breaker so pages do not overlap.
+func (q QuerySpec) SQL(columns QueryColumns, defaultSort []Sort) (SQLClauses, error) {
+	var clauses SQLClauses
+
+	where, args, err := q.WhereSQL(columns)
+	if err != nil {
+		return SQLClauses{}, err
+	}
+	clauses.Where = where
+	clauses.Args = args
+
+	orderBy, err := q.OrderBySQL(columns, defaultSort)
+	if err != nil {
+		return SQLClauses{}, err
+	}
+	clauses.OrderBy = orderBy
+
+	clauses.Limit = fmt.Sprintf("LIMIT %d OFFSET %d", q.Size, q.Offset())
+
+	return clauses, nil
+}
+
+// WhereSQL translates the filters to a WHERE clause, empty without filters.
+// A like filter matches the values containing its value, % and _ included.
+func (q QuerySpec) WhereSQL(columns QueryColumns) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	for _, filter := range q.Filters {
+		column, ok := columns[filter.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrorQueryInvalidFilter, filter.Field)
+		}
+
+		operator, ok := operatorsSQL[filter.Operator]
+		if !ok || len(filter.Values) == 0 || (filter.Operator != OperatorIn && len(filter.Values) > 1) {
+			return "", nil, fmt.Errorf("%w: %s", ErrorQueryInvalidOperator, filter.Field)
+		}
+
+		switch filter.Operator {
+		case OperatorIn:
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, strings.TrimSuffix(strings.Repeat("?, ", len(filter.Values)), ", ")))
+			for _, value := range filter.Values {
+				args = append(args, value)
+			}
+		case OperatorLike:
+			conditions = append(conditions, column+" LIKE ? ESCAPE '!'")
+			args = append(args, "%"+likeEscaper.Replace(filter.Values[0])+"%")
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", column, operator))
+			args = append(args, filter.Values[0])
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// OrderBySQL translates the sort fields, or defaultSort, to an ORDER BY
+// clause ending with the tie-breaker.
+func (q QuerySpec) OrderBySQL(columns QueryColumns, defaultSort []Sort) (string, error) {
+	sorts, err := q.Sorts(columns, defaultSort)
+	if err != nil {
+		return "", err
+	}
+
+	orders := make([]string, 0, len(sorts))
+	for _, sortField := range sorts {
+		direction := "ASC"
+		if sortField.Desc {
+			direction = "DESC"
+		}
+
+		orders = append(orders, columns[sortField.Field]+" "+direction)
+	}
+
+	return "ORDER BY " + strings.Join(orders, ", "), nil
+}
+
+// Sorts returns the checked sort fields, or defaultSort, ending with the
+// tie-breaker.
+func (q QuerySpec) Sorts(columns QueryColumns, defaultSort []Sort) ([]Sort, error) {
+	sorts := q.Sort
+	if len(sorts) == 0 {
+		sorts = defaultSort
+	}
+
+	seen := map[string]bool{}
+	checked := make([]Sort, 0, len(sorts)+1)
+	for _, sortField := range sorts {
+		if _, ok := columns[sortField.Field]; !ok || seen[sortField.Field] {
+			return nil, fmt.Errorf("%w: %s", ErrorQueryInvalidSort, sortField.Field)
+		}
+
+		seen[sortField.Field] = true
+		checked = append(checked, sortField)
+	}
+
+	if len(defaultSort) > 0 {
+		tieBreaker := defaultSort[len(defaultSort)-1]
+		if !seen[tieBreaker.Field] {
+			checked = append(checked, tieBreaker)
+		}
+	}
+
+	return checked, nil
+}
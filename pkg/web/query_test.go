@@ -0,0 +1,231 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testQueryColumns = QueryColumns{
+	"id":          "products.id",
+	"description": "products.description",
+	"price":       "products.price",
+}
+
+func parseQuery(query string) (QuerySpec, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/products?"+query, nil)
+
+	return ParseQuerySpec(c)
+}
+
+func TestParseQuerySpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected QuerySpec
+	}{
+		{"defaults", "", QuerySpec{Page: 1, Size: DefaultPageSize}},
+		{"page and size", "page=3&size=20", QuerySpec{Page: 3, Size: 20}},
+		{"cursor", "cursor=abc", QuerySpec{Size: DefaultPageSize, Cursor: "abc"}},
+		{"sort", "sort=-price,%20id", QuerySpec{Page: 1, Size: DefaultPageSize, Sort: []Sort{{Field: "price", Desc: true}, {Field: "id"}}}},
+		{"filters sorted", "price[lte]=20&id[in]=1,2&price[gte]=10&description[like]=ma", QuerySpec{Page: 1, Size: DefaultPageSize, Filters: []Filter{
+			{Field: "description", Operator: OperatorLike, Values: []string{"ma"}},
+			{Field: "id", Operator: OperatorIn, Values: []string{"1", "2"}},
+			{Field: "price", Operator: OperatorGte, Values: []string{"10"}},
+			{Field: "price", Operator: OperatorLte, Values: []string{"20"}},
+		}}},
+		{"other params ignored", "anomalies=flag&Price[eq]=1", QuerySpec{Page: 1, Size: DefaultPageSize}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			result, err := parseQuery(test.query)
+
+			// Assert
+			assert.Nil(t, err, "error should be nil")
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestParseQuerySpecErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedError error
+	}{
+		{"page zero", "page=0", ErrorQueryInvalidPage},
+		{"size zero", "size=0", ErrorQueryInvalidSize},
+		{"size too big", "size=501", ErrorQueryInvalidSize},
+		{"page and cursor", "page=2&cursor=abc", ErrorQueryPageAndCursor},
+		{"empty sort field", "sort=price,,id", ErrorQueryInvalidSort},
+		{"only desc", "sort=-", ErrorQueryInvalidSort},
+		{"unknown operator", "price[between]=1", ErrorQueryInvalidOperator},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			result, err := parseQuery(test.query)
+
+			// Assert
+			assert.ErrorIs(t, err, test.expectedError)
+			assert.True(t, IsQueryError(err), "error should be a query error")
+			assert.Equal(t, QuerySpec{}, result, "spec should be empty")
+		})
+	}
+}
+
+func TestParseQuerySpecParamTypes(t *testing.T) {
+	// Act
+	result, err := parseQuery("page=two&size=1.5")
+
+	// Assert
+	var appError *AppError
+	assert.ErrorIs(t, err, ErrorInvalidParam)
+	assert.True(t, errors.As(err, &appError), "error should be an AppError")
+	assert.Equal(t, []FieldError{
+		{Field: "page", Rule: "integer", Message: "must be an integer"},
+		{Field: "size", Rule: "integer", Message: "must be an integer"},
+	}, appError.Fields)
+	assert.Equal(t, "invalid param: page must be an integer, size must be an integer", appError.PublicMessage())
+	assert.Equal(t, QuerySpec{}, result, "spec should be empty")
+}
+
+func TestWhereSQL(t *testing.T) {
+	tests := []struct {
+		name          string
+		filters       []Filter
+		expectedWhere string
+		expectedArgs  []interface{}
+	}{
+		{"no filters", nil, "", nil},
+		{"comparisons", []Filter{
+			{Field: "price", Operator: OperatorGt, Values: []string{"10"}},
+			{Field: "id", Operator: OperatorNe, Values: []string{"3"}},
+		}, "WHERE products.price > ? AND products.id <> ?", []interface{}{"10", "3"}},
+		{"in", []Filter{{Field: "id", Operator: OperatorIn, Values: []string{"1", "2", "3"}}}, "WHERE products.id IN (?, ?, ?)", []interface{}{"1", "2", "3"}},
+		{"like", []Filter{{Field: "description", Operator: OperatorLike, Values: []string{"mate"}}}, "WHERE products.description LIKE ? ESCAPE '!'", []interface{}{"%mate%"}},
+		{"like wildcards", []Filter{{Field: "description", Operator: OperatorLike, Values: []string{"50%_off!"}}}, "WHERE products.description LIKE ? ESCAPE '!'", []interface{}{"%50!%!_off!!%"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			spec := QuerySpec{Filters: test.filters}
+
+			// Act
+			where, args, err := spec.WhereSQL(testQueryColumns)
+
+			// Assert
+			assert.Nil(t, err, "error should be nil")
+			assert.Equal(t, test.expectedWhere, where)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestWhereSQLErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        Filter
+		expectedError error
+	}{
+		{"field not allowed", Filter{Field: "cost", Operator: OperatorEq, Values: []string{"1"}}, ErrorQueryInvalidFilter},
+		{"column name", Filter{Field: "products.price", Operator: OperatorEq, Values: []string{"1"}}, ErrorQueryInvalidFilter},
+		{"unknown operator", Filter{Field: "price", Operator: "between", Values: []string{"1"}}, ErrorQueryInvalidOperator},
+		{"no values", Filter{Field: "price", Operator: OperatorEq}, ErrorQueryInvalidOperator},
+		{"many values", Filter{Field: "price", Operator: OperatorEq, Values: []string{"1", "2"}}, ErrorQueryInvalidOperator},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			spec := QuerySpec{Filters: []Filter{test.filter}}
+
+			// Act
+			where, args, err := spec.WhereSQL(testQueryColumns)
+
+			// Assert
+			assert.ErrorIs(t, err, test.expectedError)
+			assert.Empty(t, where, "where should be empty")
+			assert.Nil(t, args, "args should be nil")
+		})
+	}
+}
+
+func TestOrderBySQL(t *testing.T) {
+	defaultSort := []Sort{{Field: "id"}}
+
+	tests := []struct {
+		name     string
+		sort     []Sort
+		expected string
+	}{
+		{"default sort", nil, "ORDER BY products.id ASC"},
+		{"tie-breaker added", []Sort{{Field: "price", Desc: true}}, "ORDER BY products.price DESC, products.id ASC"},
+		{"tie-breaker sorted by client", []Sort{{Field: "price"}, {Field: "id", Desc: true}}, "ORDER BY products.price ASC, products.id DESC"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			spec := QuerySpec{Sort: test.sort}
+
+			// Act
+			orderBy, err := spec.OrderBySQL(testQueryColumns, defaultSort)
+
+			// Assert
+			assert.Nil(t, err, "error should be nil")
+			assert.Equal(t, test.expected, orderBy)
+		})
+	}
+}
+
+func TestOrderBySQLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sort []Sort
+	}{
+		{"field not allowed", []Sort{{Field: "cost"}}},
+		{"repeated field", []Sort{{Field: "price"}, {Field: "price", Desc: true}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			spec := QuerySpec{Sort: test.sort}
+
+			// Act
+			orderBy, err := spec.OrderBySQL(testQueryColumns, []Sort{{Field: "id"}})
+
+			// Assert
+			assert.ErrorIs(t, err, ErrorQueryInvalidSort)
+			assert.Empty(t, orderBy, "order by should be empty")
+		})
+	}
+}
+
+func TestQuerySpecSQL(t *testing.T) {
+	// Arrange
+	spec := QuerySpec{Page: 3, Size: 20, Filters: []Filter{{Field: "price", Operator: OperatorLte, Values: []string{"5"}}}}
+
+	// Act
+	clauses, err := spec.SQL(testQueryColumns, []Sort{{Field: "id"}})
+
+	// Assert
+	assert.Nil(t, err, "error should be nil")
+	assert.Equal(t, SQLClauses{
+		Where:   "WHERE products.price <= ?",
+		OrderBy: "ORDER BY products.id ASC",
+		Limit:   "LIMIT 20 OFFSET 40",
+		Args:    []interface{}{"5"},
+	}, clauses)
+}
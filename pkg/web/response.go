@@ -1,21 +1,46 @@
 package web
 
 import (
+	"encoding/csv"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SuccessResponse struct {
-	Data interface{} `json:"data"`
+	Data  interface{} `json:"data"`
+	Meta  *PageMeta   `json:"meta,omitempty"`
+	Links *PageLinks  `json:"links,omitempty"`
+}
+
+// PageMeta describes the page of a list response. Keyset listings have
+// cursors instead of page numbers and totals.
+type PageMeta struct {
+	Page       int    `json:"page,omitempty"`
+	Size       int    `json:"size"`
+	Total      *int   `json:"total,omitempty"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// PageLinks are the URLs of the pages around a list response.
+type PageLinks struct {
+	Self  string `json:"self"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
 }
 
 type ErrorResponse struct {
-	Status  int    `json:"-"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
 }
 
 func Response(c *gin.Context, status int, data interface{}) {
@@ -26,6 +51,83 @@ func Success(c *gin.Context, status int, data interface{}) {
 	Response(c, status, SuccessResponse{Data: data})
 }
 
+// Paginated responds with a page of a listing of total rows, with the
+// pagination metadata and the links to the other pages.
+func Paginated(c *gin.Context, status int, data interface{}, spec QuerySpec, total int) {
+	totalPages := (total + spec.Size - 1) / spec.Size
+	meta := PageMeta{
+		Page:       spec.Page,
+		Size:       spec.Size,
+		Total:      &total,
+		TotalPages: &totalPages,
+	}
+
+	links := PageLinks{
+		Self:  pageLink(c, "page", strconv.Itoa(spec.Page)),
+		First: pageLink(c, "page", "1"),
+	}
+	if totalPages > 0 {
+		links.Last = pageLink(c, "page", strconv.Itoa(totalPages))
+	}
+	if spec.Page > 1 {
+		links.Prev = pageLink(c, "page", strconv.Itoa(spec.Page-1))
+	}
+	if spec.Page < totalPages {
+		links.Next = pageLink(c, "page", strconv.Itoa(spec.Page+1))
+	}
+
+	Response(c, status, SuccessResponse{Data: data, Meta: &meta, Links: &links})
+}
+
+// CursorPaginated responds with a page of a keyset listing, with the cursors
+// and the links to the pages around it.
+func CursorPaginated(c *gin.Context, status int, data interface{}, spec QuerySpec, cursors Cursors) {
+	meta := PageMeta{
+		Size:       spec.Size,
+		NextCursor: cursors.Next,
+		PrevCursor: cursors.Prev,
+	}
+
+	links := PageLinks{
+		Self:  c.Request.URL.RequestURI(),
+		First: pageLink(c, "cursor", ""),
+	}
+	if cursors.Next != "" {
+		links.Next = pageLink(c, "cursor", cursors.Next)
+	}
+	if cursors.Prev != "" {
+		links.Prev = pageLink(c, "cursor", cursors.Prev)
+	}
+
+	Response(c, status, SuccessResponse{Data: data, Meta: &meta, Links: &links})
+}
+
+// pageLink is the URL of the request with param replaced, or removed when
+// value is empty. Page numbers and cursors never go together.
+func pageLink(c *gin.Context, param string, value string) string {
+	query := c.Request.URL.Query()
+	query.Del("page")
+	query.Del("cursor")
+	if value != "" {
+		query.Set(param, value)
+	}
+
+	link := *c.Request.URL
+	link.RawQuery = query.Encode()
+
+	return link.RequestURI()
+}
+
+// CSV writes records as a text/csv attachment named filename.
+func CSV(c *gin.Context, status int, filename string, records [][]string) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(status)
+
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.WriteAll(records)
+}
+
 // NewErrorf creates a new error with the given status code and the message
 // formatted according to args and format.
 func Error(c *gin.Context, status int, format string, args ...interface{}) {